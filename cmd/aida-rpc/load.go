@@ -0,0 +1,374 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/executor/extension/validator"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/urfave/cli/v2"
+)
+
+// LoadCommand replays recorded requests against an endpoint to benchmark it.
+var LoadCommand = cli.Command{
+	Action: Load,
+	Name:   "load",
+	Usage:  "replays recorded requests against a JSON-RPC endpoint reporting its performance",
+	Flags: []cli.Flag{
+		&utils.RpcRecordingFileFlag,
+		&utils.RpcTargetFlag,
+		&utils.WorkersFlag,
+		&utils.RpcTimeScaleFlag,
+		&utils.RpcEstimateGasToleranceFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The load command sends requests of the --rpc-recording to the --rpc-target endpoint
+using --workers concurrent connections, each waiting for the response before sending
+the next request. If --rpc-time-scale is set, requests are paced according to the
+scaled intervals between their recorded timestamps. Requests to the latest block
+are pinned to the recorded block and responses are compared with the recorded ones.
+Throughput, latency percentiles and mismatch counts are reported at the end.`,
+}
+
+// Load replays the recording against the target endpoint.
+func Load(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+
+	files, err := recordingFiles(cfg.RpcRecordingPath)
+	if err != nil {
+		return err
+	}
+
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	g := newLoadGenerator(cfg.RpcTargetUrl, workers, cfg.RpcTimeScale, cfg.RpcEstimateGasTolerance)
+	stats, err := g.run(ctx.Context, files)
+	if stats != nil {
+		stats.print(logger.NewLogger(cfg.LogLevel, "rpc-load"))
+	}
+	return err
+}
+
+// recordingFiles returns the recording file or all files within the recording directory.
+func recordingFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat the rpc path; %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := utils.GetFilesWithinDirectories("", []string{path})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get files from dir %v; %w", path, err)
+	}
+	return files, nil
+}
+
+// loadGenerator sends recorded requests to the target endpoint.
+type loadGenerator struct {
+	target    string
+	workers   int
+	timeScale float64
+	tolerance float64
+	client    *http.Client
+}
+
+func newLoadGenerator(target string, workers int, timeScale, tolerance float64) *loadGenerator {
+	return &loadGenerator{
+		target:    target,
+		workers:   workers,
+		timeScale: timeScale,
+		tolerance: tolerance,
+		client:    &http.Client{Timeout: time.Minute},
+	}
+}
+
+// run replays all requests of given recording files and returns the collected statistics.
+func (g *loadGenerator) run(ctx context.Context, files []string) (*loadStats, error) {
+	stats := newLoadStats()
+	requests := make(chan *rpc.RequestAndResults)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < g.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range requests {
+				g.process(req, stats)
+			}
+		}()
+	}
+
+	stats.start = time.Now()
+	err := g.dispatch(ctx, files, requests, stats.start)
+	close(requests)
+	wg.Wait()
+	stats.duration = time.Since(stats.start)
+
+	return stats, err
+}
+
+// dispatch reads the recording and passes requests to the workers, pacing them if time scale is set.
+func (g *loadGenerator) dispatch(ctx context.Context, files []string, requests chan<- *rpc.RequestAndResults, start time.Time) error {
+	var first uint64
+	for _, file := range files {
+		iter, err := rpc.NewFileReader(ctx, file)
+		if err != nil {
+			return fmt.Errorf("cannot open rpc recording file %v; %w", file, err)
+		}
+
+		for iter.Next() {
+			req := iter.Value()
+			req.DecodeInfo()
+
+			if g.timeScale > 0 {
+				ts := recordedTimestamp(req)
+				if first == 0 {
+					first = ts
+				}
+				if delay := paceDelay(first, ts, g.timeScale, time.Since(start)); delay > 0 {
+					time.Sleep(delay)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				iter.Close()
+				return ctx.Err()
+			case requests <- req:
+			}
+		}
+
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return fmt.Errorf("cannot read rpc recording file %v; %w", file, err)
+		}
+	}
+	return nil
+}
+
+// process sends the request to the target and compares the response with the recording.
+func (g *loadGenerator) process(req *rpc.RequestAndResults, stats *loadStats) {
+	start := time.Now()
+	resp, err := g.send(req)
+	latency := time.Since(start)
+	if err != nil {
+		stats.add(req.Query.MethodBase, latency, false, err)
+		return
+	}
+
+	var mismatch error
+	if res := decodeLoadResult(req.Query.MethodBase, resp); res != nil && !req.SkipValidation {
+		mismatch = validator.CompareRpcResult(res, req, g.tolerance)
+	}
+	stats.add(req.Query.MethodBase, latency, mismatch != nil, nil)
+}
+
+// send the request to the target, requests to the latest block are pinned to the recorded block.
+func (g *loadGenerator) send(req *rpc.RequestAndResults) (*proxyMessage, error) {
+	params := make([]interface{}, len(req.Query.Params))
+	copy(params, req.Query.Params)
+	if l := len(params); l >= 2 {
+		if tag, ok := params[l-1].(string); ok && (tag == "latest" || tag == "pending") {
+			params[l-1] = hexutil.EncodeUint64(uint64(req.RequestedBlock))
+		}
+	}
+
+	payload, err := json.Marshal(utils.JsonRPCRequest{
+		Method:  req.Query.Method,
+		Params:  params,
+		ID:      1,
+		JSONRPC: "2.0",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal request; %w", err)
+	}
+
+	resp, err := g.client.Post(g.target, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+
+	msgs, err := decodeProxyMessages(body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode response; %w", err)
+	}
+	if len(msgs) != 1 {
+		return nil, errors.New("unexpected batch response")
+	}
+	return msgs[0], nil
+}
+
+// decodeLoadResult converts JSON-RPC response into the result representation used by the comparator.
+// Nil is returned for responses of methods which are not compared.
+func decodeLoadResult(method string, resp *proxyMessage) txcontext.Result {
+	if resp.Error != nil {
+		return rpc.NewResult(nil, errors.New(resp.Error.Message), 0)
+	}
+
+	switch method {
+	case "getBalance":
+		var v hexutil.Big
+		if err := v.UnmarshalJSON(resp.Result); err != nil {
+			return nil
+		}
+		return rpc.NewResult(v.ToInt().Bytes(), nil, 0)
+	case "getTransactionCount", "estimateGas":
+		var v hexutil.Uint64
+		if err := v.UnmarshalJSON(resp.Result); err != nil {
+			return nil
+		}
+		return rpc.NewResult(littleendian.Uint64ToBytes(uint64(v)), nil, 0)
+	case "getCode", "call", "getStorageAt":
+		var v hexutil.Bytes
+		if err := v.UnmarshalJSON(resp.Result); err != nil {
+			return nil
+		}
+		if v == nil {
+			v = hexutil.Bytes{}
+		}
+		return rpc.NewResult(v, nil, 0)
+	case "getLogs":
+		return rpc.NewResult(resp.Result, nil, 0)
+	default:
+		return nil
+	}
+}
+
+// recordedTimestamp returns timestamp of the recorded block in nanoseconds.
+func recordedTimestamp(req *rpc.RequestAndResults) uint64 {
+	if req.Response != nil {
+		return req.Response.Timestamp
+	}
+	return req.Error.Timestamp
+}
+
+// paceDelay returns how long to wait before sending request recorded at given timestamp.
+func paceDelay(first, ts uint64, scale float64, elapsed time.Duration) time.Duration {
+	if ts <= first {
+		return 0
+	}
+	return time.Duration(float64(ts-first)*scale) - elapsed
+}
+
+// loadStats collects results of the load replay.
+type loadStats struct {
+	lock      sync.Mutex
+	start     time.Time
+	duration  time.Duration
+	latencies []time.Duration
+	methods   map[string]*loadMethodStats
+}
+
+type loadMethodStats struct {
+	count      uint64
+	failures   uint64 // requests which could not be sent or answered
+	mismatches uint64 // responses not matching the recording
+}
+
+func newLoadStats() *loadStats {
+	return &loadStats{
+		methods: make(map[string]*loadMethodStats),
+	}
+}
+
+func (s *loadStats) add(method string, latency time.Duration, mismatch bool, failure error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	m, ok := s.methods[method]
+	if !ok {
+		m = new(loadMethodStats)
+		s.methods[method] = m
+	}
+
+	m.count++
+	switch {
+	case failure != nil:
+		m.failures++
+	case mismatch:
+		m.mismatches++
+	}
+	s.latencies = append(s.latencies, latency)
+}
+
+// percentile returns latency below which given fraction (0-1) of requests were answered.
+func (s *loadStats) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(s.latencies))*p+0.5) - 1
+	i = max(0, min(i, len(s.latencies)-1))
+	return s.latencies[i]
+}
+
+// print logs the summary of the replay.
+func (s *loadStats) print(log logger.Logger) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	total := len(s.latencies)
+
+	log.Noticef("Replayed %v requests in %v (%.2f req/s)", total, s.duration.Round(time.Millisecond), float64(total)/s.duration.Seconds())
+	log.Noticef("Latency p50 %v, p90 %v, p99 %v, max %v", s.percentile(0.5), s.percentile(0.9), s.percentile(0.99), s.percentile(1))
+
+	names := make([]string, 0, len(s.methods))
+	for m := range s.methods {
+		names = append(names, m)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := s.methods[name]
+		log.Noticef("\t%v: count %v, mismatches %v, failures %v", name, m.count, m.mismatches, m.failures)
+	}
+}
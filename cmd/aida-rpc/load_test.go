@@ -0,0 +1,120 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/rpc"
+)
+
+func TestLoadGenerator_RequestsAreReplayedAndCompared(t *testing.T) {
+	var blocks []interface{}
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot decode request; %v", err)
+			return
+		}
+		blocks = append(blocks, req.Params[len(req.Params)-1])
+
+		res := `"0x64"`
+		if req.Method == "eth_getCode" {
+			res = `"0x01"`
+		}
+		_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":1,"result":`+res+`}`)
+	}))
+	defer target.Close()
+
+	dir := t.TempDir()
+	writer, err := rpc.NewFileWriter(dir, 1_000_000, rpc.HeaderV2)
+	if err != nil {
+		t.Fatalf("cannot create writer; %v", err)
+	}
+	records := []*rpc.RequestAndResults{
+		{
+			Query:     &rpc.Body{Namespace: "eth", MethodBase: "getBalance"},
+			ParamsRaw: []byte(`["0x1","latest"]`),
+			Response:  &rpc.Response{BlockID: 5, Result: json.RawMessage(`"0x64"`)},
+		},
+		{
+			Query:     &rpc.Body{Namespace: "eth", MethodBase: "getCode"},
+			ParamsRaw: []byte(`["0x1","0x3"]`),
+			Response:  &rpc.Response{BlockID: 5, Result: json.RawMessage(`"0x02"`)},
+		},
+	}
+	for _, rec := range records {
+		if err = writer.Write(rec); err != nil {
+			t.Fatalf("cannot write record; %v", err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatalf("cannot close writer; %v", err)
+	}
+
+	// a single worker keeps the order of requests
+	g := newLoadGenerator(target.URL, 1, 0, 0)
+	stats, err := g.run(context.Background(), []string{filepath.Join(dir, "rpc-recording-000001.dat")})
+	if err != nil {
+		t.Fatalf("cannot run load; %v", err)
+	}
+
+	if got, want := len(stats.latencies), 2; got != want {
+		t.Fatalf("unexpected number of requests, got %v, want %v", got, want)
+	}
+	if m := stats.methods["getBalance"]; m.count != 1 || m.mismatches != 0 || m.failures != 0 {
+		t.Errorf("unexpected getBalance stats %+v", m)
+	}
+	if m := stats.methods["getCode"]; m.count != 1 || m.mismatches != 1 {
+		t.Errorf("unexpected getCode stats %+v", m)
+	}
+	if len(blocks) != 2 || blocks[0] != "0x5" || blocks[1] != "0x3" {
+		t.Errorf("latest block must be pinned to the recorded block, got %v", blocks)
+	}
+}
+
+func TestPaceDelay_ScalesRecordedIntervals(t *testing.T) {
+	first := uint64(10 * time.Second)
+	tests := []struct {
+		ts      uint64
+		scale   float64
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{first, 1, 0, 0},
+		{first + uint64(time.Second), 1, 0, time.Second},
+		{first + uint64(time.Second), 0.5, 0, 500 * time.Millisecond},
+		{first + uint64(time.Second), 1, 300 * time.Millisecond, 700 * time.Millisecond},
+		{first - 1, 1, 0, 0},
+	}
+
+	for _, test := range tests {
+		if got := paceDelay(first, test.ts, test.scale, test.elapsed); got != test.want {
+			t.Errorf("unexpected delay, got %v, want %v", got, test.want)
+		}
+	}
+}
@@ -32,9 +32,21 @@ func main() {
 		Usage: "Sends real API requests recorded on rpcapi.fantom.network to StateDB then compares recorded" +
 			"result with result returned by DB.",
 		Copyright: "(c) 2023 Fantom Foundation",
+		Commands: []*cli.Command{
+			&RecordProxyCommand,
+			&ServeCommand,
+			&LoadCommand,
+		},
 		Flags: []cli.Flag{
 			&utils.RpcRecordingFileFlag,
 			&utils.WorkersFlag,
+			&utils.AidaDbFlag,
+			&utils.RpcEstimateGasFlag,
+			&utils.RpcEstimateGasToleranceFlag,
+			&utils.RpcReferenceFlag,
+			&utils.RpcReferenceCacheFlag,
+			&utils.RpcOfflineFlag,
+			&utils.RpcRulesFlag,
 
 			// VM
 			&utils.VmImplementation,
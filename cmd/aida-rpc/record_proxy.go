@@ -0,0 +1,339 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/urfave/cli/v2"
+)
+
+// blockRefreshInterval defines how long the latest block of the target is cached by the proxy.
+const blockRefreshInterval = 200 * time.Millisecond
+
+// RecordProxyCommand runs a JSON-RPC reverse proxy recording all forwarded requests.
+var RecordProxyCommand = cli.Command{
+	Action: RecordProxy,
+	Name:   "record-proxy",
+	Usage:  "runs JSON-RPC proxy in front of an endpoint recording requests and responses",
+	Flags: []cli.Flag{
+		&utils.RpcTargetFlag,
+		&utils.RpcListenFlag,
+		&utils.RpcRecordingFileFlag,
+		&utils.RpcRecordingMaxSizeFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The record-proxy command forwards all JSON-RPC requests received on --rpc-listen
+to the --rpc-target endpoint. Request and response pairs of recordable methods are
+written together with the latest block of the target into the --rpc-recording
+directory. Files are rotated once they exceed --rpc-recording-max-size MB,
+so the directory can be replayed directly by aida-rpc.`,
+}
+
+// RecordProxy runs the recording proxy until it is interrupted.
+func RecordProxy(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	if cfg.RpcRecordingPath == "" {
+		return fmt.Errorf("output directory must be specified with --%v", utils.RpcRecordingFileFlag.Name)
+	}
+
+	writer, err := rpc.NewFileWriter(cfg.RpcRecordingPath, int64(cfg.RpcRecordingMaxSize)*1_000_000, rpc.HeaderV2)
+	if err != nil {
+		return err
+	}
+
+	log := logger.NewLogger(cfg.LogLevel, "record-proxy")
+	server := &http.Server{
+		Addr:    cfg.RpcListenAddress,
+		Handler: newRecordProxy(cfg.RpcTargetUrl, writer, log),
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Noticef("Recording requests to %v on %v into %v", cfg.RpcTargetUrl, cfg.RpcListenAddress, cfg.RpcRecordingPath)
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	return errors.Join(err, writer.Close())
+}
+
+// recordProxy forwards JSON-RPC requests to the target endpoint and records them.
+type recordProxy struct {
+	target string
+	client *http.Client
+	writer *rpc.FileWriter
+	blocks *blockTracker
+	log    logger.Logger
+}
+
+func newRecordProxy(target string, writer *rpc.FileWriter, log logger.Logger) *recordProxy {
+	client := &http.Client{Timeout: time.Minute}
+	return &recordProxy{
+		target: target,
+		client: client,
+		writer: writer,
+		blocks: &blockTracker{target: target, client: client},
+		log:    log,
+	}
+}
+
+// ServeHTTP forwards the request unchanged and passes the response back to the client.
+// The request is recorded only after the response was delivered.
+func (p *recordProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "cannot read request", http.StatusBadRequest)
+		return
+	}
+
+	// the latest block is resolved before forwarding since it is the state the request is executed at
+	block, blockErr := p.blocks.latest()
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, p.target, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, "cannot create request", http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.log.Warningf("Cannot forward request; %v", err)
+		http.Error(w, "cannot reach target", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		p.log.Warningf("Cannot read response; %v", err)
+		http.Error(w, "cannot read response", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(respBody)
+
+	if blockErr != nil {
+		p.log.Warningf("Request not recorded, cannot get latest block; %v", blockErr)
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	p.record(body, respBody, block)
+}
+
+// record matches requests with their responses and writes recordable ones into the recording.
+func (p *recordProxy) record(reqBody, respBody []byte, block blockInfo) {
+	requests, err := decodeProxyMessages(reqBody)
+	if err != nil {
+		p.log.Debugf("Cannot decode request; %v", err)
+		return
+	}
+	responses, err := decodeProxyMessages(respBody)
+	if err != nil {
+		p.log.Debugf("Cannot decode response; %v", err)
+		return
+	}
+
+	byId := make(map[string]*proxyMessage, len(responses))
+	for _, resp := range responses {
+		byId[string(resp.ID)] = resp
+	}
+
+	for _, req := range requests {
+		resp, ok := byId[string(req.ID)]
+		// notifications have no response
+		if !ok {
+			continue
+		}
+
+		rec := req.toRecord(resp, block)
+		if rec == nil {
+			continue
+		}
+		if err = p.writer.Write(rec); err != nil {
+			p.log.Errorf("Cannot record %v; %v", req.Method, err)
+		}
+	}
+}
+
+// proxyMessage is a JSON-RPC request or response passing through the proxy.
+type proxyMessage struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params json.RawMessage   `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  *rpc.ErrorMessage `json:"error"`
+}
+
+// decodeProxyMessages decodes both single and batch JSON-RPC messages.
+func decodeProxyMessages(data []byte) ([]*proxyMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var batch []*proxyMessage
+		err := json.Unmarshal(data, &batch)
+		return batch, err
+	}
+
+	msg := new(proxyMessage)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return []*proxyMessage{msg}, nil
+}
+
+// toRecord creates recording of the request with given response.
+// Nil is returned for methods that cannot be recorded.
+func (m *proxyMessage) toRecord(resp *proxyMessage, block blockInfo) *rpc.RequestAndResults {
+	namespace, method, found := strings.Cut(m.Method, "_")
+	if !found || !rpc.CanRecordVersion(rpc.HeaderV2, namespace, method) {
+		return nil
+	}
+
+	// only positional params are supported by the recording
+	params := bytes.TrimSpace(m.Params)
+	if len(params) > 0 && params[0] != '[' {
+		return nil
+	}
+
+	rec := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Namespace:  namespace,
+			MethodBase: method,
+		},
+		ParamsRaw: params,
+	}
+	if resp.Error != nil {
+		rec.Error = &rpc.ErrorResponse{
+			BlockID:   block.number,
+			Timestamp: block.timestamp,
+			Error:     *resp.Error,
+		}
+	} else {
+		rec.Response = &rpc.Response{
+			BlockID:   block.number,
+			Timestamp: block.timestamp,
+			Result:    resp.Result,
+		}
+	}
+	return rec
+}
+
+// blockInfo identifies a block, timestamp is in nanoseconds as expected by the recording.
+type blockInfo struct {
+	number    uint64
+	timestamp uint64
+}
+
+// blockTracker caches the latest block of the target endpoint.
+type blockTracker struct {
+	target    string
+	client    *http.Client
+	mu        sync.Mutex
+	block     blockInfo
+	refreshed time.Time
+}
+
+// latest returns the latest block of the target, refreshing it if the cached one is outdated.
+func (t *blockTracker) latest() (blockInfo, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if time.Since(t.refreshed) < blockRefreshInterval {
+		return t.block, nil
+	}
+
+	block, err := t.fetch()
+	if err != nil {
+		return blockInfo{}, err
+	}
+	t.block = block
+	t.refreshed = time.Now()
+	return block, nil
+}
+
+func (t *blockTracker) fetch() (blockInfo, error) {
+	payload, err := json.Marshal(utils.JsonRPCRequest{
+		Method:  "eth_getBlockByNumber",
+		Params:  []interface{}{"latest", false},
+		ID:      1,
+		JSONRPC: "2.0",
+	})
+	if err != nil {
+		return blockInfo{}, err
+	}
+
+	resp, err := t.client.Post(t.target, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return blockInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var m struct {
+		Result *struct {
+			Number        hexutil.Uint64  `json:"number"`
+			Timestamp     hexutil.Uint64  `json:"timestamp"`
+			TimestampNano *hexutil.Uint64 `json:"timestampNano"`
+		} `json:"result"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return blockInfo{}, fmt.Errorf("cannot decode latest block; %w", err)
+	}
+	if m.Result == nil {
+		return blockInfo{}, errors.New("latest block not found")
+	}
+
+	block := blockInfo{
+		number:    uint64(m.Result.Number),
+		timestamp: uint64(m.Result.Timestamp) * uint64(time.Second),
+	}
+	// Opera and Sonic nodes provide the timestamp with nanosecond precision
+	if m.Result.TimestampNano != nil {
+		block.timestamp = uint64(*m.Result.TimestampNano)
+	}
+	return block, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+)
+
+// newStubTarget creates JSON-RPC server answering every request with given result.
+func newStubTarget(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot decode request; %v", err)
+			return
+		}
+
+		var res string
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			res = `{"number":"0xa","timestamp":"0x5"}`
+		case "eth_getBalance":
+			res = `"0x64"`
+		default:
+			_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":`+string(req.ID)+`,"error":{"code":-32601,"message":"not found"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":`+string(req.ID)+`,"result":`+res+`}`)
+	}))
+}
+
+func TestRecordProxy_RequestsAreForwardedAndRecorded(t *testing.T) {
+	target := newStubTarget(t)
+	defer target.Close()
+
+	dir := t.TempDir()
+	writer, err := rpc.NewFileWriter(dir, 1_000_000, rpc.HeaderV2)
+	if err != nil {
+		t.Fatalf("cannot create writer; %v", err)
+	}
+
+	proxy := httptest.NewServer(newRecordProxy(target.URL, writer, logger.NewLogger("critical", "test")))
+	defer proxy.Close()
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x1","latest"]}`,
+		`{"jsonrpc":"2.0","id":2,"method":"eth_getCode","params":["0x1","latest"]}`,
+		// unknown methods are forwarded but not recorded
+		`{"jsonrpc":"2.0","id":3,"method":"web3_clientVersion","params":[]}`,
+	}
+	for _, req := range requests {
+		resp, err := http.Post(proxy.URL, "application/json", strings.NewReader(req))
+		if err != nil {
+			t.Fatalf("cannot send request; %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if !strings.Contains(string(body), `"jsonrpc":"2.0"`) {
+			t.Errorf("unexpected response %s", body)
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatalf("cannot close writer; %v", err)
+	}
+
+	iter, err := rpc.NewFileReader(context.Background(), filepath.Join(dir, "rpc-recording-000001.dat"))
+	if err != nil {
+		t.Fatalf("cannot open recording; %v", err)
+	}
+	defer iter.Close()
+
+	var recs []*rpc.RequestAndResults
+	for iter.Next() {
+		recs = append(recs, iter.Value())
+	}
+	if got, want := len(recs), 2; got != want {
+		t.Fatalf("unexpected number of records, got %v, want %v", got, want)
+	}
+
+	if recs[0].Query.Method != "eth_getBalance" || recs[0].Response == nil || string(recs[0].Response.Result) != `"0x64"` {
+		t.Errorf("unexpected first record %v", recs[0].Query.Method)
+	}
+	recs[0].DecodeInfo()
+	if recs[0].RecordedBlock != 10 || recs[0].Timestamp != 5 {
+		t.Errorf("unexpected block %v and timestamp %v", recs[0].RecordedBlock, recs[0].Timestamp)
+	}
+
+	if recs[1].Query.Method != "eth_getCode" || recs[1].Error == nil || recs[1].Error.Error.Code != -32601 {
+		t.Errorf("unexpected second record %v", recs[1].Query.Method)
+	}
+}
+
+func TestDecodeProxyMessages_DecodesBatch(t *testing.T) {
+	msgs, err := decodeProxyMessages([]byte(` [{"id":1,"method":"eth_chainId"},{"id":2,"method":"eth_blockNumber"}]`))
+	if err != nil {
+		t.Fatalf("cannot decode batch; %v", err)
+	}
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("unexpected number of messages, got %v, want %v", got, want)
+	}
+	if msgs[1].Method != "eth_blockNumber" {
+		t.Errorf("unexpected method %v", msgs[1].Method)
+	}
+}
@@ -86,7 +86,7 @@ func TestRpc_AllDbEventsAreIssuedInOrder_Sequential(t *testing.T) {
 		archiveFour.EXPECT().Release(),
 	)
 
-	if err := run(cfg, provider, db, rpcProcessor{cfg}, nil); err != nil {
+	if err := run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil); err != nil {
 		t.Errorf("run failed: %v", err)
 	}
 }
@@ -141,7 +141,7 @@ func TestRpc_AllDbEventsAreIssuedInOrder_Parallel(t *testing.T) {
 		archiveThree.EXPECT().Release(),
 	)
 
-	if err := run(cfg, provider, db, rpcProcessor{cfg}, nil); err != nil {
+	if err := run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil); err != nil {
 		t.Errorf("run failed: %v", err)
 	}
 }
@@ -326,7 +326,7 @@ func TestRpc_ValidationDoesNotFailOnValidTransaction_Sequential(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err != nil {
 		t.Errorf("run must not fail")
 	}
@@ -361,7 +361,7 @@ func TestRpc_ValidationDoesNotFailOnValidTransaction_Parallel(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err != nil {
 		t.Errorf("run must not fail")
 	}
@@ -395,7 +395,7 @@ func TestRpc_ValidationFailsOnValidTransaction_Sequential(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err == nil {
 		t.Errorf("run must fail")
 	}
@@ -434,7 +434,7 @@ func TestRpc_ValidationFailsOnValidTransaction_Parallel(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err == nil {
 		t.Errorf("run must fail")
 	}
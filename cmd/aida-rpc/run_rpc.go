@@ -17,6 +17,7 @@
 package main
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Fantom-foundation/Aida/executor"
@@ -29,6 +30,7 @@ import (
 	"github.com/Fantom-foundation/Aida/rpc"
 	"github.com/Fantom-foundation/Aida/state"
 	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
 	"github.com/urfave/cli/v2"
 )
 
@@ -51,22 +53,37 @@ func RunRpc(ctx *cli.Context) error {
 
 	defer rpcSource.Close()
 
-	return run(cfg, rpcSource, nil, makeRpcProcessor(cfg), nil)
+	// logs for getLogs requests are reconstructed from substates
+	var logs rpc.LogReader
+	if cfg.AidaDb != "" {
+		sdb, err := db.NewReadOnlySubstateDB(cfg.AidaDb)
+		if err != nil {
+			return fmt.Errorf("cannot open aida-db; %w", err)
+		}
+		defer sdb.Close()
+		logs = rpc.NewSubstateLogReader(sdb)
+	}
+
+	return run(cfg, rpcSource, nil, makeRpcProcessor(cfg, logs), nil)
 }
 
-func makeRpcProcessor(cfg *utils.Config) rpcProcessor {
+func makeRpcProcessor(cfg *utils.Config, logs rpc.LogReader) rpcProcessor {
 	return rpcProcessor{
-		cfg: cfg,
+		cfg:  cfg,
+		logs: logs,
 	}
 }
 
 type rpcProcessor struct {
-	cfg *utils.Config
+	cfg  *utils.Config
+	logs rpc.LogReader
 }
 
 func (p rpcProcessor) Process(state executor.State[*rpc.RequestAndResults], ctx *executor.Context) error {
 	var err error
-	ctx.ExecutionResult, err = rpc.Execute(uint64(state.Block), state.Data, ctx.Archive, p.cfg)
+	start := time.Now()
+	ctx.ExecutionResult, err = rpc.Execute(uint64(state.Block), state.Data, ctx.Archive, p.logs, p.cfg)
+	state.Data.ExecutionTime = time.Since(start)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,393 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/urfave/cli/v2"
+)
+
+// JSON-RPC error codes returned by the server.
+const (
+	methodNotFoundErrCode = -32601
+	invalidParamsErrCode  = -32602
+	executionErrCode      = -32000
+)
+
+// servedMethods maps methods answered from the archive to the position of their block parameter.
+var servedMethods = map[string]int{
+	"getBalance":          1,
+	"getTransactionCount": 1,
+	"getCode":             1,
+	"getStorageAt":        2,
+	"call":                1,
+	"estimateGas":         1,
+}
+
+// ServeCommand answers JSON-RPC queries directly from an archive StateDB.
+var ServeCommand = cli.Command{
+	Action: Serve,
+	Name:   "serve",
+	Usage:  "answers JSON-RPC queries over HTTP directly from an archive StateDB",
+	Flags: []cli.Flag{
+		&utils.StateDbSrcFlag,
+		&utils.RpcListenFlag,
+		&utils.ChainIDFlag,
+		&utils.VmImplementation,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The serve command opens the archive of --db-src and answers eth_getBalance,
+eth_getTransactionCount, eth_getCode, eth_getStorageAt, eth_call and eth_estimateGas
+(as well as their ftm_ aliases) on --rpc-listen. Block tags are resolved against
+the last block of the archive. Block headers are not part of the archive,
+hence the EVM uses the time of serving as block timestamp.`,
+}
+
+// Serve runs the JSON-RPC server until it is interrupted.
+func Serve(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	if cfg.StateDbSrc == "" {
+		return fmt.Errorf("archive must be specified with --%v", utils.StateDbSrcFlag.Name)
+	}
+
+	cfg.SetStateDbSrcReadOnly()
+	// estimations are served against the requested block
+	cfg.RpcEstimateGas = true
+
+	db, _, err := utils.PrepareStateDB(cfg)
+	if err != nil {
+		return fmt.Errorf("cannot open archive; %w", err)
+	}
+	defer db.Close()
+
+	log := logger.NewLogger(cfg.LogLevel, "rpc-serve")
+	server := &http.Server{
+		Addr:    cfg.RpcListenAddress,
+		Handler: newArchiveServer(db, cfg, log),
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Noticef("Serving archive %v on %v", cfg.StateDbSrc, cfg.RpcListenAddress)
+	if err = server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// archiveServer answers JSON-RPC requests using archive states of the StateDB.
+type archiveServer struct {
+	db  state.StateDB
+	cfg *utils.Config
+	log logger.Logger
+}
+
+func newArchiveServer(db state.StateDB, cfg *utils.Config, log logger.Logger) *archiveServer {
+	return &archiveServer{
+		db:  db,
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// serveResponse is a JSON-RPC response produced by the archiveServer.
+type serveResponse struct {
+	Version string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Result  interface{}       `json:"result,omitempty"`
+	Error   *rpc.ErrorMessage `json:"error,omitempty"`
+}
+
+// ServeHTTP answers both single and batch requests.
+func (s *archiveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "cannot read request", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := decodeProxyMessages(body)
+	if err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+
+	responses := make([]*serveResponse, 0, len(requests))
+	for _, req := range requests {
+		res, msg := s.handle(req)
+		responses = append(responses, &serveResponse{
+			Version: "2.0",
+			ID:      req.ID,
+			Result:  res,
+			Error:   msg,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		err = enc.Encode(responses)
+	} else {
+		err = enc.Encode(responses[0])
+	}
+	if err != nil {
+		s.log.Warningf("Cannot write response; %v", err)
+	}
+}
+
+// handle executes single request returning either its result or an error.
+func (s *archiveServer) handle(req *proxyMessage) (interface{}, *rpc.ErrorMessage) {
+	namespace, method, _ := strings.Cut(req.Method, "_")
+	if namespace != "eth" && namespace != "ftm" {
+		return nil, &rpc.ErrorMessage{Code: methodNotFoundErrCode, Message: fmt.Sprintf("method %v not found", req.Method)}
+	}
+
+	switch method {
+	case "chainId":
+		return hexutil.Uint64(s.cfg.ChainID), nil
+	case "blockNumber":
+		height, err := s.archiveHeight()
+		if err != nil {
+			return nil, &rpc.ErrorMessage{Code: executionErrCode, Message: err.Error()}
+		}
+		return hexutil.Uint64(height), nil
+	}
+
+	blockIdx, ok := servedMethods[method]
+	if !ok {
+		return nil, &rpc.ErrorMessage{Code: methodNotFoundErrCode, Message: fmt.Sprintf("method %v not found", req.Method)}
+	}
+
+	var params []interface{}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpc.ErrorMessage{Code: invalidParamsErrCode, Message: fmt.Sprintf("invalid params; %v", err)}
+		}
+	}
+	if err := validateServeParams(method, params); err != nil {
+		return nil, &rpc.ErrorMessage{Code: invalidParamsErrCode, Message: err.Error()}
+	}
+
+	var blockParam interface{}
+	if len(params) > blockIdx {
+		blockParam = params[blockIdx]
+	}
+	block, err := s.resolveBlock(blockParam)
+	if err != nil {
+		return nil, &rpc.ErrorMessage{Code: invalidParamsErrCode, Message: err.Error()}
+	}
+
+	result, err := s.execute(block, &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method:     req.Method,
+			Params:     params,
+			Namespace:  namespace,
+			MethodBase: method,
+		},
+		RecordedBlock:  int(block),
+		RequestedBlock: int(block),
+		Timestamp:      uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		return nil, &rpc.ErrorMessage{Code: executionErrCode, Message: err.Error()}
+	}
+
+	return encodeServeResult(method, result)
+}
+
+// execute runs the request against archive state of given block.
+func (s *archiveServer) execute(block uint64, rec *rpc.RequestAndResults) (txcontext.Result, error) {
+	archive, err := s.db.GetArchiveState(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get archive of block %v; %w", block, err)
+	}
+	defer archive.Release()
+
+	if err = archive.BeginTransaction(0); err != nil {
+		return nil, fmt.Errorf("cannot begin transaction; %w", err)
+	}
+	defer archive.EndTransaction()
+
+	return rpc.Execute(block, rec, archive, nil, s.cfg)
+}
+
+// archiveHeight returns the last block available in the archive.
+func (s *archiveServer) archiveHeight() (uint64, error) {
+	height, empty, err := s.db.GetArchiveBlockHeight()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get archive block height; %w", err)
+	}
+	if empty {
+		return 0, errors.New("archive is empty")
+	}
+	return height, nil
+}
+
+// resolveBlock converts block number or block tag into a block available in the archive.
+// Missing block parameter defaults to the latest block.
+func (s *archiveServer) resolveBlock(param interface{}) (uint64, error) {
+	height, err := s.archiveHeight()
+	if err != nil {
+		return 0, err
+	}
+
+	// block can be specified as an object as well (EIP-1898)
+	if m, ok := param.(map[string]interface{}); ok {
+		if _, ok = m["blockHash"]; ok {
+			return 0, errors.New("blocks cannot be specified by hash")
+		}
+		param = m["blockNumber"]
+	}
+
+	switch p := param.(type) {
+	case nil:
+		return height, nil
+	case string:
+		switch p {
+		case "latest", "pending", "safe", "finalized":
+			return height, nil
+		case "earliest":
+			return 0, nil
+		}
+		block, err := hexutil.DecodeUint64(p)
+		if err != nil {
+			return 0, fmt.Errorf("invalid block %v; %w", p, err)
+		}
+		if block > height {
+			return 0, fmt.Errorf("block %v is not available in the archive, last block is %v", block, height)
+		}
+		return block, nil
+	default:
+		return 0, fmt.Errorf("unexpected block type %T", param)
+	}
+}
+
+// validateServeParams checks types of leading params which are expected by rpc.Execute.
+func validateServeParams(method string, params []interface{}) error {
+	switch method {
+	case "call", "estimateGas":
+		if len(params) < 1 {
+			return errors.New("missing transaction arguments")
+		}
+		args, ok := params[0].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected transaction arguments type %T", params[0])
+		}
+		return validateTxArgs(args)
+	case "getStorageAt":
+		if len(params) < 2 {
+			return errors.New("missing address or storage key")
+		}
+		for _, p := range params[:2] {
+			if _, ok := p.(string); !ok {
+				return fmt.Errorf("unexpected param type %T", p)
+			}
+		}
+	default:
+		if len(params) < 1 {
+			return errors.New("missing address")
+		}
+		if _, ok := params[0].(string); !ok {
+			return fmt.Errorf("unexpected address type %T", params[0])
+		}
+	}
+	return nil
+}
+
+// validateTxArgs checks fields of transaction arguments decoded by rpc.Execute.
+func validateTxArgs(args map[string]interface{}) error {
+	for _, field := range []string{"from", "to", "value", "gas", "gasPrice", "data"} {
+		v, ok := args[field]
+		if !ok || v == nil {
+			continue
+		}
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T of field %v", v, field)
+		}
+
+		hex := strings.TrimPrefix(str, "0x")
+		switch field {
+		case "from", "to":
+			if !common.IsHexAddress(str) {
+				return fmt.Errorf("invalid address %q of field %v", str, field)
+			}
+		case "data":
+			if _, err := hexutil.Decode("0x" + hex); err != nil {
+				return fmt.Errorf("invalid field %v; %w", field, err)
+			}
+		default:
+			if _, ok = new(big.Int).SetString(hex, 16); !ok {
+				return fmt.Errorf("invalid hex number %q of field %v", str, field)
+			}
+		}
+	}
+	return nil
+}
+
+// encodeServeResult converts result of rpc.Execute into its JSON-RPC representation.
+func encodeServeResult(method string, result txcontext.Result) (interface{}, *rpc.ErrorMessage) {
+	if result == nil {
+		return nil, &rpc.ErrorMessage{Code: executionErrCode, Message: "request cannot be executed"}
+	}
+
+	res, err := result.GetRawResult()
+	if err != nil {
+		return nil, &rpc.ErrorMessage{Code: executionErrCode, Message: err.Error()}
+	}
+
+	switch method {
+	case "getBalance":
+		return (*hexutil.Big)(new(big.Int).SetBytes(res)), nil
+	case "getTransactionCount", "estimateGas":
+		return hexutil.Uint64(littleendian.BytesToUint64(res)), nil
+	case "getStorageAt":
+		return common.BytesToHash(res), nil
+	default:
+		return hexutil.Bytes(res), nil
+	}
+}
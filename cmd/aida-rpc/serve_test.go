@@ -0,0 +1,157 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"go.uber.org/mock/gomock"
+)
+
+func TestArchiveServer_ResolveBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil).AnyTimes()
+	s := newArchiveServer(db, nil, logger.NewLogger("critical", "test"))
+
+	tests := []struct {
+		param   interface{}
+		want    uint64
+		wantErr bool
+	}{
+		{nil, 10, false},
+		{"latest", 10, false},
+		{"pending", 10, false},
+		{"earliest", 0, false},
+		{"0x5", 5, false},
+		{map[string]interface{}{"blockNumber": "0x3"}, 3, false},
+		{"0xb", 0, true},
+		{map[string]interface{}{"blockHash": "0x1"}, 0, true},
+		{true, 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := s.resolveBlock(test.param)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("resolving %v must fail", test.param)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("cannot resolve %v; %v", test.param, err)
+		}
+		if got != test.want {
+			t.Errorf("unexpected block of %v, got %v, want %v", test.param, got, test.want)
+		}
+	}
+}
+
+func TestArchiveServer_GetBalanceIsAnsweredFromArchive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+	cfg := utils.NewTestConfig(t, utils.MainnetChainID, 0, 0, false, "")
+
+	gomock.InOrder(
+		db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil),
+		db.EXPECT().GetArchiveState(uint64(5)).Return(archive, nil),
+		archive.EXPECT().BeginTransaction(uint32(0)),
+		archive.EXPECT().GetBalance(common.HexToAddress("0x1")).Return(uint256.NewInt(255)),
+		archive.EXPECT().EndTransaction(),
+		archive.EXPECT().Release(),
+	)
+
+	server := httptest.NewServer(newArchiveServer(db, cfg, logger.NewLogger("critical", "test")))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"eth_getBalance","params":["0x1","0x5"]}`))
+	if err != nil {
+		t.Fatalf("cannot send request; %v", err)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		ID     int    `json:"id"`
+		Result string `json:"result"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("cannot decode response; %v", err)
+	}
+	if res.ID != 7 || res.Result != "0xff" {
+		t.Errorf("unexpected response %+v", res)
+	}
+}
+
+func TestArchiveServer_UnknownMethodReturnsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	s := newArchiveServer(db, nil, logger.NewLogger("critical", "test"))
+
+	_, msg := s.handle(&proxyMessage{Method: "eth_sendRawTransaction"})
+	if msg == nil || msg.Code != methodNotFoundErrCode {
+		t.Errorf("unexpected error %v", msg)
+	}
+}
+
+func TestArchiveServer_InvalidTransactionArgumentsAreRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	s := newArchiveServer(db, nil, logger.NewLogger("critical", "test"))
+
+	tests := []string{
+		`[{"to":1}]`,
+		`[{"to":"0x12"}]`,
+		`[{"from":"0xzz00000000000000000000000000000000000000"}]`,
+		`[{"value":"0xg"}]`,
+		`[{"gas":true}]`,
+		`[{"gasPrice":"0x1.5"}]`,
+		`[{"data":"0x123"}]`,
+		`[{"data":"0xzz"}]`,
+	}
+
+	for _, params := range tests {
+		_, msg := s.handle(&proxyMessage{Method: "eth_call", Params: json.RawMessage(params)})
+		if msg == nil || msg.Code != invalidParamsErrCode {
+			t.Errorf("unexpected error %v of params %v", msg, params)
+		}
+	}
+}
+
+func TestArchiveServer_ValidTransactionArgumentsAreAccepted(t *testing.T) {
+	args := map[string]interface{}{
+		"from":     "0x0000000000000000000000000000000000000001",
+		"to":       "0x0000000000000000000000000000000000000002",
+		"value":    "0x01",
+		"gas":      "0x5208",
+		"gasPrice": nil,
+		"data":     "0xa9059cbb",
+	}
+	if err := validateTxArgs(args); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+}
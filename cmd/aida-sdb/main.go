@@ -36,6 +36,7 @@ func initTraceApp() *cli.App {
 			&RecordCommand,
 			&trace.TraceReplayCommand,
 			&trace.TraceReplaySubstateCommand,
+			&trace.TraceDiffCommand,
 		},
 	}
 }
@@ -45,6 +45,7 @@ var RecordCommand = cli.Command{
 		&utils.ChainIDFlag,
 		&utils.TraceFileFlag,
 		&utils.TraceDebugFlag,
+		&utils.TraceTimingFlag,
 		&utils.DebugFromFlag,
 		&utils.AidaDbFlag,
 		&log.LogLevelFlag,
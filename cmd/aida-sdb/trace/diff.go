@@ -0,0 +1,478 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/holiman/uint256"
+	"github.com/urfave/cli/v2"
+)
+
+// DiffTrace compares the StateDB operations of two storage traces transaction by transaction.
+func DiffTrace(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("trace diff command requires exactly 2 arguments")
+	}
+	log := logger.NewLogger(ctx.String(logger.LogLevelFlag.Name), "Trace-Diff")
+	verbose := ctx.Bool(utils.TraceDebugFlag.Name)
+
+	traceA := ctx.Args().Get(0)
+	traceB := ctx.Args().Get(1)
+	streamA, errA := streamDecodedTransactions(traceA)
+	streamB, errB := streamDecodedTransactions(traceB)
+
+	summary := newTraceDiffSummary()
+	txA, okA := <-streamA
+	txB, okB := <-streamB
+	for okA || okB {
+		var diff transactionDiff
+		switch {
+		case okA && (!okB || txA.before(txB)):
+			diff = diffOperations(txA.operations, nil)
+			diff.block, diff.transaction = txA.block, txA.transaction
+			txA, okA = <-streamA
+		case okB && (!okA || txB.before(txA)):
+			diff = diffOperations(nil, txB.operations)
+			diff.block, diff.transaction = txB.block, txB.transaction
+			txB, okB = <-streamB
+		default:
+			diff = diffOperations(txA.operations, txB.operations)
+			diff.block, diff.transaction = txA.block, txA.transaction
+			txA, okA = <-streamA
+			txB, okB = <-streamB
+		}
+		summary.add(diff)
+		if !diff.isEmpty() {
+			diff.print(verbose)
+		}
+	}
+
+	if err := <-errA; err != nil {
+		return fmt.Errorf("cannot read trace %v; %w", traceA, err)
+	}
+	if err := <-errB; err != nil {
+		return fmt.Errorf("cannot read trace %v; %w", traceB, err)
+	}
+
+	summary.print(log)
+	if summary.differingTransactions > 0 {
+		return fmt.Errorf("traces differ in %v out of %v transactions", summary.differingTransactions, summary.transactions)
+	}
+	return nil
+}
+
+// decodedTransaction contains the decoded StateDB operations of a single transaction.
+type decodedTransaction struct {
+	block       int
+	transaction int
+	operations  []decodedOperation
+}
+
+// before returns true if the transaction precedes the other transaction.
+func (t decodedTransaction) before(other decodedTransaction) bool {
+	if t.block != other.block {
+		return t.block < other.block
+	}
+	return t.transaction < other.transaction
+}
+
+// decodedOperation is a StateDB operation with its decoded arguments.
+type decodedOperation struct {
+	kind      string // name of the StateDB operation
+	arguments string // textual representation of the decoded arguments
+}
+
+func (o decodedOperation) String() string {
+	return fmt.Sprintf("%v(%v)", o.kind, o.arguments)
+}
+
+// streamDecodedTransactions reads a storage trace in a separate goroutine and
+// emits its transactions in order. Transactions are separated in the same way as
+// the operation provider does for replaying traces. The returned error channel
+// yields the result of the reading once the transaction channel is closed.
+func streamDecodedTransactions(traceFile string) (<-chan decodedTransaction, <-chan error) {
+	out := make(chan decodedTransaction, 16)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(out)
+
+		provider, err := executor.OpenOperations(&utils.Config{TraceFile: traceFile, Last: math.MaxUint64 - 1})
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer provider.Close()
+
+		// the replay context has to be kept for the whole trace
+		// since operations are encoded relative to the previous ones
+		rCtx := context.NewReplay()
+		decoder := newOperationDecoder()
+		errs <- provider.Run(0, math.MaxInt, func(info executor.TransactionInfo[[]operation.Operation]) error {
+			decoder.operations = nil
+			for _, op := range info.Data {
+				operation.Execute(op, decoder, rCtx)
+			}
+			out <- decodedTransaction{
+				block:       info.Block,
+				transaction: info.Transaction,
+				operations:  decoder.operations,
+			}
+			return nil
+		})
+	}()
+	return out, errs
+}
+
+// operationDecoder is a StateDB which collects the decoded arguments of the
+// operations executed on it. Only the methods used by storage-trace operations
+// are implemented; calling any other method panics.
+type operationDecoder struct {
+	state.StateDB
+	operations []decodedOperation
+	snapshot   int
+}
+
+func newOperationDecoder() *operationDecoder {
+	return &operationDecoder{}
+}
+
+func (d *operationDecoder) add(kind string, args ...any) {
+	arguments := ""
+	for i, arg := range args {
+		if i > 0 {
+			arguments += ", "
+		}
+		arguments += fmt.Sprint(arg)
+	}
+	d.operations = append(d.operations, decodedOperation{kind: kind, arguments: arguments})
+}
+
+func (d *operationDecoder) CreateAccount(addr common.Address) {
+	d.add("CreateAccount", addr)
+}
+
+func (d *operationDecoder) CreateContract(addr common.Address) {
+	d.add("CreateContract", addr)
+}
+
+func (d *operationDecoder) Exist(addr common.Address) bool {
+	d.add("Exist", addr)
+	return false
+}
+
+func (d *operationDecoder) Empty(addr common.Address) bool {
+	d.add("Empty", addr)
+	return false
+}
+
+func (d *operationDecoder) SelfDestruct(addr common.Address) {
+	d.add("SelfDestruct", addr)
+}
+
+func (d *operationDecoder) Selfdestruct6780(addr common.Address) {
+	d.add("SelfDestruct6780", addr)
+}
+
+func (d *operationDecoder) HasSelfDestructed(addr common.Address) bool {
+	d.add("HasSelfDestructed", addr)
+	return false
+}
+
+func (d *operationDecoder) GetBalance(addr common.Address) *uint256.Int {
+	d.add("GetBalance", addr)
+	return uint256.NewInt(0)
+}
+
+func (d *operationDecoder) AddBalance(addr common.Address, value *uint256.Int, reason tracing.BalanceChangeReason) {
+	d.add("AddBalance", addr, value, reason)
+}
+
+func (d *operationDecoder) SubBalance(addr common.Address, value *uint256.Int, reason tracing.BalanceChangeReason) {
+	d.add("SubBalance", addr, value, reason)
+}
+
+func (d *operationDecoder) GetNonce(addr common.Address) uint64 {
+	d.add("GetNonce", addr)
+	return 0
+}
+
+func (d *operationDecoder) SetNonce(addr common.Address, nonce uint64) {
+	d.add("SetNonce", addr, nonce)
+}
+
+func (d *operationDecoder) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	d.add("GetCommittedState", addr, key)
+	return common.Hash{}
+}
+
+func (d *operationDecoder) GetState(addr common.Address, key common.Hash) common.Hash {
+	d.add("GetState", addr, key)
+	return common.Hash{}
+}
+
+func (d *operationDecoder) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	d.add("SetState", addr, key, value)
+}
+
+func (d *operationDecoder) GetStorageRoot(addr common.Address) common.Hash {
+	d.add("GetStorageRoot", addr)
+	return common.Hash{}
+}
+
+func (d *operationDecoder) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	d.add("GetTransientState", addr, key)
+	return common.Hash{}
+}
+
+func (d *operationDecoder) SetTransientState(addr common.Address, key common.Hash, value common.Hash) {
+	d.add("SetTransientState", addr, key, value)
+}
+
+func (d *operationDecoder) GetCodeHash(addr common.Address) common.Hash {
+	d.add("GetCodeHash", addr)
+	return common.Hash{}
+}
+
+func (d *operationDecoder) GetCode(addr common.Address) []byte {
+	d.add("GetCode", addr)
+	return nil
+}
+
+func (d *operationDecoder) SetCode(addr common.Address, code []byte) {
+	d.add("SetCode", addr, common.Bytes2Hex(code))
+}
+
+func (d *operationDecoder) GetCodeSize(addr common.Address) int {
+	d.add("GetCodeSize", addr)
+	return 0
+}
+
+func (d *operationDecoder) Snapshot() int {
+	// snapshot IDs are numbered per transaction so that they are comparable across traces
+	id := d.snapshot
+	d.snapshot++
+	d.add("Snapshot", id)
+	return id
+}
+
+func (d *operationDecoder) RevertToSnapshot(id int) {
+	d.add("RevertToSnapshot", id)
+}
+
+func (d *operationDecoder) Finalise(deleteEmptyObjects bool) {
+	d.add("Finalise", deleteEmptyObjects)
+}
+
+// Block, transaction and sync-period boundaries are used for aligning the traces;
+// they are not part of the compared operations.
+
+func (d *operationDecoder) BeginTransaction(uint32) error {
+	d.snapshot = 0
+	return nil
+}
+
+func (d *operationDecoder) EndTransaction() error {
+	return nil
+}
+
+func (d *operationDecoder) BeginBlock(uint64) error {
+	return nil
+}
+
+func (d *operationDecoder) EndBlock() error {
+	return nil
+}
+
+func (d *operationDecoder) BeginSyncPeriod(uint64) {}
+
+func (d *operationDecoder) EndSyncPeriod() {}
+
+// transactionDiff describes the differences of the operations of a single transaction.
+type transactionDiff struct {
+	block       int
+	transaction int
+	inserted    []decodedOperation // operations only present in the second trace
+	missing     []decodedOperation // operations only present in the first trace
+	reordered   []decodedOperation // operations present in both traces at different positions
+}
+
+func (d transactionDiff) isEmpty() bool {
+	return len(d.inserted) == 0 && len(d.missing) == 0 && len(d.reordered) == 0
+}
+
+func (d transactionDiff) print(verbose bool) {
+	fmt.Printf("block %v, tx %v: %v inserted, %v missing, %v reordered\n",
+		d.block, d.transaction, len(d.inserted), len(d.missing), len(d.reordered))
+	if !verbose {
+		return
+	}
+	for _, op := range d.inserted {
+		fmt.Printf("\t+ %v\n", op)
+	}
+	for _, op := range d.missing {
+		fmt.Printf("\t- %v\n", op)
+	}
+	for _, op := range d.reordered {
+		fmt.Printf("\t~ %v\n", op)
+	}
+}
+
+// diffOperations aligns the operations of a transaction from two traces. Operations
+// which occur more often in a than in b are missing, operations which occur more often
+// in b than in a are inserted. The remaining operations are matched by their occurrence
+// and the operations outside the longest common subsequence are reported as reordered.
+func diffOperations(a, b []decodedOperation) transactionDiff {
+	var diff transactionDiff
+
+	// count occurrences of operations in b
+	countB := make(map[decodedOperation]int)
+	for _, op := range b {
+		countB[op]++
+	}
+
+	// keep the first occurrences of a which are also in b
+	seenA := make(map[decodedOperation]int)
+	var commonA []decodedOperation
+	for _, op := range a {
+		if seenA[op] < countB[op] {
+			commonA = append(commonA, op)
+		} else {
+			diff.missing = append(diff.missing, op)
+		}
+		seenA[op]++
+	}
+
+	// map the k-th occurrence of an operation in b to the k-th occurrence in a
+	positions := make(map[decodedOperation][]int)
+	for i, op := range commonA {
+		positions[op] = append(positions[op], i)
+	}
+	var permutation []int
+	for _, op := range b {
+		if pos := positions[op]; len(pos) > 0 {
+			permutation = append(permutation, pos[0])
+			positions[op] = pos[1:]
+		} else {
+			diff.inserted = append(diff.inserted, op)
+		}
+	}
+
+	// common operations in the same order form an increasing subsequence
+	inOrder := longestIncreasingSubsequence(permutation)
+	for _, i := range permutation {
+		if !inOrder[i] {
+			diff.reordered = append(diff.reordered, commonA[i])
+		}
+	}
+	return diff
+}
+
+// longestIncreasingSubsequence returns the set of values of a longest increasing
+// subsequence of a sequence of distinct values.
+func longestIncreasingSubsequence(values []int) map[int]bool {
+	// tails[l] is the index of the smallest tail of an increasing subsequence of length l+1
+	var tails []int
+	predecessor := make([]int, len(values))
+	for i, v := range values {
+		l := sort.Search(len(tails), func(j int) bool { return values[tails[j]] >= v })
+		if l > 0 {
+			predecessor[i] = tails[l-1]
+		} else {
+			predecessor[i] = -1
+		}
+		if l == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[l] = i
+		}
+	}
+
+	res := make(map[int]bool, len(tails))
+	if len(tails) == 0 {
+		return res
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = predecessor[i] {
+		res[values[i]] = true
+	}
+	return res
+}
+
+// traceDiffSummary accumulates the differences of all transactions per operation kind.
+type traceDiffSummary struct {
+	transactions          int
+	differingTransactions int
+	inserted              map[string]int
+	missing               map[string]int
+	reordered             map[string]int
+}
+
+func newTraceDiffSummary() *traceDiffSummary {
+	return &traceDiffSummary{
+		inserted:  make(map[string]int),
+		missing:   make(map[string]int),
+		reordered: make(map[string]int),
+	}
+}
+
+func (s *traceDiffSummary) add(diff transactionDiff) {
+	s.transactions++
+	if diff.isEmpty() {
+		return
+	}
+	s.differingTransactions++
+	for _, op := range diff.inserted {
+		s.inserted[op.kind]++
+	}
+	for _, op := range diff.missing {
+		s.missing[op.kind]++
+	}
+	for _, op := range diff.reordered {
+		s.reordered[op.kind]++
+	}
+}
+
+func (s *traceDiffSummary) print(log logger.Logger) {
+	log.Noticef("Compared %v transactions, %v differ", s.transactions, s.differingTransactions)
+
+	kinds := make(map[string]bool)
+	for _, counts := range []map[string]int{s.inserted, s.missing, s.reordered} {
+		for kind := range counts {
+			kinds[kind] = true
+		}
+	}
+	sorted := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		sorted = append(sorted, kind)
+	}
+	sort.Strings(sorted)
+
+	for _, kind := range sorted {
+		log.Noticef("%v: %v inserted, %v missing, %v reordered", kind, s.inserted[kind], s.missing[kind], s.reordered[kind])
+	}
+}
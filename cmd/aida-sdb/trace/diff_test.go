@@ -0,0 +1,97 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"testing"
+)
+
+func TestTraceDiff_IdenticalOperationsHaveNoDifferences(t *testing.T) {
+	ops := []decodedOperation{
+		{kind: "GetState", arguments: "a, 1"},
+		{kind: "SetState", arguments: "a, 1, 2"},
+		{kind: "GetState", arguments: "a, 1"},
+	}
+	diff := diffOperations(ops, ops)
+	if !diff.isEmpty() {
+		t.Errorf("unexpected differences: %+v", diff)
+	}
+}
+
+func TestTraceDiff_InsertedAndMissingOperationsAreReported(t *testing.T) {
+	a := []decodedOperation{
+		{kind: "GetBalance", arguments: "a"},
+		{kind: "GetState", arguments: "a, 1"},
+		{kind: "GetState", arguments: "a, 1"},
+	}
+	b := []decodedOperation{
+		{kind: "GetBalance", arguments: "a"},
+		{kind: "GetState", arguments: "a, 1"},
+		{kind: "GetNonce", arguments: "a"},
+	}
+	diff := diffOperations(a, b)
+	if got, want := len(diff.missing), 1; got != want || diff.missing[0] != a[2] {
+		t.Errorf("unexpected missing operations: %v", diff.missing)
+	}
+	if got, want := len(diff.inserted), 1; got != want || diff.inserted[0] != b[2] {
+		t.Errorf("unexpected inserted operations: %v", diff.inserted)
+	}
+	if len(diff.reordered) != 0 {
+		t.Errorf("unexpected reordered operations: %v", diff.reordered)
+	}
+}
+
+func TestTraceDiff_ReorderedOperationsAreReported(t *testing.T) {
+	a := []decodedOperation{
+		{kind: "GetBalance", arguments: "a"},
+		{kind: "GetNonce", arguments: "a"},
+		{kind: "GetCode", arguments: "a"},
+		{kind: "GetState", arguments: "a, 1"},
+	}
+	b := []decodedOperation{
+		{kind: "GetBalance", arguments: "a"},
+		{kind: "GetCode", arguments: "a"},
+		{kind: "GetState", arguments: "a, 1"},
+		{kind: "GetNonce", arguments: "a"},
+	}
+	diff := diffOperations(a, b)
+	if len(diff.inserted) != 0 || len(diff.missing) != 0 {
+		t.Errorf("unexpected inserted or missing operations: %+v", diff)
+	}
+	if got, want := len(diff.reordered), 1; got != want || diff.reordered[0] != a[1] {
+		t.Errorf("unexpected reordered operations: %v", diff.reordered)
+	}
+}
+
+func TestTraceDiff_SummaryCountsPerOperationKind(t *testing.T) {
+	summary := newTraceDiffSummary()
+	summary.add(diffOperations(
+		[]decodedOperation{{kind: "GetState", arguments: "a, 1"}},
+		[]decodedOperation{{kind: "GetState", arguments: "a, 2"}},
+	))
+	summary.add(diffOperations(nil, nil))
+
+	if got, want := summary.transactions, 2; got != want {
+		t.Errorf("unexpected number of transactions, got %v, want %v", got, want)
+	}
+	if got, want := summary.differingTransactions, 1; got != want {
+		t.Errorf("unexpected number of differing transactions, got %v, want %v", got, want)
+	}
+	if summary.inserted["GetState"] != 1 || summary.missing["GetState"] != 1 {
+		t.Errorf("unexpected counts, inserted %v, missing %v", summary.inserted, summary.missing)
+	}
+}
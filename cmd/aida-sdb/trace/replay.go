@@ -46,6 +46,18 @@ func ReplayTrace(ctx *cli.Context) error {
 	defer operationProvider.Close()
 
 	rCtx := context.NewReplay()
+	if cfg.Profile {
+		rCtx.EnableProfiling(cfg.ProfileFile)
+	}
+	if cfg.TraceTiming {
+		if cfg.TimeScale < 0 {
+			return fmt.Errorf("time scale must not be negative")
+		}
+		rCtx.EnablePacing(cfg.TimeScale)
+		if cfg.Profile {
+			rCtx.Stats.EnablePercentiles()
+		}
+	}
 
 	processor := operationProcessor{cfg, rCtx}
 
@@ -58,6 +58,8 @@ var TraceReplayCommand = cli.Command{
 		&utils.TraceFileFlag,
 		&utils.TraceDirectoryFlag,
 		&utils.TraceDebugFlag,
+		&utils.TraceTimingFlag,
+		&utils.TimeScaleFlag,
 		&utils.DebugFromFlag,
 		//&utils.ValidateFlag,
 		//&utils.ValidateTxStateFlag,
@@ -69,7 +71,11 @@ The trace replay command requires two arguments:
 <blockNumFirst> <blockNumLast>
 
 <blockNumFirst> and <blockNumLast> are the first and
-last block of the inclusive range of blocks to replay storage traces.`,
+last block of the inclusive range of blocks to replay storage traces.
+
+If --trace-timing is set, the time between operations recorded
+with "record --trace-timing" is reproduced, scaled by --time-scale.
+Use --profile to report latency percentiles per operation.`,
 }
 
 // TraceReplaySubstateCommand data structure for the replay-substate app
@@ -108,3 +114,24 @@ The trace replay-substate command requires two arguments:
 <blockNumFirst> and <blockNumLast> are the first and
 last block of the inclusive range of blocks to replay storage traces.`,
 }
+
+// TraceDiffCommand data structure for the diff app
+var TraceDiffCommand = cli.Command{
+	Action:    DiffTrace,
+	Name:      "diff",
+	Usage:     "compares StateDB operations of two storage traces",
+	ArgsUsage: "<traceA> <traceB>",
+	Flags: []cli.Flag{
+		&utils.TraceDebugFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The trace diff command requires two arguments:
+<traceA> <traceB>
+
+<traceA> and <traceB> are storage trace files, e.g. recorded
+with different VM implementations (--vm-impl). Operations are
+aligned per transaction and inserted, missing and reordered
+operations are reported. With --trace-debug, the differing
+operations are printed.`,
+}
@@ -32,10 +32,14 @@ func initStochasticApp() *cli.App {
 		Copyright: "(c) 2022-23 Fantom Foundation",
 		Flags:     []cli.Flag{},
 		Commands: []*cli.Command{
+			&stochastic.StochasticCompareCommand,
 			&stochastic.StochasticEstimateCommand,
 			&stochastic.StochasticGenerateCommand,
+			&stochastic.StochasticMergeCommand,
 			&stochastic.StochasticRecordCommand,
 			&stochastic.StochasticReplayCommand,
+			&stochastic.StochasticShrinkCommand,
+			&stochastic.StochasticValidateCommand,
 			&stochastic.StochasticVisualizeCommand,
 		},
 	}
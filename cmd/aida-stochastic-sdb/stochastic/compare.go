@@ -0,0 +1,105 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// compareSteps is the number of operations sampled from each model.
+const compareSteps = 1_000_000
+
+// StochasticCompareCommand data structure for the compare app.
+var StochasticCompareCommand = cli.Command{
+	Action:    stochasticCompareAction,
+	Name:      "compare",
+	Usage:     "compares operation n-grams of first-order and higher-order models with recorded ones",
+	ArgsUsage: "<events.json> <simulation.json>",
+	Flags: []cli.Flag{
+		&utils.RandomSeedFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The stochastic compare command requires two arguments:
+<events.json> <simulation.json>
+
+<events.json> is an event file recorded with a markov order greater than one and
+<simulation.json> is the simulation file estimated from it. The command samples
+operations from the higher-order model and from its first-order reduction and
+prints the total variation distances of their n-gram distributions to the
+recorded n-gram distributions.`,
+}
+
+// stochasticCompareAction implements the compare command.
+func stochasticCompareAction(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("missing events and simulation file as parameter")
+	}
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	log := logger.NewLogger(cfg.LogLevel, "Stochastic Compare")
+
+	events, err := stochastic.ReadEvents(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if events.Order < 2 {
+		return fmt.Errorf("events file must be recorded with a markov order greater than one")
+	}
+	simulation, err := stochastic.ReadSimulation(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	if simulation.Order != events.Order {
+		return fmt.Errorf("markov order of simulation (%v) and events (%v) differ", simulation.Order, events.Order)
+	}
+
+	// sample the higher-order model and its first-order reduction
+	log.Infof("Sample %v operations from each model", compareSteps)
+	higher, err := stochastic.SampleStates(simulation, compareSteps, rand.New(rand.NewSource(cfg.RandomSeed)))
+	if err != nil {
+		return err
+	}
+	reduced := *simulation
+	reduced.Order = 1
+	reduced.Contexts = nil
+	first, err := stochastic.SampleStates(&reduced, compareSteps, rand.New(rand.NewSource(cfg.RandomSeed)))
+	if err != nil {
+		return err
+	}
+
+	log.Noticef("Total variation distance of n-grams to recording:")
+	log.Noticef("%4v %12v %12v", "n", "first-order", fmt.Sprintf("order %v", simulation.Order))
+	for n := 2; n <= events.Order+1; n++ {
+		recorded, err := stochastic.RecordedNGrams(events, n)
+		if err != nil {
+			return err
+		}
+		dFirst := stochastic.NGramDistance(recorded, stochastic.CountNGrams(simulation.Operations, first, n))
+		dHigher := stochastic.NGramDistance(recorded, stochastic.CountNGrams(simulation.Operations, higher, n))
+		log.Noticef("%4v %12.6f %12.6f", n, dFirst, dHigher)
+	}
+	return nil
+}
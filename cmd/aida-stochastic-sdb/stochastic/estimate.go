@@ -35,7 +35,16 @@ var StochasticEstimateCommand = cli.Command{
 The stochastic estimator command requires one argument:
 <events.json>
 
-<events.json> is the event file produced by the stochastic recorder.`,
+<events.json> is the event file produced by the stochastic recorder.
+
+If the event file contains phases, a model is estimated for each phase.
+
+For the random accesses of contracts, keys, and values, the estimator fits
+an exponential, a uniform, and a Zipf distribution and selects the best fit by
+its likelihood. If none of them fits the recorded accesses closely, the
+empirical distribution of the accesses is selected. The selection can be
+changed by editing the "distribution" field of the access statistics in the
+simulation file.`,
 }
 
 // stochasticEstimateAction implements estimator command for computing statistical parameters.
@@ -58,6 +67,9 @@ func stochasticEstimateAction(ctx *cli.Context) error {
 	// estimate parameters
 	log.Info("Estimate parameters")
 	estimationModel := stochastic.NewEstimationModelJSON(eventRegistryJSON)
+	if len(estimationModel.Phases) > 0 {
+		log.Infof("Estimated %v phases of %v blocks", len(estimationModel.Phases), estimationModel.PhaseLength)
+	}
 
 	// write simulation file
 	outputFileName := ctx.String(utils.OutputFlag.Name)
@@ -0,0 +1,97 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// StochasticMergeCommand data structure for the merge app.
+var StochasticMergeCommand = cli.Command{
+	Action:    stochasticMergeAction,
+	Name:      "merge",
+	Usage:     "merges event files recorded for separate block ranges",
+	ArgsUsage: "<events.json> <events.json> ...",
+	Flags: []cli.Flag{
+		&utils.MergeWeightsFlag,
+		&utils.OutputFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The stochastic merge command requires at least two arguments:
+<events.json> <events.json> ...
+
+Each <events.json> is an event file produced by the stochastic recorder.
+The transition frequencies of the event files are summed and their access,
+snapshot, and value statistics are mixed. The optional --weights scale the
+event files, e.g., --weights 2,1 counts the events of the first file twice.
+Phases are concatenated in the order of the event files if all of them
+were recorded with the same phase length.
+The merged event file is written to --output (default: ./events.json) and
+can be used by the estimate and visualize commands.`,
+}
+
+// stochasticMergeAction implements the merge command.
+func stochasticMergeAction(ctx *cli.Context) error {
+	if ctx.Args().Len() < 2 {
+		return fmt.Errorf("missing event files to merge")
+	}
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	log := logger.NewLogger(cfg.LogLevel, "Stochastic Merge")
+
+	// read event files
+	events := make([]*stochastic.EventRegistryJSON, 0, ctx.Args().Len())
+	hasPhases := false
+	for _, filename := range ctx.Args().Slice() {
+		log.Infof("Read events file %v", filename)
+		e, err := stochastic.ReadEvents(filename)
+		if err != nil {
+			return err
+		}
+		hasPhases = hasPhases || len(e.Phases) > 0
+		events = append(events, e)
+	}
+
+	// merge events
+	var weights []float64
+	if len(cfg.MergeWeights) > 0 {
+		weights = cfg.MergeWeights
+	}
+	merged, err := stochastic.MergeEvents(events, weights)
+	if err != nil {
+		return err
+	}
+	if hasPhases && len(merged.Phases) == 0 {
+		log.Warning("Phases are omitted since not all event files have phases of the same length")
+	}
+
+	// write merged event file
+	outputFileName := cfg.Output
+	if outputFileName == "" {
+		outputFileName = "./events.json"
+	}
+	log.Noticef("Write events file %v", outputFileName)
+	return WriteEvents(merged, outputFileName)
+}
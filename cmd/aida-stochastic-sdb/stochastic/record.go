@@ -26,9 +26,13 @@ import (
 	"github.com/Fantom-foundation/Aida/executor"
 	"github.com/Fantom-foundation/Aida/state"
 	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/tracer"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
 	substatecontext "github.com/Fantom-foundation/Aida/txcontext/substate"
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/Fantom-foundation/Substate/db"
+	"github.com/Fantom-foundation/Substate/substate"
 	"github.com/urfave/cli/v2"
 )
 
@@ -46,19 +50,34 @@ var StochasticRecordCommand = cli.Command{
 		&utils.ChainIDFlag,
 		&utils.AidaDbFlag,
 		&utils.CacheFlag,
+		&utils.FromTraceFlag,
+		&utils.TraceFileFlag,
+		&utils.TraceDirectoryFlag,
+		&utils.MarkovOrderFlag,
+		&utils.PhaseLengthFlag,
 	},
 	Description: `
 The stochastic record command requires two arguments:
 <blockNumFirst> <blockNumLast>
 
 <blockNumFirst> and <blockNumLast> are the first and
-last block for recording events.`,
+last block for recording events.
+
+If --from-trace is set, the events are derived from the
+storage traces given by --trace-file or --trace-dir
+instead of executing the substates of the aida-db.
+
+If --markov-order is greater than one, the recorder additionally
+counts the operations following each context of preceding operations
+for a higher-order Markov process.
+
+If --phase-length is set, the events of each window of the given
+number of blocks are additionally recorded as a phase of a
+time-varying model. Windows without blocks are omitted.`,
 }
 
 // stochasticRecordAction implements recording of events.
 func stochasticRecordAction(ctx *cli.Context) error {
-	var err error
-
 	cfg, err := utils.NewConfig(ctx, utils.BlockRangeArgs)
 	if err != nil {
 		return err
@@ -72,6 +91,15 @@ func stochasticRecordAction(ctx *cli.Context) error {
 	}
 	defer utils.StopCPUProfile(cfg)
 
+	if cfg.FromTrace {
+		return stochasticRecordFromTrace(cfg)
+	}
+	return stochasticRecordFromSubstates(cfg)
+}
+
+// stochasticRecordFromSubstates records events by executing the substates
+// of the aida-db on an in-memory StateDB wrapped by an event proxy.
+func stochasticRecordFromSubstates(cfg *utils.Config) error {
 	processor, err := executor.MakeLiveDbTxProcessor(cfg)
 	if err != nil {
 		return err
@@ -97,9 +125,23 @@ func stochasticRecordAction(ctx *cli.Context) error {
 
 	// create a new event registry
 	eventRegistry := stochastic.NewEventRegistry()
+	if err := eventRegistry.SetMarkovOrder(cfg.MarkovOrder); err != nil {
+		return err
+	}
+
+	phases, err := newPhaseRecorder(cfg)
+	if err != nil {
+		return err
+	}
+	registerOp := func(op int) {
+		eventRegistry.RegisterOp(op)
+		if phases != nil {
+			phases.RegisterOp(op)
+		}
+	}
 
 	curSyncPeriod := cfg.First / cfg.SyncPeriodLength
-	eventRegistry.RegisterOp(stochastic.BeginSyncPeriodID)
+	registerOp(stochastic.BeginSyncPeriodID)
 
 	// iterate over all substates in order
 	for iter.Next() {
@@ -110,21 +152,30 @@ func stochasticRecordAction(ctx *cli.Context) error {
 				break
 			}
 			if oldBlock != math.MaxUint64 {
-				eventRegistry.RegisterOp(stochastic.EndBlockID)
-				newSyncPeriod := tx.Block / cfg.SyncPeriodLength
-				for curSyncPeriod < newSyncPeriod {
-					eventRegistry.RegisterOp(stochastic.EndSyncPeriodID)
-					curSyncPeriod++
-					eventRegistry.RegisterOp(stochastic.BeginSyncPeriodID)
-				}
+				registerOp(stochastic.EndBlockID)
+			}
+			// advance sync-periods, including those before the first block with substates
+			newSyncPeriod := tx.Block / cfg.SyncPeriodLength
+			for curSyncPeriod < newSyncPeriod {
+				registerOp(stochastic.EndSyncPeriodID)
+				curSyncPeriod++
+				registerOp(stochastic.BeginSyncPeriodID)
 			}
 			// open new block with a begin-block operation and clear index cache
 			eventRegistry.RegisterOp(stochastic.BeginBlockID)
+			if phases != nil {
+				if err := phases.BeginBlock(tx.Block); err != nil {
+					return err
+				}
+			}
 			oldBlock = tx.Block
 		}
 
 		var statedb state.StateDB
 		statedb = state.MakeInMemoryStateDB(substatecontext.NewWorldState(tx.InputSubstate), tx.Block)
+		if phases != nil {
+			statedb = stochastic.NewEventProxy(statedb, phases.Registry())
+		}
 		statedb = stochastic.NewEventProxy(statedb, &eventRegistry)
 		if _, err = processor.ProcessTransaction(statedb, int(tx.Block), tx.Transaction, substatecontext.NewTxContext(tx)); err != nil {
 			return err
@@ -139,35 +190,167 @@ func stochasticRecordAction(ctx *cli.Context) error {
 	}
 	// end last block
 	if oldBlock != math.MaxUint64 {
-		eventRegistry.RegisterOp(stochastic.EndBlockID)
+		registerOp(stochastic.EndBlockID)
 	}
-	eventRegistry.RegisterOp(stochastic.EndSyncPeriodID)
+	registerOp(stochastic.EndSyncPeriodID)
 
 	sec = time.Since(start).Seconds()
 	fmt.Printf("stochastic record: Total elapsed time: %.3f s, processed %v blocks\n", sec, cfg.Last-cfg.First+1)
 
-	// writing event registry
-	fmt.Printf("stochastic record: write events file ...\n")
-	if cfg.Output == "" {
-		cfg.Output = "./events.json"
+	return writeEventRegistry(cfg, &eventRegistry, phases)
+}
+
+// stochasticRecordFromTrace records events from storage traces. The operations
+// of a trace are replayed on a fresh in-memory StateDB per transaction that is
+// wrapped by an event proxy, so the registry observes the same StateDB calls
+// as when the substates are executed.
+func stochasticRecordFromTrace(cfg *utils.Config) error {
+	traceFiles, err := tracer.GetTraceFiles(cfg)
+	if err != nil {
+		return fmt.Errorf("cannot find trace files; %w", err)
+	}
+	if len(traceFiles) == 0 {
+		return fmt.Errorf("no trace files found")
 	}
-	err = WriteEvents(&eventRegistry, cfg.Output)
+
+	iter := tracer.NewTraceIterator(traceFiles, cfg.First)
+	defer iter.Release()
+
+	var (
+		start   time.Time
+		sec     float64
+		lastSec float64
+	)
+	start = time.Now()
+	sec = time.Since(start).Seconds()
+	lastSec = time.Since(start).Seconds()
+
+	// create a new event registry
+	eventRegistry := stochastic.NewEventRegistry()
+	if err := eventRegistry.SetMarkovOrder(cfg.MarkovOrder); err != nil {
+		return err
+	}
+	phases, err := newPhaseRecorder(cfg)
 	if err != nil {
 		return err
 	}
+	registerOp := func(op int) {
+		eventRegistry.RegisterOp(op)
+		if phases != nil {
+			phases.RegisterOp(op)
+		}
+	}
+	// wrap a StateDB with event proxies of the registries
+	newEventProxy := func(block uint64) state.StateDB {
+		statedb := state.MakeInMemoryStateDB(substatecontext.NewWorldState(substate.WorldState{}), block)
+		if phases != nil {
+			statedb = stochastic.NewEventProxy(statedb, phases.Registry())
+		}
+		return stochastic.NewEventProxy(statedb, &eventRegistry)
+	}
+	registerOp(stochastic.BeginSyncPeriodID)
 
-	return nil
+	rCtx := context.NewReplay()
+	oldBlock := uint64(math.MaxUint64) // set to an infeasible block
+	statedb := newEventProxy(cfg.First)
+
+	// Sync-period operations are deferred until the next block is known to
+	// be in range; otherwise the sync-periods of the trace beyond the last
+	// block would be registered.
+	pendingSyncOps := []int{}
+	firstOp := true
+
+	// iterate over all operations in order
+	for iter.Next() {
+		op := iter.Value()
+
+		// stop if the last block has been passed
+		if bb, ok := op.(*operation.BeginBlock); ok && bb.BlockNumber > cfg.Last {
+			break
+		}
+
+		switch t := op.(type) {
+		case *operation.BeginSyncPeriod:
+			// the first sync-period has already been registered
+			if !firstOp {
+				pendingSyncOps = append(pendingSyncOps, stochastic.BeginSyncPeriodID)
+			}
+		case *operation.EndSyncPeriod:
+			pendingSyncOps = append(pendingSyncOps, stochastic.EndSyncPeriodID)
+		case *operation.BeginBlock:
+			for _, syncOp := range pendingSyncOps {
+				registerOp(syncOp)
+			}
+			pendingSyncOps = pendingSyncOps[:0]
+			eventRegistry.RegisterOp(stochastic.BeginBlockID)
+			if phases != nil {
+				if err := phases.BeginBlock(t.BlockNumber); err != nil {
+					return err
+				}
+			}
+			oldBlock = t.BlockNumber
+		case *operation.EndBlock:
+			registerOp(stochastic.EndBlockID)
+		case *operation.BeginTransaction:
+			// substates are executed on a new StateDB for each transaction
+			// without transaction events; mirror this for traces.
+			statedb = newEventProxy(oldBlock)
+		case *operation.EndTransaction:
+			// ignored
+		default:
+			operation.Execute(op, statedb, rCtx)
+		}
+		firstOp = false
+
+		// report progress
+		sec = time.Since(start).Seconds()
+		if sec-lastSec >= 15 {
+			fmt.Printf("stochastic record: Elapsed time: %.0f s, at block %v\n", sec, oldBlock)
+			lastSec = sec
+		}
+	}
+	// pending sync-periods are dropped and the last one is closed
+	registerOp(stochastic.EndSyncPeriodID)
+
+	sec = time.Since(start).Seconds()
+	fmt.Printf("stochastic record: Total elapsed time: %.3f s, processed %v blocks\n", sec, cfg.Last-cfg.First+1)
+
+	return writeEventRegistry(cfg, &eventRegistry, phases)
+}
+
+// newPhaseRecorder creates a phase recorder if a phase length is configured.
+func newPhaseRecorder(cfg *utils.Config) (*stochastic.PhaseRecorder, error) {
+	if cfg.PhaseLength == 0 {
+		return nil, nil
+	}
+	return stochastic.NewPhaseRecorder(cfg.First, cfg.PhaseLength, cfg.MarkovOrder)
+}
+
+// writeEventRegistry writes the event registry with its phases (if recorded)
+// to the configured output file.
+func writeEventRegistry(cfg *utils.Config, eventRegistry *stochastic.EventRegistry, phases *stochastic.PhaseRecorder) error {
+	fmt.Printf("stochastic record: write events file ...\n")
+	if cfg.Output == "" {
+		cfg.Output = "./events.json"
+	}
+	events := eventRegistry.NewEventRegistryJSON()
+	if phases != nil {
+		events.PhaseLength = cfg.PhaseLength
+		events.Phases = phases.Finish()
+		fmt.Printf("stochastic record: recorded %v phases\n", len(events.Phases))
+	}
+	return WriteEvents(&events, cfg.Output)
 }
 
 // WriteEvents writes event file in JSON format.
-func WriteEvents(r *stochastic.EventRegistry, filename string) error {
+func WriteEvents(events *stochastic.EventRegistryJSON, filename string) error {
 	f, fErr := os.Create(filename)
 	if fErr != nil {
 		return fmt.Errorf("cannot open JSON file; %v", fErr)
 	}
 	defer f.Close()
 
-	jOut, jErr := json.MarshalIndent(r.NewEventRegistryJSON(), "", "    ")
+	jOut, jErr := json.MarshalIndent(events, "", "    ")
 	if jErr != nil {
 		return fmt.Errorf("failed to convert JSON file; %v", jErr)
 	}
@@ -0,0 +1,176 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/state/proxy"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	substatecontext "github.com/Fantom-foundation/Aida/txcontext/substate"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/Fantom-foundation/Substate/substate"
+	substatetypes "github.com/Fantom-foundation/Substate/types"
+)
+
+// makeRecordTestSubstates creates value transfers of a sender to two recipients,
+// two transactions in block 2 and one transaction in each of blocks 3, 6 and 9.
+func makeRecordTestSubstates() []*substate.Substate {
+	sender := substatetypes.Address{0x01}
+	recipients := []substatetypes.Address{{0x02}, {0x03}, {0x02}, {0x03}, {0x02}}
+	blocks := []uint64{2, 2, 3, 6, 9}
+	txs := []int{0, 1, 0, 0, 0}
+
+	substates := []*substate.Substate{}
+	for i, recipient := range recipients {
+		to := recipient
+		substates = append(substates, &substate.Substate{
+			Block:       blocks[i],
+			Transaction: txs[i],
+			Env: &substate.Env{
+				Number:   blocks[i],
+				GasLimit: 100_000_000,
+			},
+			Message: &substate.Message{
+				Nonce:     uint64(i),
+				From:      sender,
+				To:        &to,
+				GasPrice:  big.NewInt(12),
+				Value:     big.NewInt(int64(i + 1)),
+				Gas:       1_000_000,
+				GasFeeCap: big.NewInt(1_000_000),
+				GasTipCap: big.NewInt(1_000_000),
+			},
+			InputSubstate: substate.WorldState{
+				sender:    substate.NewAccount(uint64(i), big.NewInt(1_000_000_000_000), nil),
+				recipient: substate.NewAccount(0, big.NewInt(int64(i)), nil),
+			},
+			OutputSubstate: substate.WorldState{},
+			Result:         &substate.Result{Status: 1},
+		})
+	}
+	return substates
+}
+
+// recordTestTrace executes the substates on in-memory StateDBs and records
+// the issued operations as a storage trace. Sync-periods are emitted like
+// the sync-period emitter of the trace recorder does.
+func recordTestTrace(t *testing.T, cfg *utils.Config, substates []*substate.Substate) {
+	processor, err := executor.MakeLiveDbTxProcessor(cfg)
+	if err != nil {
+		t.Fatalf("cannot create processor: %v", err)
+	}
+	rCtx, err := context.NewRecord(cfg.TraceFile, cfg.First)
+	if err != nil {
+		t.Fatalf("cannot create trace: %v", err)
+	}
+
+	syncPeriod := cfg.First / cfg.SyncPeriodLength
+	operation.WriteOp(rCtx, operation.NewBeginSyncPeriod(syncPeriod))
+	for i, tx := range substates {
+		if i == 0 || substates[i-1].Block != tx.Block {
+			for syncPeriod < tx.Block/cfg.SyncPeriodLength {
+				operation.WriteOp(rCtx, operation.NewEndSyncPeriod())
+				syncPeriod++
+				operation.WriteOp(rCtx, operation.NewBeginSyncPeriod(syncPeriod))
+			}
+			operation.WriteOp(rCtx, operation.NewBeginBlock(tx.Block))
+		}
+		statedb := proxy.NewRecorderProxy(state.MakeInMemoryStateDB(substatecontext.NewWorldState(tx.InputSubstate), tx.Block), rCtx)
+		statedb.BeginTransaction(uint32(tx.Transaction))
+		if _, err := processor.ProcessTransaction(statedb, int(tx.Block), tx.Transaction, substatecontext.NewTxContext(tx)); err != nil {
+			t.Fatalf("cannot process transaction %v/%v: %v", tx.Block, tx.Transaction, err)
+		}
+		statedb.EndTransaction()
+		if i == len(substates)-1 || substates[i+1].Block != tx.Block {
+			operation.WriteOp(rCtx, operation.NewEndBlock())
+		}
+	}
+	operation.WriteOp(rCtx, operation.NewEndSyncPeriod())
+	rCtx.Close()
+}
+
+func TestStochasticRecord_TraceAndSubstatesProduceIdenticalEvents(t *testing.T) {
+	dir := t.TempDir()
+	cfg := utils.NewTestConfig(t, utils.MainnetChainID, 1, 9, false, "")
+	cfg.AidaDb = filepath.Join(dir, "aida-db")
+	cfg.TraceFile = filepath.Join(dir, "trace.dat")
+	// sync-periods change before blocks 2, 6 (twice, skipping the empty one of blocks 4-5) and 9
+	cfg.SyncPeriodLength = 2
+	cfg.MarkovOrder = 1
+	cfg.Workers = 1
+
+	substates := makeRecordTestSubstates()
+	sdb, err := db.NewDefaultSubstateDB(cfg.AidaDb)
+	if err != nil {
+		t.Fatalf("cannot create aida-db: %v", err)
+	}
+	for _, tx := range substates {
+		if err := sdb.PutSubstate(tx); err != nil {
+			t.Fatalf("cannot put substate: %v", err)
+		}
+	}
+	if err := sdb.Close(); err != nil {
+		t.Fatalf("cannot close aida-db: %v", err)
+	}
+	recordTestTrace(t, cfg, substates)
+
+	cfg.Output = filepath.Join(dir, "substate-events.json")
+	if err := stochasticRecordFromSubstates(cfg); err != nil {
+		t.Fatalf("cannot record events from substates: %v", err)
+	}
+	cfg.Output = filepath.Join(dir, "trace-events.json")
+	if err := stochasticRecordFromTrace(cfg); err != nil {
+		t.Fatalf("cannot record events from trace: %v", err)
+	}
+
+	want, err := stochastic.ReadEvents(filepath.Join(dir, "substate-events.json"))
+	if err != nil {
+		t.Fatalf("cannot read events: %v", err)
+	}
+	got, err := stochastic.ReadEvents(filepath.Join(dir, "trace-events.json"))
+	if err != nil {
+		t.Fatalf("cannot read events: %v", err)
+	}
+	if len(want.Operations) == 0 {
+		t.Fatalf("no operations recorded from substates")
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("events of trace and substates differ\nsubstates: %v\ntrace: %v", want.Operations, got.Operations)
+	}
+	if got, want := countTransitions(want, "ES", "BS"), uint64(4); got != want {
+		t.Errorf("unexpected number of sync-period transitions, got %v, want %v", got, want)
+	}
+}
+
+// countTransitions returns the recorded frequency of transitions between two operations.
+func countTransitions(events *stochastic.EventRegistryJSON, from, to string) uint64 {
+	i, j := slices.Index(events.Operations, from), slices.Index(events.Operations, to)
+	if i < 0 || j < 0 {
+		return 0
+	}
+	return events.TransitionFreq[i][j]
+}
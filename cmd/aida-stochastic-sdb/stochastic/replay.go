@@ -37,6 +37,9 @@ var StochasticReplayCommand = cli.Command{
 	Usage:     "Simulates StateDB operations using a random generator with realistic distributions",
 	ArgsUsage: "<simulation-length> <simulation-file>",
 	Flags: []cli.Flag{
+		&utils.ArchiveMaxQueryAgeFlag,
+		&utils.ArchiveModeFlag,
+		&utils.ArchiveVariantFlag,
 		&utils.BalanceRangeFlag,
 		&utils.CarmenSchemaFlag,
 		&utils.ContinueOnFailureFlag,
@@ -44,7 +47,10 @@ var StochasticReplayCommand = cli.Command{
 		&utils.DebugFromFlag,
 		&utils.MemoryBreakdownFlag,
 		&utils.NonceRangeFlag,
+		&utils.OperationLogFlag,
+		&utils.PhaseLengthFlag,
 		&utils.RandomSeedFlag,
+		&utils.ReaderStreamsFlag,
 		&utils.StateDbImplementationFlag,
 		&utils.StateDbVariantFlag,
 		&utils.DbTmpFlag,
@@ -52,6 +58,7 @@ var StochasticReplayCommand = cli.Command{
 		&utils.TraceFileFlag,
 		&utils.TraceDebugFlag,
 		&utils.TraceFlag,
+		&utils.ShadowDb,
 		&utils.ShadowDbImplementationFlag,
 		&utils.ShadowDbVariantFlag,
 		&logger.LogLevelFlag,
@@ -61,7 +68,17 @@ The stochastic replay command requires two argument:
 <simulation-length> <simulation.json> 
 
 <simulation-length> determines the number of blocks
-<simulation.json> contains the simulation parameters produced by the stochastic estimator.`,
+<simulation.json> contains the simulation parameters produced by the stochastic estimator.
+
+If the simulation file contains the phases of a time-varying model, the
+replay switches to the model of the next phase after the number of blocks
+given by --phase-length (default: the phase length of the recording).
+
+With --reader-streams, the given number of reader streams issue read-only
+operations against archive states of random past blocks concurrently to the
+main stream (requires --archive). The age of queried blocks is limited by
+--archive-max-query-age. With a shadow DB, the results of the reader streams
+are cross-checked. The throughput of each stream is reported at the end.`,
 }
 
 // stochasticReplayAction implements the replay command. The user provides simulation file and
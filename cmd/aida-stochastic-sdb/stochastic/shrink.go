@@ -0,0 +1,181 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state/proxy"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// StochasticShrinkCommand data structure for the shrink app.
+var StochasticShrinkCommand = cli.Command{
+	Action:    stochasticShrinkAction,
+	Name:      "shrink",
+	Usage:     "Reduces a failing stochastic operation sequence to a minimal one",
+	ArgsUsage: "<operation-log>",
+	Flags: []cli.Flag{
+		&utils.CarmenSchemaFlag,
+		&utils.ChainIDFlag,
+		&utils.StateDbImplementationFlag,
+		&utils.StateDbVariantFlag,
+		&utils.DbTmpFlag,
+		&utils.ShadowDb,
+		&utils.ShadowDbImplementationFlag,
+		&utils.ShadowDbVariantFlag,
+		&utils.OutputFlag,
+		&utils.TraceFileFlag,
+		&utils.TraceFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The stochastic shrink command requires one argument:
+<operation-log>
+
+<operation-log> is an operation log written by the stochastic replay with the
+--operation-log flag. The operations are replayed on fresh StateDBs and reduced
+by delta debugging to a minimal sequence that still causes the StateDB to report
+an error. The minimal sequence is written as a standalone Go test to the --output
+file (or stdout) and, if --trace is set, recorded as a storage trace.`,
+}
+
+var (
+	// errReplayPanic is reported if replaying an operation sequence panics.
+	errReplayPanic = errors.New("replay panicked")
+	// errReplaySetup is reported if the StateDB or the trace for a replay cannot be prepared.
+	errReplaySetup = errors.New("cannot prepare replay")
+)
+
+// stochasticShrinkAction implements the shrink command.
+func stochasticShrinkAction(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("missing operation log as parameter")
+	}
+
+	cfg, err := utils.NewConfig(ctx, utils.PathArg)
+	if err != nil {
+		return err
+	}
+	log := logger.NewLogger(cfg.LogLevel, "Stochastic Shrink")
+
+	ops, err := stochastic.ReadOperations(cfg.ArgPath)
+	if err != nil {
+		return err
+	}
+	log.Noticef("Read %v operations", len(ops))
+
+	// make sure that the logged sequence reproduces the failure
+	err = replayOperations(cfg, ops, false)
+	if err == nil {
+		return fmt.Errorf("operation log does not cause a StateDB error")
+	}
+	if errors.Is(err, errReplayPanic) || errors.Is(err, errReplaySetup) {
+		return err
+	}
+	log.Noticef("Operation log fails with: %v", err)
+
+	// only sequences failing with the original error reproduce the failure
+	failure := err
+	start := time.Now()
+	fails := func(seq []stochastic.Operation) bool {
+		return reproducesFailure(failure, replayOperations(cfg, seq, false))
+	}
+	shrunk := stochastic.ShrinkOperations(ops, fails, func(n int) {
+		log.Infof("Reduced to %v operations", n)
+	})
+	log.Noticef("Reduced %v operations to %v operations in %v", len(ops), len(shrunk), time.Since(start).Round(time.Second))
+	for _, op := range shrunk {
+		log.Debugf("\t%v", op)
+	}
+
+	// write the minimal sequence as a Go test
+	out := os.Stdout
+	if cfg.Output != "" {
+		if out, err = os.Create(cfg.Output); err != nil {
+			return fmt.Errorf("cannot create %v; %v", cfg.Output, err)
+		}
+		defer out.Close()
+	}
+	if err = stochastic.WriteOperationTest(out, shrunk, cfg); err != nil {
+		return fmt.Errorf("cannot write test; %v", err)
+	}
+	if cfg.Output != "" {
+		log.Noticef("Test written to %v", cfg.Output)
+	}
+
+	// record the minimal sequence as a storage trace
+	if cfg.Trace {
+		err = replayOperations(cfg, shrunk, true)
+		log.Noticef("Storage trace written to %v; replay failed with: %v", cfg.TraceFile, err)
+	}
+	return nil
+}
+
+// replayOperations replays an operation sequence on a fresh StateDB and
+// returns the error reported by the StateDB. If trace is set, the operations
+// are recorded as a storage trace.
+func replayOperations(cfg *utils.Config, ops []stochastic.Operation, trace bool) (err error) {
+	db, stateDbDir, err := utils.PrepareStateDB(cfg)
+	if err != nil {
+		return fmt.Errorf("%w; %v", errReplaySetup, err)
+	}
+	defer os.RemoveAll(stateDbDir)
+
+	if trace {
+		rCtx, err := context.NewRecord(cfg.TraceFile, uint64(0))
+		if err != nil {
+			return fmt.Errorf("%w; %v", errReplaySetup, err)
+		}
+		defer rCtx.Close()
+		db = proxy.NewRecorderProxy(db, rCtx)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", errReplayPanic, r)
+		}
+	}()
+	defer db.Close()
+
+	return stochastic.ReplayOperations(db, ops)
+}
+
+// reproducesFailure returns true if the error of a replay is the original failure.
+// Panics, setup errors and StateDB errors with a different message are not reproductions.
+// The position of the failing operation is ignored since it moves while shrinking.
+func reproducesFailure(failure, err error) bool {
+	if err == nil || errors.Is(err, errReplayPanic) || errors.Is(err, errReplaySetup) {
+		return false
+	}
+	return causeOf(err).Error() == causeOf(failure).Error()
+}
+
+// causeOf returns the StateDB error wrapped by a replay error.
+func causeOf(err error) error {
+	if cause := errors.Unwrap(err); cause != nil {
+		return cause
+	}
+	return err
+}
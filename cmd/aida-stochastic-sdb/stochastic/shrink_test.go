@@ -0,0 +1,45 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestShrink_OnlyOriginalFailureIsReproduced(t *testing.T) {
+	cause := errors.New("invalid balance")
+	failure := fmt.Errorf("operation 12 (SubBalance): %w", cause)
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{fmt.Errorf("operation 12 (SubBalance): %w", cause), true},
+		{fmt.Errorf("operation 3 (SubBalance): %w", errors.New("invalid balance")), true},
+		{fmt.Errorf("operation 3 (SetNonce): %w", errors.New("invalid nonce")), false},
+		{fmt.Errorf("%w: invalid balance", errReplayPanic), false},
+		{fmt.Errorf("%w; cannot create directory", errReplaySetup), false},
+	}
+	for _, test := range tests {
+		if got := reproducesFailure(failure, test.err); got != test.want {
+			t.Errorf("unexpected reproduction of %v, got %v, want %v", test.err, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// StochasticValidateCommand data structure for the validate app.
+var StochasticValidateCommand = cli.Command{
+	Action:    stochasticValidateAction,
+	Name:      "validate",
+	Usage:     "Validates a simulation file against the recorded events",
+	ArgsUsage: "<events.json> <simulation.json>",
+	Flags: []cli.Flag{
+		&utils.BalanceRangeFlag,
+		&utils.BlockLengthFlag,
+		&utils.MaxCohensWFlag,
+		&utils.MaxKsDistanceFlag,
+		&utils.MaxTotalVariationFlag,
+		&utils.NonceRangeFlag,
+		&utils.RandomSeedFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The stochastic validate command requires two arguments:
+<events.json> <simulation.json>
+
+<events.json> is the event file produced by the stochastic recorder and
+<simulation.json> is a simulation file. The simulation is run for --block-length
+blocks on an in-memory StateDB whose events are counted, and the counted
+statistics are compared with the recorded ones. Each test has its own threshold
+since the divergences are on different scales: --max-cohens-w for operation
+frequencies, --max-total-variation for the stationary distribution and
+--max-ks-distance for access-class distributions. The command fails if any
+divergence exceeds its threshold.`,
+}
+
+// stochasticValidateAction implements the validate command.
+func stochasticValidateAction(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("missing events and simulation file as parameter")
+	}
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	log := logger.NewLogger(cfg.LogLevel, "Stochastic Validate")
+
+	events, err := stochastic.ReadEvents(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	simulation, err := stochastic.ReadSimulation(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Simulate %v blocks", cfg.BlockLength)
+	simulated, err := stochastic.SimulateEvents(simulation, int(cfg.BlockLength), cfg, log)
+	if err != nil {
+		return err
+	}
+
+	results, err := stochastic.ValidateSimulation(events, simulated, stochastic.ValidationThresholds{
+		CohensW:        cfg.MaxCohensW,
+		TotalVariation: cfg.MaxTotalVariation,
+		KsDistance:     cfg.MaxKsDistance,
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	log.Noticef("%-25v %-18v %10v %10v", "statistic", "test", "divergence", "threshold")
+	for _, res := range results {
+		status := "ok"
+		if res.Diverges() {
+			status = "FAILED"
+			failed++
+		}
+		log.Noticef("%-25v %-18v %10.4f %10.4f %-6v %v", res.Statistic, res.Test, res.Divergence, res.Threshold, status, res.Detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v statistics diverge by more than their threshold", failed, len(results))
+	}
+	return nil
+}
@@ -34,12 +34,19 @@ var StochasticVisualizeCommand = cli.Command{
 	ArgsUsage: "<event-file>",
 	Flags: []cli.Flag{
 		&utils.PortFlag,
+		&utils.OutputFlag,
 	},
 	Description: `
 The stochastic visualize command requires one argument:
 <events.json>
 
-<events.json> is the event file produced by the stochastic recorder.`,
+<events.json> is the event file produced by the stochastic recorder.
+
+Without the --output flag, the pages are served by a local web-server. With
+the --output flag, the pages are written as a static HTML report into the given
+directory whose index is index.html. Graphs are embedded as SVG; the echarts
+library is fetched from its asset host once and copied into the report so
+that the report can be viewed offline.`,
 }
 
 // stochasticVisualizeAction implements the visualize command for computing statistical parameters.
@@ -59,6 +66,12 @@ func stochasticVisualizeAction(ctx *cli.Context) error {
 		return err
 	}
 
+	// export events as static report
+	if outputDir := ctx.String(utils.OutputFlag.Name); outputDir != "" {
+		log.Noticef("Write report to %v", outputDir)
+		return visualizer.ExportReport(eventRegistry, outputDir)
+	}
+
 	// fire-up web-server and visualize events
 	port := ctx.String(utils.PortFlag.Name)
 	if port == "" {
@@ -33,6 +33,7 @@ var CloneCommand = cli.Command{
 		&CloneDb,
 		&ClonePatch,
 		&CloneCustom,
+		&CloneContracts,
 	},
 }
 
@@ -92,6 +93,30 @@ Clone custom is a specialized clone tool which copies specific components in aid
 `,
 }
 
+// CloneContracts enables creation of aida-db subset containing only transactions of given contracts
+var CloneContracts = cli.Command{
+	Action:    createContractsClone,
+	Name:      "contracts",
+	Usage:     "clone contracts creates aida-db subset with transactions touching given contracts",
+	ArgsUsage: "<blockNumFirst> <blockNumLast>",
+	Flags: []cli.Flag{
+		&utils.AidaDbFlag,
+		&utils.ContractsFlag,
+		&utils.TargetDbFlag,
+		&utils.CompactDbFlag,
+		&utils.WorkersFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+Clone contracts copies substates from the given block range whose input or output allocs contain
+ any of the given contract addresses, together with their deleted accounts. Substates modifying
+ an account touched by already copied substates (e.g. other transactions of the same sender) are
+ copied as well, hence the subset may contain more transactions than those of the given contracts.
+ State of all accounts touched by copied transactions is stored as update-set at block
+ <blockNumFirst> - 1, so the subset can be primed and executed by aida-vm-sdb from <blockNumFirst>.
+`,
+}
+
 // clonePatch creates aida-db patch
 func clonePatch(ctx *cli.Context) error {
 	// TODO refactor
@@ -176,3 +201,26 @@ func createCustomClone(ctx *cli.Context) error {
 
 	return nil
 }
+
+// createContractsClone creates aida-db subset with transactions of given contracts
+func createContractsClone(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.BlockRangeArgs)
+	if err != nil {
+		return err
+	}
+
+	aidaDb, targetDb, err := utildb.OpenCloningDbs(cfg.AidaDb, cfg.TargetDb)
+	if err != nil {
+		return err
+	}
+
+	err = utildb.CloneContracts(cfg, aidaDb, targetDb)
+	if err != nil {
+		return err
+	}
+
+	utildb.MustCloseDB(aidaDb)
+	utildb.MustCloseDB(targetDb)
+
+	return utildb.PrintMetadata(cfg.TargetDb)
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Aida/cmd/util-db/flags"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utildb"
+	"github.com/Fantom-foundation/Aida/utildb/dbcomponent"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/urfave/cli/v2"
+)
+
+// FsckCommand checks the integrity of AidaDb per block range and optionally repairs damaged ranges.
+var FsckCommand = cli.Command{
+	Action: fsck,
+	Name:   "fsck",
+	Usage:  "Checks integrity of AidaDb per block range and repairs damaged ranges.",
+	Flags: []cli.Flag{
+		&utils.AidaDbFlag,
+		&utils.ChainIDFlag,
+		&utils.DbComponentFlag,
+		&utils.DbTmpFlag,
+		&utils.PatchCacheFlag,
+		&utils.PatchMirrorFlag,
+		&utils.UpdateTypeFlag,
+		&flags.RangeSizeFlag,
+		&flags.RepairFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+Walks substates, update-sets, deleted accounts and state hashes of AidaDb in block
+ranges of --range-size blocks. For each range, the records are decoded, gaps are
+detected, and a hash over the decoded records is printed. The metadata block range
+is checked against the substates.
+
+With --repair, damaged block ranges are re-fetched from the aida-db patches and
+their records are replaced. Afterwards, the metadata block range is set to the
+block range of the substates; epochs which are inconsistent or belong to a changed
+block range are reset to zero.
+
+Limitations: ranges which are not covered by any patch, invalid keys and a missing
+substate component are not repaired; such an AidaDb needs to be re-generated.
+`,
+}
+
+// fsck checks the integrity of AidaDb and repairs damaged block ranges if requested.
+func fsck(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	log := logger.NewLogger(cfg.LogLevel, "Fsck")
+
+	component, err := dbcomponent.ParseDbComponent(cfg.DbComponent)
+	if err != nil {
+		return err
+	}
+	rangeSize := ctx.Uint64(flags.RangeSizeFlag.Name)
+	repair := ctx.Bool(flags.RepairFlag.Name)
+
+	var aidaDb db.BaseDB
+	if repair {
+		aidaDb, err = db.NewDefaultBaseDB(cfg.AidaDb)
+	} else {
+		aidaDb, err = db.NewReadOnlyBaseDB(cfg.AidaDb)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open db; %v", err)
+	}
+	defer utildb.MustCloseDB(aidaDb)
+
+	report, err := utildb.Fsck(aidaDb, component, rangeSize, log)
+	if err != nil {
+		return err
+	}
+	printFsckReport(report, log)
+	if report.Ok() {
+		log.Notice("No problems found")
+		return nil
+	}
+	if !repair {
+		return fmt.Errorf("aida-db is damaged; %v problems and %v damaged block ranges found", len(report.Problems), len(report.Damaged()))
+	}
+
+	remaining, err := utildb.RepairFsck(cfg, aidaDb, report, log)
+	if err != nil {
+		return err
+	}
+	for _, rng := range remaining {
+		log.Warningf("%v of blocks %v-%v is not covered by any patch and needs to be re-generated", rng.Component, rng.First, rng.Last)
+	}
+
+	// check repaired db again
+	log.Notice("Checking repaired AidaDb...")
+	report, err = utildb.Fsck(aidaDb, component, rangeSize, log)
+	if err != nil {
+		return err
+	}
+	printFsckReport(report, log)
+	if !report.Ok() {
+		if len(remaining) > 0 {
+			return fmt.Errorf("aida-db is still damaged; %v block ranges are not covered by any patch and need to be re-generated", len(remaining))
+		}
+		return fmt.Errorf("aida-db is still damaged; %v problems and %v damaged block ranges found", len(report.Problems), len(report.Damaged()))
+	}
+	log.Notice("Repair successful!")
+	return nil
+}
+
+// printFsckReport prints the hashes of the checked block ranges and the problems found.
+func printFsckReport(report *utildb.FsckReport, log logger.Logger) {
+	for _, problem := range report.Problems {
+		log.Warning(problem)
+	}
+	for _, rng := range report.Ranges {
+		log.Infof("%v %v-%v: %v records; hash %x", rng.Component, rng.First, rng.Last, rng.Count, rng.Hash)
+		if rng.NumProblems == 0 {
+			continue
+		}
+		log.Warningf("%v %v-%v is damaged; %v problems", rng.Component, rng.First, rng.Last, rng.NumProblems)
+		for _, problem := range rng.Problems {
+			log.Warningf("\t%v", problem)
+		}
+	}
+}
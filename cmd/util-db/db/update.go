@@ -34,11 +34,19 @@ var UpdateCommand = cli.Command{
 		&logger.LogLevelFlag,
 		&utils.CompactDbFlag,
 		&utils.DbTmpFlag,
+		&utils.PatchCacheFlag,
+		&utils.PatchMirrorFlag,
 		&utils.ValidateFlag,
 		&utils.UpdateTypeFlag,
 	},
 	Description: ` 
 Updates aida-db by downloading patches from aida-db generation server.
+
+Interrupted downloads are resumed and every patch is verified against the
+checksum listed in patches.json before it is merged. With --patch-cache,
+downloaded patches are kept and reused by later updates. With --patch-mirror,
+patches are taken from a local directory or file server instead of the
+aida-db generation server.
 `,
 }
 
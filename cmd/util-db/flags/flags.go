@@ -41,4 +41,13 @@ var (
 		Name:  "force",
 		Usage: "Forces generation even when dbHash is found.",
 	}
+	RangeSizeFlag = cli.Uint64Flag{
+		Name:  "range-size",
+		Usage: "Number of blocks of the ranges which are checked and hashed separately",
+		Value: 100_000,
+	}
+	RepairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Re-fetches damaged block ranges from aida-db patches",
+	}
 )
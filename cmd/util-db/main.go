@@ -43,6 +43,7 @@ var UtilDbApp = cli.App{
 		&db.UpdateCommand,
 		&db.InfoCommand,
 		&db.ValidateCommand,
+		&db.FsckCommand,
 		&db.GenDeletedAccountsCommand,
 		&db.SubstateDumpCommand,
 		&db.GenerateDbHashCommand,
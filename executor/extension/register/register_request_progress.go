@@ -84,6 +84,7 @@ func makeRegisterRequestProgress(cfg *utils.Config, reportFrequency int, when wh
 		when:            when,
 		ps:              utils.NewPrinters(),
 		id:              rr.MakeRunIdentity(time.Now().Unix(), cfg),
+		analytics:       newRpcMethodAnalytics(),
 	}
 }
 
@@ -115,6 +116,10 @@ type registerRequestProgress struct {
 
 	id   *rr.RunIdentity
 	meta *rr.RunMetadata
+
+	// Per-method analytics
+	analytics      *rpcMethodAnalytics
+	methodsPrinter *utils.PrinterToDb
 }
 
 type rpcProcessInfo struct {
@@ -138,6 +143,12 @@ func (rp *registerRequestProgress) PreRun(executor.State[*rpc.RequestAndResults]
 	}
 	rp.ps.AddPrinter(p2db)
 
+	// per-method analytics are stored only once the run is finished
+	rp.methodsPrinter, err = utils.NewPrinterToSqlite3(connection, registerRpcMethodsCreateTableIfNotExist, registerRpcMethodsInsertOrReplace, rp.analytics.rows)
+	if err != nil {
+		return err
+	}
+
 	// 3. if metadata could be fetched -> continue without the failed metadata
 	rm, err := rr.MakeRunMetadata(connection, rp.id, rr.FetchUnixInfo)
 
@@ -158,7 +169,9 @@ func (rp *registerRequestProgress) PreRun(executor.State[*rpc.RequestAndResults]
 }
 
 // PostTransaction increments number of transactions and saves gas used in last substate.
+// Execution time measured by the processor and outcome of the request are recorded into per-method analytics.
 func (rp *registerRequestProgress) PostTransaction(state executor.State[*rpc.RequestAndResults], ctx *executor.Context) error {
+	rp.analytics.add(state.Data, ctx.ExecutionResult, state.Data.ExecutionTime)
 
 	rp.lock.Lock()
 	defer rp.lock.Unlock()
@@ -205,6 +218,12 @@ func (rp *registerRequestProgress) PostRun(_ executor.State[*rpc.RequestAndResul
 	rp.ps.Print()
 	rp.ps.Close()
 
+	rp.analytics.print(rp.log)
+	if err := rp.methodsPrinter.Print(); err != nil {
+		rp.log.Errorf("Cannot register per-method analytics; %v", err)
+	}
+	rp.methodsPrinter.Close()
+
 	rp.meta.Meta["Runtime"] = strconv.Itoa(int(time.Since(rp.startOfRun).Seconds()))
 	if err != nil {
 		rp.meta.Meta["RunSucceed"] = strconv.FormatBool(false)
@@ -0,0 +1,217 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package register
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/txcontext"
+)
+
+const (
+	registerRpcMethodsCreateTableIfNotExist = `
+		CREATE TABLE IF NOT EXISTS stats_rpc_methods (
+			method TEXT NOT NULL,
+			count INTEGER NOT NULL,
+			errors INTEGER NOT NULL,
+			mismatches TEXT,
+			latency_mean_ms float,
+			latency_p50_ms float,
+			latency_p90_ms float,
+			latency_p99_ms float,
+			latency_max_ms float,
+			latency_histogram TEXT,
+			gas_used INTEGER
+		)
+	`
+	registerRpcMethodsInsertOrReplace = `
+		INSERT or REPLACE INTO stats_rpc_methods (
+			method, count, errors, mismatches,
+			latency_mean_ms, latency_p50_ms, latency_p90_ms, latency_p99_ms, latency_max_ms, latency_histogram,
+			gas_used
+		) VALUES (
+			?, ?, ?, ?,
+			?, ?, ?, ?, ?, ?,
+			?
+		)
+	`
+)
+
+// latencyBounds are upper bounds of latency histogram buckets, the last bucket is unbounded.
+var latencyBounds = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// rpcMethodAnalytics collects latency, error and gas statistics of every replayed method.
+type rpcMethodAnalytics struct {
+	lock    sync.Mutex
+	methods map[string]*rpcMethodStats
+}
+
+// rpcMethodStats are statistics of a single method.
+type rpcMethodStats struct {
+	count        uint64
+	errors       uint64            // number of requests StateDB returned an error for
+	mismatches   map[string]uint64 // number of comparator errors by their type
+	latencySum   time.Duration
+	latencyMax   time.Duration
+	latencyHisto []uint64
+	gasUsed      uint64
+}
+
+func newRpcMethodAnalytics() *rpcMethodAnalytics {
+	return &rpcMethodAnalytics{
+		methods: make(map[string]*rpcMethodStats),
+	}
+}
+
+// add records the outcome of a single request handled within given time.
+func (a *rpcMethodAnalytics) add(data *rpc.RequestAndResults, result txcontext.Result, latency time.Duration) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	s, ok := a.methods[data.Query.MethodBase]
+	if !ok {
+		s = &rpcMethodStats{
+			mismatches:   make(map[string]uint64),
+			latencyHisto: make([]uint64, len(latencyBounds)+1),
+		}
+		a.methods[data.Query.MethodBase] = s
+	}
+
+	s.count++
+	s.latencySum += latency
+	s.latencyMax = max(s.latencyMax, latency)
+	i := sort.Search(len(latencyBounds), func(i int) bool { return latency <= latencyBounds[i] })
+	s.latencyHisto[i]++
+
+	if result != nil {
+		if _, err := result.GetRawResult(); err != nil {
+			s.errors++
+		}
+		// only calls consume gas in a meaningful way
+		if data.Query.MethodBase == "call" {
+			s.gasUsed += result.GetGasUsed()
+		}
+	}
+
+	if data.MismatchType != "" {
+		s.mismatches[data.MismatchType]++
+	}
+}
+
+// percentile returns upper bound of the histogram bucket containing given percentile (0-1).
+// The maximum latency is returned for the unbounded bucket.
+func (s *rpcMethodStats) percentile(p float64) time.Duration {
+	target := uint64(float64(s.count)*p + 0.5)
+	if target == 0 {
+		target = 1
+	}
+
+	var seen uint64
+	for i, n := range s.latencyHisto {
+		seen += n
+		if seen >= target {
+			if i < len(latencyBounds) {
+				return min(latencyBounds[i], s.latencyMax)
+			}
+			break
+		}
+	}
+	return s.latencyMax
+}
+
+func (s *rpcMethodStats) mean() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return s.latencySum / time.Duration(s.count)
+}
+
+// sortedMethods returns names of all recorded methods in alphabetical order.
+func (a *rpcMethodAnalytics) sortedMethods() []string {
+	names := make([]string, 0, len(a.methods))
+	for m := range a.methods {
+		names = append(names, m)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// print logs statistics of every method.
+func (a *rpcMethodAnalytics) print(log logger.Logger) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, m := range a.sortedMethods() {
+		s := a.methods[m]
+		log.Noticef("%v: count %v, errors %v, latency mean %v, p50 %v, p90 %v, p99 %v, max %v",
+			m, s.count, s.errors, s.mean(), s.percentile(0.5), s.percentile(0.9), s.percentile(0.99), s.latencyMax)
+		if m == "call" {
+			log.Noticef("\tgas used %v", s.gasUsed)
+		}
+		types := make([]string, 0, len(s.mismatches))
+		for typ := range s.mismatches {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		for _, typ := range types {
+			log.Noticef("\t%v: %v", typ, s.mismatches[typ])
+		}
+	}
+}
+
+// rows returns statistics of every method formatted for the register database.
+func (a *rpcMethodAnalytics) rows() [][]any {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	rows := make([][]any, 0, len(a.methods))
+	for _, m := range a.sortedMethods() {
+		s := a.methods[m]
+		// maps and slices of integers are always encodable
+		mismatches, _ := json.Marshal(s.mismatches)
+		histogram, _ := json.Marshal(s.latencyHisto)
+		rows = append(rows, []any{
+			m, s.count, s.errors, string(mismatches),
+			toMs(s.mean()), toMs(s.percentile(0.5)), toMs(s.percentile(0.9)), toMs(s.percentile(0.99)), toMs(s.latencyMax), string(histogram),
+			s.gasUsed,
+		})
+	}
+	return rows
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package register
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+func newAnalyticsTestRequest(method string) *rpc.RequestAndResults {
+	return &rpc.RequestAndResults{
+		Query: &rpc.Body{MethodBase: method},
+	}
+}
+
+func TestRpcMethodAnalytics_CollectsStatisticsPerMethod(t *testing.T) {
+	a := newRpcMethodAnalytics()
+
+	for i := 1; i <= 100; i++ {
+		a.add(newAnalyticsTestRequest("call"), rpc.NewResult([]byte{1}, nil, 10), time.Duration(i)*time.Millisecond)
+	}
+	failed := newAnalyticsTestRequest("getBalance")
+	failed.MismatchType = "no-matching-result"
+	a.add(failed, rpc.NewResult(nil, errors.New("failure"), 0), time.Millisecond)
+
+	call := a.methods["call"]
+	if call.count != 100 || call.gasUsed != 1000 || call.errors != 0 {
+		t.Errorf("unexpected call stats: count %v, gas %v, errors %v", call.count, call.gasUsed, call.errors)
+	}
+	if got, want := call.percentile(0.5), 50*time.Millisecond; got != want {
+		t.Errorf("unexpected p50, got %v, want %v", got, want)
+	}
+	if got, want := call.percentile(0.99), 100*time.Millisecond; got != want {
+		t.Errorf("unexpected p99, got %v, want %v", got, want)
+	}
+	if got, want := call.latencyMax, 100*time.Millisecond; got != want {
+		t.Errorf("unexpected max, got %v, want %v", got, want)
+	}
+
+	balance := a.methods["getBalance"]
+	if balance.errors != 1 || balance.mismatches["no-matching-result"] != 1 {
+		t.Errorf("unexpected getBalance stats: errors %v, mismatches %v", balance.errors, balance.mismatches)
+	}
+
+	rows := a.rows()
+	if got, want := len(rows), 2; got != want {
+		t.Fatalf("unexpected number of rows, got %v, want %v", got, want)
+	}
+	if rows[0][0] != "call" || rows[1][0] != "getBalance" {
+		t.Errorf("rows must be sorted by method, got %v and %v", rows[0][0], rows[1][0])
+	}
+}
+
+func TestRegisterRequestProgress_RecordsExecutionTimeOfProcessor(t *testing.T) {
+	cfg := &utils.Config{}
+	ext := makeRegisterRequestProgress(cfg, defaultRequestReportFrequency, OnPreBlock, logger.NewLogger("critical", "Test"))
+
+	req := newAnalyticsTestRequest("call")
+	req.ExecutionTime = 42 * time.Millisecond
+	ctx := &executor.Context{ExecutionResult: rpc.NewResult([]byte{1}, nil, 10)}
+	if err := ext.PostTransaction(executor.State[*rpc.RequestAndResults]{Data: req}, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	call := ext.analytics.methods["call"]
+	if call == nil || call.count != 1 {
+		t.Fatalf("request was not recorded")
+	}
+	if got, want := call.latencyMax, 42*time.Millisecond; got != want {
+		t.Errorf("unexpected latency, got %v, want %v", got, want)
+	}
+}
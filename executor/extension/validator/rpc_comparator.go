@@ -18,7 +18,9 @@ package validator
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"strconv"
 	"strings"
@@ -30,6 +32,7 @@ import (
 	"github.com/Fantom-foundation/Aida/txcontext"
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
@@ -47,6 +50,29 @@ const (
 	internalError
 )
 
+func (t comparatorErrorType) String() string {
+	switch t {
+	case noMatchingResult:
+		return "no-matching-result"
+	case noMatchingErrors:
+		return "no-matching-errors"
+	case expectedErrorGotResult:
+		return "expected-error-got-result"
+	case expectedResultGotError:
+		return "expected-result-got-error"
+	case unexpectedDataType:
+		return "unexpected-data-type"
+	case cannotUnmarshalResult:
+		return "cannot-unmarshal-result"
+	case cannotSendRpcRequest:
+		return "cannot-send-rpc-request"
+	case internalError:
+		return "internal-error"
+	default:
+		return "other"
+	}
+}
+
 const (
 	// internalErrorCode is created when RPC-API could not execute request
 	// - for purpose of replay, this error is not critical and does not cause an error
@@ -96,7 +122,13 @@ func MakeRpcComparator(cfg *utils.Config) executor.Extension[*rpc.RequestAndResu
 }
 
 func makeRPCComparator(cfg *utils.Config, log logger.Logger) *rpcComparator {
-	return &rpcComparator{cfg: cfg, log: log}
+	return &rpcComparator{
+		cfg:                   cfg,
+		log:                   log,
+		estimateGasDeviations: newEstimateGasDeviations(),
+		reference:             newRpcReference(cfg),
+		rules:                 newDefaultRpcRules(),
+	}
 }
 
 type rpcComparator struct {
@@ -106,6 +138,19 @@ type rpcComparator struct {
 	numberOfRetriedRequests int
 	totalNumberOfRequests   int
 	numberOfErrors          int
+	estimateGasDeviations   *estimateGasDeviations
+	reference               *rpcReference
+	rules                   *rpcRules
+}
+
+// PreRun loads comparison rules and responses cached from the reference endpoint.
+func (c *rpcComparator) PreRun(executor.State[*rpc.RequestAndResults], *executor.Context) error {
+	if c.cfg.RpcRulesFile != "" {
+		if err := c.rules.load(c.cfg.RpcRulesFile); err != nil {
+			return err
+		}
+	}
+	return c.reference.open()
 }
 
 // PostTransaction compares result with recording. If ContinueOnFailure
@@ -118,7 +163,19 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 		return nil
 	}
 
-	compareErr := compare(ctx.ExecutionResult, state)
+	if c.rules.excluded(state.Data, state.Block) {
+		return nil
+	}
+
+	if state.Data.Query.MethodBase == "estimateGas" {
+		// deviation is collected once the comparison, including possible resend, is finished
+		defer c.estimateGasDeviations.add(ctx.ExecutionResult, state.Data)
+	}
+
+	compareErr, rule := c.compare(ctx.ExecutionResult, state, ctx)
+	if rule != nil {
+		rule.hits.Add(1)
+	}
 	if compareErr != nil {
 		// request method base 'call' cannot be resent, because we need timestamp of the block that executed
 		// this request. As of right now there we cannot get the timestamp, hence we skip these requests
@@ -128,11 +185,13 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 			if state.Data.Error != nil {
 				return nil
 			} else {
+				state.Data.MismatchType = compareErr.typ.String()
 				return compareErr
 			}
 		}
 		// lot errors are recorded wrongly, for this case we resend the request and compare it again
-		if !state.Data.IsRecovered {
+		// - getLogs cannot be resent since its block range is part of the filter object
+		if !state.Data.IsRecovered && state.Data.Query.MethodBase != "getLogs" {
 			c.log.Debugf("retrying %v request", state.Data.Query.Method)
 			c.numberOfRetriedRequests++
 			c.log.Debugf("current ration retried against total %v/%v", c.numberOfRetriedRequests, c.totalNumberOfRequests)
@@ -140,7 +199,10 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 			if err := c.resendRequest(ctx.ExecutionResult, state); err != nil {
 				return err
 			}
-			compareErr = compare(ctx.ExecutionResult, state)
+			compareErr, rule = c.compare(ctx.ExecutionResult, state, ctx)
+			if rule != nil {
+				rule.hits.Add(1)
+			}
 			if compareErr == nil {
 				return nil
 			}
@@ -150,6 +212,8 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 			return nil
 		}
 
+		state.Data.MismatchType = compareErr.typ.String()
+
 		if !c.cfg.ContinueOnFailure {
 			return compareErr
 		}
@@ -170,26 +234,81 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 	return nil
 }
 
-func compare(result txcontext.Result, state executor.State[*rpc.RequestAndResults]) *comparatorError {
-	switch state.Data.Query.MethodBase {
+// compare result with recording, mismatches accepted by a tolerance rule are not reported.
+// The rule is returned only if it turned a mismatch into a match.
+func (c *rpcComparator) compare(result txcontext.Result, state executor.State[*rpc.RequestAndResults], ctx *executor.Context) (*comparatorError, *rpcRule) {
+	var rule *rpcRule
+	tolerance := c.cfg.RpcEstimateGasTolerance
+	if state.Data.Query.MethodBase == "estimateGas" {
+		rule = c.rules.gasToleranceRule(state.Data, state.Block)
+		if rule != nil {
+			tolerance = *rule.GasTolerance
+		}
+	}
+
+	err := c.compareMethod(result, state, ctx, tolerance)
+	if err == nil {
+		// the rule is applied only if the default tolerance reports a mismatch
+		if rule != nil && c.compareMethod(result, state, ctx, c.cfg.RpcEstimateGasTolerance) != nil {
+			return nil, rule
+		}
+		return nil, nil
+	}
+
+	if rule = c.rules.errorRule(result, state.Data, state.Block); rule != nil {
+		return nil, rule
+	}
+	return err, nil
+}
+
+func (c *rpcComparator) compareMethod(result txcontext.Result, state executor.State[*rpc.RequestAndResults], ctx *executor.Context, tolerance float64) *comparatorError {
+	if state.Data.Query.MethodBase == "getProof" {
+		root, err := ctx.Archive.GetHash()
+		if err != nil {
+			return &comparatorError{
+				error: fmt.Errorf("cannot get state root of block %v; %w", state.Data.RequestedBlock, err),
+				typ:   defaultErrorType,
+			}
+		}
+		return compareProof(result, state.Data, state.Block, root)
+	}
+
+	return compareResult(result, state.Data, state.Block, tolerance)
+}
+
+// compareResult compares result of methods which do not depend on the archive with recorded data.
+func compareResult(result txcontext.Result, data *rpc.RequestAndResults, block int, tolerance float64) *comparatorError {
+	switch data.Query.MethodBase {
 	case "getBalance":
-		return compareBalance(result, state.Data, state.Block)
+		return compareBalance(result, data, block)
 	case "getTransactionCount":
-		return compareTransactionCount(result, state.Data, state.Block)
+		return compareTransactionCount(result, data, block)
 	case "call":
-		return compareCall(result, state.Data, state.Block)
+		return compareCall(result, data, block)
 	case "estimateGas":
-		// estimateGas is currently not suitable for replay since the estimation  in geth is always calculated
-		// for current state that means recorded result and result returned by StateDB are not comparable
+		return compareEstimateGas(result, data, block, tolerance)
 	case "getCode":
-		return compareCode(result, state.Data, state.Block)
+		return compareCode(result, data, block)
 	case "getStorageAt":
-		return compareStorageAt(result, state.Data, state.Block)
+		return compareStorageAt(result, data, block)
+	case "getLogs":
+		return compareLogs(result, data, block)
 	}
 
 	return nil
 }
 
+// CompareRpcResult compares result with recorded data in the same way as the rpc comparator does.
+// Results which cannot be unmarshalled are not reported, the same as within the comparator.
+// Proofs cannot be verified without the state root, hence they are not compared.
+func CompareRpcResult(result txcontext.Result, data *rpc.RequestAndResults, tolerance float64) error {
+	err := compareResult(result, data, data.RecordedBlock, tolerance)
+	if err == nil || err.typ == cannotUnmarshalResult {
+		return nil
+	}
+	return err
+}
+
 func (c *rpcComparator) resendRequest(result txcontext.Result, state executor.State[*rpc.RequestAndResults]) *comparatorError {
 	var payload []byte
 
@@ -220,8 +339,12 @@ func (c *rpcComparator) resendRequest(result txcontext.Result, state executor.St
 	}
 
 	c.log.Debugf("Retried params: %v", retriedReq.Params)
-	// we only record on mainnet, so we can safely put mainnet chainID constant here
-	m, err := utils.SendRpcRequest(retriedReq, utils.MainnetChainID)
+	m, err := c.reference.send(retriedReq)
+	if errors.Is(err, errNotCached) {
+		// the recorded data are kept, so the request is compared with them once again
+		c.log.Debugf("%v request is not cached, skipping resend", retriedReq.Method)
+		return nil
+	}
 	if err != nil {
 		return newComparatorError(result, nil, nil, state.Data, state.Block, cannotSendRpcRequest)
 	}
@@ -458,11 +581,12 @@ func compareEVMStateDBError(result txcontext.Result, err error, data *rpc.Reques
 		noMatchingErrors)
 }
 
-// compareEstimateGas compares recorded data for estimateGas method with result from StateDB
-func compareEstimateGas(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
+// compareEstimateGas compares recorded data for estimateGas method with result from StateDB.
+// Results are accepted if their relative deviation does not exceed given tolerance.
+func compareEstimateGas(result txcontext.Result, data *rpc.RequestAndResults, block int, tolerance float64) *comparatorError {
 	res, err := result.GetRawResult()
 	if res != nil {
-		return compareEstimateGasStateDBResult(result, res, data, block)
+		return compareEstimateGasStateDBResult(result, res, data, block, tolerance)
 	}
 
 	if err != nil {
@@ -473,7 +597,7 @@ func compareEstimateGas(result txcontext.Result, data *rpc.RequestAndResults, bl
 }
 
 // compareEstimateGasStateDBResult compares estimateGas data recorded on API server with data returned by StateDB
-func compareEstimateGasStateDBResult(result txcontext.Result, res []byte, data *rpc.RequestAndResults, block int) *comparatorError {
+func compareEstimateGasStateDBResult(result txcontext.Result, res []byte, data *rpc.RequestAndResults, block int, tolerance float64) *comparatorError {
 	stateDBGas := littleendian.BytesToUint64(res)
 
 	// did we receive an error
@@ -507,8 +631,8 @@ func compareEstimateGasStateDBResult(result txcontext.Result, res []byte, data *
 		return newComparatorError(result, recordedResult, string(data.Response.Result), data, block, cannotUnmarshalResult)
 	}
 
-	if stateDBGas != recordedResult {
-		return newComparatorError(result, recordedResult, recordedString, data, block, noMatchingResult)
+	if math.Abs(relativeDeviation(stateDBGas, recordedResult)) > tolerance {
+		return newComparatorError(result, stateDBGas, recordedString, data, block, noMatchingResult)
 	}
 
 	return nil
@@ -571,6 +695,111 @@ func compareStorageAt(result txcontext.Result, data *rpc.RequestAndResults, bloc
 	return nil
 }
 
+// PostRun reports distribution of estimateGas deviations, hits of comparison rules and closes the reference cache.
+func (c *rpcComparator) PostRun(executor.State[*rpc.RequestAndResults], *executor.Context, error) error {
+	c.estimateGasDeviations.print(c.log)
+	if c.cfg.RpcRulesFile != "" {
+		c.rules.print(c.log)
+	}
+	return c.reference.close()
+}
+
+// compareProof verifies getProof data recorded on API server and proof returned by StateDB against the state root
+// of the archive. Both proofs must be valid and claim the same values, their encoding is not compared.
+func compareProof(result txcontext.Result, data *rpc.RequestAndResults, block int, root common.Hash) *comparatorError {
+	res, err := result.GetRawResult()
+	if err != nil {
+		return compareEVMStateDBError(result, err, data, block)
+	}
+
+	if data.Error != nil {
+		// internal error?
+		if data.Error.Error.Code == internalErrorCode {
+			return newComparatorError(result, string(res), data.Error.Error, data, block, internalError)
+		}
+		return newComparatorError(result, string(res), data.Error.Error, data, block, expectedErrorGotResult)
+	}
+
+	var dbProof, recordedProof rpc.AccountResult
+	if err = json.Unmarshal(res, &dbProof); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+	if err = json.Unmarshal(data.Response.Result, &recordedProof); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+
+	if err = dbProof.Verify(root); err != nil {
+		return newComparatorError(result, fmt.Sprintf("invalid proof; %v", err), string(data.Response.Result), data, block, noMatchingResult)
+	}
+	if err = recordedProof.Verify(root); err != nil {
+		return newComparatorError(result, string(res), fmt.Sprintf("invalid proof; %v", err), data, block, noMatchingResult)
+	}
+
+	// both proofs are valid for the same root, hence they can only differ in requested slots
+	if dbProof.Address != recordedProof.Address || len(dbProof.StorageProof) != len(recordedProof.StorageProof) {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, noMatchingResult)
+	}
+	for i, sp := range dbProof.StorageProof {
+		if common.HexToHash(sp.Key) != common.HexToHash(recordedProof.StorageProof[i].Key) {
+			return newComparatorError(result, string(res), string(data.Response.Result), data, block, noMatchingResult)
+		}
+	}
+
+	return nil
+}
+
+// comparableLog contains fields of a log which can be reconstructed from substates
+type comparableLog struct {
+	Address     common.Address `json:"address"`
+	Topics      []common.Hash  `json:"topics"`
+	Data        hexutil.Bytes  `json:"data"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+func (l comparableLog) String() string {
+	return fmt.Sprintf("{block: %d, address: %v, topics: %v, data: %v}", uint64(l.BlockNumber), l.Address, l.Topics, l.Data)
+}
+
+// compareLogs compares getLogs data recorded on API server with logs reconstructed from substates.
+// Logs are compared as sets, hence their order does not matter.
+func compareLogs(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
+	res, _ := result.GetRawResult()
+
+	if data.Error != nil {
+		// internal error?
+		if data.Error.Error.Code == internalErrorCode {
+			return newComparatorError(result, string(res), data.Error.Error, data, block, internalError)
+		}
+		return newComparatorError(result, string(res), data.Error.Error, data, block, expectedErrorGotResult)
+	}
+
+	var dbLogs, recordedLogs []comparableLog
+	if err := json.Unmarshal(res, &dbLogs); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+	if err := json.Unmarshal(data.Response.Result, &recordedLogs); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+
+	if len(dbLogs) != len(recordedLogs) {
+		return newComparatorError(result, dbLogs, recordedLogs, data, block, noMatchingResult)
+	}
+
+	counts := make(map[string]int, len(dbLogs))
+	for _, l := range dbLogs {
+		counts[l.String()]++
+	}
+	for _, l := range recordedLogs {
+		key := l.String()
+		if counts[key] == 0 {
+			return newComparatorError(result, dbLogs, recordedLogs, data, block, noMatchingResult)
+		}
+		counts[key]--
+	}
+
+	return nil
+}
+
 // newComparatorError returns new comparatorError with given StateDB and recorded data based on the typ.
 func newComparatorError(result txcontext.Result, stateDB, expected any, data *rpc.RequestAndResults, block int, typ comparatorErrorType) *comparatorError {
 	switch typ {
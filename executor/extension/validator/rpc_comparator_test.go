@@ -21,6 +21,7 @@ import (
 	"errors"
 	"math/big"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Fantom-foundation/Aida/executor"
@@ -29,6 +30,7 @@ import (
 	"github.com/Fantom-foundation/Aida/rpc"
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/status-im/keycard-go/hexutils"
 )
 
@@ -334,7 +336,7 @@ func Test_compareEstimateGasOK(t *testing.T) {
 	}
 
 	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(1)), nil, 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err != nil {
 		t.Errorf("error must be nil; err: %v", err)
 	}
@@ -355,7 +357,7 @@ func Test_compareEstimateGasErrorNoMatchingResult(t *testing.T) {
 	}
 
 	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(0)), nil, 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err == nil {
 		t.Errorf("error must not be null")
 		return
@@ -380,7 +382,7 @@ func Test_compareEstimateGasErrorExpectedResultGotErr(t *testing.T) {
 	}
 
 	res := rpc.NewResult(nil, errors.New("error"), 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err == nil {
 		t.Errorf("error must be nil; err: %v", err)
 		return
@@ -406,7 +408,7 @@ func Test_compareEstimateGasErrorExpectedErrGotResult(t *testing.T) {
 		},
 	}
 	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(0)), nil, 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err == nil {
 		t.Errorf("error must not be null")
 		return
@@ -514,3 +516,174 @@ func Test_compareStorageAtErrorNoMatchingResult(t *testing.T) {
 	}
 
 }
+
+// Test_compareLogsOKRegardlessOfOrder tests compare func for getLogs method
+// It expects no error since both sides contain same logs in different order
+func Test_compareLogsOKRegardlessOfOrder(t *testing.T) {
+	rec := []byte(`[` +
+		`{"address":"0x0000000000000000000000000000000000000002","topics":[],"data":"0x02","blockNumber":"0x1"},` +
+		`{"address":"0x0000000000000000000000000000000000000001","topics":["0x0000000000000000000000000000000000000000000000000000000000000001"],"data":"0x01","blockNumber":"0x1"}` +
+		`]`)
+	db := []byte(`[` +
+		`{"address":"0x0000000000000000000000000000000000000001","topics":["0x0000000000000000000000000000000000000000000000000000000000000001"],"data":"0x01","blockNumber":"0x1"},` +
+		`{"address":"0x0000000000000000000000000000000000000002","topics":[],"data":"0x02","blockNumber":"0x1"}` +
+		`]`)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getLogs",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(db, nil, 0)
+	err := compareLogs(res, data, 0)
+	if err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+}
+
+// Test_compareLogsErrorNoMatchingResult tests compare func for getLogs method
+// It expects an error of no matching results since log data are different
+func Test_compareLogsErrorNoMatchingResult(t *testing.T) {
+	rec := []byte(`[{"address":"0x0000000000000000000000000000000000000001","topics":[],"data":"0x01","blockNumber":"0x1"}]`)
+	db := []byte(`[{"address":"0x0000000000000000000000000000000000000001","topics":[],"data":"0x02","blockNumber":"0x1"}]`)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getLogs",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(db, nil, 0)
+	err := compareLogs(res, data, 0)
+	if err == nil {
+		t.Errorf("error must not be nil; err: %v", err)
+		return
+	}
+
+	if err.typ != noMatchingResult {
+		t.Errorf("error must be type 'noMatchingResult'; err: %v", err)
+	}
+}
+
+// Test_compareEstimateGasAcceptsResultWithinTolerance tests compare func for estimateGas method
+// It expects no error if the deviation is within tolerance and an error otherwise
+func Test_compareEstimateGasAcceptsResultWithinTolerance(t *testing.T) {
+	rec, _ := json.Marshal("0x64")
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_estimateGas",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(103)), nil, 10)
+	if err := compareEstimateGas(res, data, 0, 0.05); err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+
+	err := compareEstimateGas(res, data, 0, 0.01)
+	if err == nil {
+		t.Fatal("error must not be nil")
+	}
+	if err.typ != noMatchingResult {
+		t.Errorf("error must be type 'noMatchingResult'; err: %v", err)
+	}
+}
+
+func TestEstimateGasDeviations_DeviationsAreSortedIntoBuckets(t *testing.T) {
+	d := newEstimateGasDeviations()
+	d.addDeviation(0)
+	d.addDeviation(0.005)
+	d.addDeviation(-0.005)
+	d.addDeviation(2)
+
+	if got, want := d.total, uint64(4); got != want {
+		t.Errorf("unexpected total, got %v, want %v", got, want)
+	}
+	if d.higher != 2 || d.lower != 1 {
+		t.Errorf("unexpected direction counts, higher %v, lower %v", d.higher, d.lower)
+	}
+	if d.buckets[0] != 1 || d.buckets[2] != 2 || d.buckets[len(d.buckets)-1] != 1 {
+		t.Errorf("unexpected buckets %v", d.buckets)
+	}
+	if d.maxDeviation != 2 {
+		t.Errorf("unexpected max deviation %v", d.maxDeviation)
+	}
+}
+
+func TestEstimateGasDeviations_ConcurrentAddsAreAllCounted(t *testing.T) {
+	const (
+		workers  = 8
+		requests = 1000
+	)
+
+	rec, _ := json.Marshal("0x64")
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_estimateGas",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(103)), nil, 10)
+
+	// deviations are added from PostTransaction which runs on multiple workers
+	d := newEstimateGasDeviations()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				d.add(res, data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := d.total, uint64(workers*requests); got != want {
+		t.Errorf("unexpected total, got %v, want %v", got, want)
+	}
+	if got, want := d.higher, uint64(workers*requests); got != want {
+		t.Errorf("unexpected number of higher estimations, got %v, want %v", got, want)
+	}
+	if got, want := d.buckets[3], uint64(workers*requests); got != want {
+		t.Errorf("unexpected buckets %v", d.buckets)
+	}
+}
+
+// Test_compareProofErrorInvalidProof tests compare func for getProof method
+// It expects an error of no matching results since the proof cannot be verified against the root
+func Test_compareProofErrorInvalidProof(t *testing.T) {
+	rec := []byte(`{"address":"0x0000000000000000000000000000000000000001","accountProof":[],"balance":"0x0","codeHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0","storageHash":"0x0000000000000000000000000000000000000000000000000000000000000000","storageProof":[]}`)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getProof",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(rec, nil, 0)
+	err := compareProof(res, data, 0, common.Hash{1})
+	if err == nil {
+		t.Fatal("error must not be nil")
+	}
+
+	if err.typ != noMatchingResult {
+		t.Errorf("error must be type 'noMatchingResult'; err: %v", err)
+	}
+}
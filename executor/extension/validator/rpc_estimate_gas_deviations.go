@@ -0,0 +1,119 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// estimateGasDeviationBounds are upper bounds of relative deviation buckets, the last bucket is unbounded.
+var estimateGasDeviationBounds = []float64{0, 0.001, 0.01, 0.05, 0.1, 0.5}
+
+// estimateGasDeviations collects distribution of relative deviations
+// between replayed and recorded estimateGas results. It is safe for concurrent use.
+type estimateGasDeviations struct {
+	lock         sync.Mutex
+	buckets      []uint64
+	higher       uint64 // number of estimations higher than recorded
+	lower        uint64 // number of estimations lower than recorded
+	maxDeviation float64
+	total        uint64
+}
+
+func newEstimateGasDeviations() *estimateGasDeviations {
+	return &estimateGasDeviations{
+		buckets: make([]uint64, len(estimateGasDeviationBounds)+1),
+	}
+}
+
+// add records deviation of given result. Requests where either side is an error are ignored.
+func (d *estimateGasDeviations) add(result txcontext.Result, data *rpc.RequestAndResults) {
+	res, err := result.GetRawResult()
+	if err != nil || res == nil || data.Response == nil {
+		return
+	}
+
+	var recordedString string
+	if err = json.Unmarshal(data.Response.Result, &recordedString); err != nil {
+		return
+	}
+	recorded, err := hexutil.DecodeUint64(recordedString)
+	if err != nil {
+		return
+	}
+
+	d.addDeviation(relativeDeviation(littleendian.BytesToUint64(res), recorded))
+}
+
+func (d *estimateGasDeviations) addDeviation(deviation float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.total++
+	switch {
+	case deviation > 0:
+		d.higher++
+	case deviation < 0:
+		d.lower++
+	}
+
+	deviation = math.Abs(deviation)
+	d.maxDeviation = max(d.maxDeviation, deviation)
+
+	i := 0
+	for i < len(estimateGasDeviationBounds) && deviation > estimateGasDeviationBounds[i] {
+		i++
+	}
+	d.buckets[i]++
+}
+
+// print logs the distribution, nothing is printed if no estimateGas was compared.
+func (d *estimateGasDeviations) print(log logger.Logger) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.total == 0 {
+		return
+	}
+
+	log.Noticef("EstimateGas deviations of %v compared results (higher: %v, lower: %v, max: %.2f%%):",
+		d.total, d.higher, d.lower, d.maxDeviation*100)
+	log.Noticef("\texact: %v", d.buckets[0])
+	for i := 1; i < len(estimateGasDeviationBounds); i++ {
+		log.Noticef("\t<= %v%%: %v", estimateGasDeviationBounds[i]*100, d.buckets[i])
+	}
+	log.Noticef("\t> %v%%: %v", estimateGasDeviationBounds[len(estimateGasDeviationBounds)-1]*100, d.buckets[len(estimateGasDeviationBounds)])
+}
+
+// relativeDeviation returns deviation of given value from expected value relative to the expected value.
+func relativeDeviation(value, expected uint64) float64 {
+	if value == expected {
+		return 0
+	}
+	if expected == 0 {
+		return math.Inf(1)
+	}
+	return (float64(value) - float64(expected)) / float64(expected)
+}
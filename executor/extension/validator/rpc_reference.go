@@ -0,0 +1,163 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// errNotCached is returned in offline mode for requests missing in the reference cache.
+var errNotCached = errors.New("request is not cached")
+
+// rpcReference resends requests to the reference endpoint. Responses are cached in a file,
+// so the same requests can be replayed later without access to the endpoint.
+type rpcReference struct {
+	url       string
+	offline   bool
+	cachePath string
+	mu        sync.Mutex
+	cache     map[string]map[string]interface{}
+	out       *os.File
+}
+
+// rpcReferenceEntry is a single line of the reference cache file.
+type rpcReferenceEntry struct {
+	Method   string                 `json:"method"`
+	Params   []interface{}          `json:"params"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// newRpcReference creates reference from the configuration. If no endpoint is configured,
+// the public endpoint of the chain is used.
+func newRpcReference(cfg *utils.Config) *rpcReference {
+	url := cfg.RpcReferenceUrl
+	if url == "" && !cfg.RpcOffline {
+		// unsupported chains are reported once a request is resent
+		url, _ = utils.GetProvider(cfg.ChainID)
+	}
+
+	return &rpcReference{
+		url:       url,
+		offline:   cfg.RpcOffline,
+		cachePath: cfg.RpcReferenceCache,
+		cache:     make(map[string]map[string]interface{}),
+	}
+}
+
+// open loads the cache file and opens it for appending newly resent requests.
+func (r *rpcReference) open() error {
+	if r.cachePath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(r.cachePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("cannot open reference cache %v; %w", r.cachePath, err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var entry rpcReferenceEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("cannot decode reference cache %v; %w", r.cachePath, err)
+		}
+		key, err := referenceKey(entry.Method, entry.Params)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		r.cache[key] = entry.Response
+	}
+	if err = scanner.Err(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot read reference cache %v; %w", r.cachePath, err)
+	}
+
+	r.out = f
+	return nil
+}
+
+// send returns cached response of the request, if there is none, the request is sent to the endpoint.
+func (r *rpcReference) send(req utils.JsonRPCRequest) (map[string]interface{}, error) {
+	key, err := referenceKey(req.Method, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	m, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	if r.offline {
+		return nil, errNotCached
+	}
+	if r.url == "" {
+		return nil, errors.New("reference endpoint is not configured")
+	}
+
+	m, err = utils.SendRpcRequestTo(req, r.url)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[key] = m
+	if r.out == nil {
+		return m, nil
+	}
+
+	line, err := json.Marshal(rpcReferenceEntry{Method: req.Method, Params: req.Params, Response: m})
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode reference cache entry; %w", err)
+	}
+	if _, err = r.out.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("cannot write reference cache; %w", err)
+	}
+	return m, nil
+}
+
+// close releases the cache file.
+func (r *rpcReference) close() error {
+	if r.out == nil {
+		return nil
+	}
+	err := r.out.Close()
+	r.out = nil
+	return err
+}
+
+// referenceKey identifies request within the cache. Keys of params objects are sorted by json.Marshal.
+func referenceKey(method string, params []interface{}) (string, error) {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode params of %v; %w", method, err)
+	}
+	return method + string(p), nil
+}
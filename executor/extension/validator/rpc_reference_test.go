@@ -0,0 +1,86 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+func TestRpcReference_ResentResponsesAreReplayedOffline(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":0,"result":"0x10"}`)
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache.jsonl")
+	req := utils.JsonRPCRequest{
+		Method:  "eth_getBalance",
+		Params:  []interface{}{"0x1", "0x5"},
+		JSONRPC: "2.0",
+	}
+
+	online := newRpcReference(&utils.Config{RpcReferenceUrl: server.URL, RpcReferenceCache: cachePath})
+	if err := online.open(); err != nil {
+		t.Fatalf("cannot open reference; %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		m, err := online.send(req)
+		if err != nil {
+			t.Fatalf("cannot send request; %v", err)
+		}
+		if m["result"] != "0x10" {
+			t.Errorf("unexpected response %v", m)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("cached request must be sent only once, was sent %v times", calls)
+	}
+	if err := online.close(); err != nil {
+		t.Fatalf("cannot close reference; %v", err)
+	}
+
+	offline := newRpcReference(&utils.Config{RpcOffline: true, RpcReferenceCache: cachePath})
+	if err := offline.open(); err != nil {
+		t.Fatalf("cannot open reference; %v", err)
+	}
+	defer offline.close()
+
+	m, err := offline.send(req)
+	if err != nil {
+		t.Fatalf("cannot replay cached request; %v", err)
+	}
+	if m["result"] != "0x10" {
+		t.Errorf("unexpected response %v", m)
+	}
+
+	req.Params = []interface{}{"0x2", "0x5"}
+	if _, err = offline.send(req); !errors.Is(err, errNotCached) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("offline reference must not contact the endpoint")
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// falsyContract causes issues in validation, hence calls to it are excluded by default.
+const falsyContract = "0xe0c38b2a8d09aad53f1c67734b9a95e43d5981c0"
+
+// rpcRules declare which requests are excluded from the comparison and which are compared with a tolerance.
+//
+// Example of a rules file:
+//
+//	{
+//	  "exclusions": [
+//	    {"name": "broken token", "methods": ["call"], "contracts": ["0x..."], "selectors": ["0xa9059cbb"]},
+//	    {"name": "fork", "fromBlock": 100, "toBlock": 200}
+//	  ],
+//	  "tolerances": [
+//	    {"name": "estimations", "methods": ["estimateGas"], "gasTolerance": 0.05},
+//	    {"name": "aborts", "methods": ["call"], "errorMessages": {"-32000": ["execution aborted.*"]}}
+//	  ]
+//	}
+type rpcRules struct {
+	Exclusions []*rpcRule `json:"exclusions"`
+	Tolerances []*rpcRule `json:"tolerances"`
+}
+
+// rpcRule selects requests by method, contract, function selector and block range.
+// Empty criteria match every request.
+type rpcRule struct {
+	Name      string           `json:"name"`
+	Methods   []string         `json:"methods"`
+	Contracts []common.Address `json:"contracts"`
+	Selectors []hexutil.Bytes  `json:"selectors"`
+	FromBlock *int             `json:"fromBlock"`
+	ToBlock   *int             `json:"toBlock"`
+
+	// GasTolerance overrides maximum relative deviation of estimateGas results.
+	GasTolerance *float64 `json:"gasTolerance"`
+	// ErrorMessages are patterns of StateDB errors accepted for given recorded error code.
+	ErrorMessages map[int][]string `json:"errorMessages"`
+
+	errorPatterns map[int][]*regexp.Regexp
+	hits          atomic.Uint64
+}
+
+// newDefaultRpcRules returns rules applied even without a rules file.
+func newDefaultRpcRules() *rpcRules {
+	return &rpcRules{
+		Exclusions: []*rpcRule{{
+			Name:      "falsy contract",
+			Methods:   []string{"call"},
+			Contracts: []common.Address{common.HexToAddress(falsyContract)},
+		}},
+	}
+}
+
+// load appends rules declared in given file.
+func (rs *rpcRules) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read rules file %v; %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	loaded := new(rpcRules)
+	if err = dec.Decode(loaded); err != nil {
+		return fmt.Errorf("cannot decode rules file %v; %w", path, err)
+	}
+
+	for _, r := range append(loaded.Exclusions, loaded.Tolerances...) {
+		if err = r.compile(); err != nil {
+			return fmt.Errorf("invalid rule %q; %w", r.Name, err)
+		}
+	}
+
+	rs.Exclusions = append(rs.Exclusions, loaded.Exclusions...)
+	rs.Tolerances = append(rs.Tolerances, loaded.Tolerances...)
+	return nil
+}
+
+// compile validates the rule and prepares its error patterns.
+func (r *rpcRule) compile() error {
+	for _, s := range r.Selectors {
+		if len(s) != 4 {
+			return fmt.Errorf("selector %v must have 4 bytes", s)
+		}
+	}
+	if r.FromBlock != nil && r.ToBlock != nil && *r.FromBlock > *r.ToBlock {
+		return fmt.Errorf("block range %v-%v is empty", *r.FromBlock, *r.ToBlock)
+	}
+
+	r.errorPatterns = make(map[int][]*regexp.Regexp, len(r.ErrorMessages))
+	for code, messages := range r.ErrorMessages {
+		for _, m := range messages {
+			p, err := regexp.Compile(m)
+			if err != nil {
+				return fmt.Errorf("invalid error pattern %q; %w", m, err)
+			}
+			r.errorPatterns[code] = append(r.errorPatterns[code], p)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the request satisfies all criteria of the rule.
+func (r *rpcRule) matches(data *rpc.RequestAndResults, block int) bool {
+	if r.FromBlock != nil && block < *r.FromBlock {
+		return false
+	}
+	if r.ToBlock != nil && block > *r.ToBlock {
+		return false
+	}
+
+	if len(r.Methods) > 0 && !containsMethod(r.Methods, data.Query.MethodBase) {
+		return false
+	}
+
+	if len(r.Contracts) == 0 && len(r.Selectors) == 0 {
+		return true
+	}
+
+	// contracts and selectors are defined only for transaction arguments of call and estimateGas
+	var args map[string]interface{}
+	if len(data.Query.Params) > 0 {
+		args, _ = data.Query.Params[0].(map[string]interface{})
+	}
+
+	if len(r.Contracts) > 0 {
+		to, _ := args["to"].(string)
+		if to == "" || !containsAddress(r.Contracts, common.HexToAddress(to)) {
+			return false
+		}
+	}
+
+	if len(r.Selectors) > 0 {
+		input, _ := args["data"].(string)
+		if input == "" {
+			input, _ = args["input"].(string)
+		}
+		b, err := hexutil.Decode(input)
+		if err != nil || len(b) < 4 || !containsSelector(r.Selectors, b[:4]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// excluded returns true if the request matches any exclusion.
+func (rs *rpcRules) excluded(data *rpc.RequestAndResults, block int) bool {
+	for _, r := range rs.Exclusions {
+		if r.matches(data, block) {
+			r.hits.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// gasToleranceRule returns the first matching rule declaring a gas tolerance, nil if there is none.
+func (rs *rpcRules) gasToleranceRule(data *rpc.RequestAndResults, block int) *rpcRule {
+	for _, r := range rs.Tolerances {
+		if r.GasTolerance != nil && r.matches(data, block) {
+			return r
+		}
+	}
+	return nil
+}
+
+// errorRule returns the first matching rule accepting the StateDB error for the recorded error, nil if there is none.
+func (rs *rpcRules) errorRule(result txcontext.Result, data *rpc.RequestAndResults, block int) *rpcRule {
+	if data.Error == nil {
+		return nil
+	}
+	_, err := result.GetRawResult()
+	if err == nil {
+		return nil
+	}
+
+	for _, r := range rs.Tolerances {
+		patterns := r.errorPatterns[data.Error.Error.Code]
+		if len(patterns) == 0 || !r.matches(data, block) {
+			continue
+		}
+		for _, p := range patterns {
+			if p.MatchString(err.Error()) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// print logs number of requests affected by every rule. Tolerances count only requests
+// whose mismatch was turned into a match by the rule.
+func (rs *rpcRules) print(log logger.Logger) {
+	log.Noticef("Rule hits:")
+	for _, r := range rs.Exclusions {
+		log.Noticef("\texclusion %q: %v", r.Name, r.hits.Load())
+	}
+	for _, r := range rs.Tolerances {
+		log.Noticef("\ttolerance %q: %v", r.Name, r.hits.Load())
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSelector(selectors []hexutil.Bytes, selector []byte) bool {
+	for _, s := range selectors {
+		if bytes.Equal(s, selector) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+)
+
+const testRules = `{
+	"exclusions": [
+		{"name": "token", "methods": ["call"], "contracts": ["0x0000000000000000000000000000000000000001"], "selectors": ["0xa9059cbb"]},
+		{"name": "fork", "fromBlock": 100, "toBlock": 200}
+	],
+	"tolerances": [
+		{"name": "estimations", "methods": ["estimateGas"], "gasTolerance": 0.05},
+		{"name": "aborts", "errorMessages": {"-32000": ["^execution aborted"]}}
+	]
+}`
+
+func loadTestRules(t *testing.T) *rpcRules {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(testRules), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rules := newDefaultRpcRules()
+	if err := rules.load(path); err != nil {
+		t.Fatalf("cannot load rules; %v", err)
+	}
+	return rules
+}
+
+func newRuleTestRequest(method string, args map[string]interface{}) *rpc.RequestAndResults {
+	return &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			MethodBase: method,
+			Params:     []interface{}{args, "latest"},
+		},
+	}
+}
+
+func TestRpcRules_ExclusionsMatchContractSelectorAndBlockRange(t *testing.T) {
+	rules := loadTestRules(t)
+
+	tests := []struct {
+		name  string
+		data  *rpc.RequestAndResults
+		block int
+		want  bool
+	}{
+		{"matching call", newRuleTestRequest("call", map[string]interface{}{"to": "0x1", "data": "0xa9059cbb00"}), 1, true},
+		{"matching input", newRuleTestRequest("call", map[string]interface{}{"to": "0x1", "input": "0xa9059cbb"}), 1, true},
+		{"other selector", newRuleTestRequest("call", map[string]interface{}{"to": "0x1", "data": "0x12345678"}), 1, false},
+		{"other contract", newRuleTestRequest("call", map[string]interface{}{"to": "0x2", "data": "0xa9059cbb"}), 1, false},
+		{"other method", newRuleTestRequest("estimateGas", map[string]interface{}{"to": "0x1", "data": "0xa9059cbb"}), 1, false},
+		{"block range", newRuleTestRequest("getBalance", nil), 150, true},
+		{"falsy contract", newRuleTestRequest("call", map[string]interface{}{"to": falsyContract}), 1, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rules.excluded(test.data, test.block); got != test.want {
+				t.Errorf("unexpected exclusion, got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if got := rules.Exclusions[1].hits.Load(); got != 2 {
+		t.Errorf("unexpected number of hits, got %v, want 2", got)
+	}
+}
+
+func TestRpcRules_GasToleranceRuleMatchesMethod(t *testing.T) {
+	rules := loadTestRules(t)
+
+	r := rules.gasToleranceRule(newRuleTestRequest("estimateGas", nil), 1)
+	if r == nil || *r.GasTolerance != 0.05 {
+		t.Errorf("unexpected tolerance rule %v", r)
+	}
+	if r = rules.gasToleranceRule(newRuleTestRequest("call", nil), 1); r != nil {
+		t.Errorf("unexpected tolerance rule %q", r.Name)
+	}
+}
+
+func TestRpcRules_ErrorRuleAcceptsNormalisedErrors(t *testing.T) {
+	rules := loadTestRules(t)
+	data := newRuleTestRequest("call", nil)
+	data.Error = &rpc.ErrorResponse{Error: rpc.ErrorMessage{Code: -32000}}
+
+	if rules.errorRule(rpc.NewResult(nil, errors.New("execution aborted: timeout"), 0), data, 1) == nil {
+		t.Error("error must be accepted")
+	}
+	if rules.errorRule(rpc.NewResult(nil, errors.New("out of gas"), 0), data, 1) != nil {
+		t.Error("error must not be accepted")
+	}
+	data.Error.Error.Code = 3
+	if rules.errorRule(rpc.NewResult(nil, errors.New("execution aborted: timeout"), 0), data, 1) != nil {
+		t.Error("error of other code must not be accepted")
+	}
+}
+
+func TestRpcRules_ToleranceHitsAreCountedOnlyIfRuleChangesOutcome(t *testing.T) {
+	cfg := &utils.Config{RpcEstimateGasTolerance: 0.01}
+	c := makeRPCComparator(cfg, logger.NewLogger("critical", "rpc-test"))
+	c.rules = loadTestRules(t)
+
+	rec, _ := json.Marshal("0x64")
+	for _, gas := range []uint64{100, 103, 103} {
+		data := newRuleTestRequest("estimateGas", nil)
+		data.Response = &rpc.Response{Result: rec}
+		data.IsRecovered = true
+
+		ctx := &executor.Context{ExecutionResult: rpc.NewResult(littleendian.Uint64ToBytes(gas), nil, 10)}
+		if err := c.PostTransaction(executor.State[*rpc.RequestAndResults]{Data: data}, ctx); err != nil {
+			t.Fatalf("unexpected error; %v", err)
+		}
+	}
+
+	// exact result matches even with the default tolerance
+	if got := c.rules.Tolerances[0].hits.Load(); got != 2 {
+		t.Errorf("unexpected number of hits, got %v, want 2", got)
+	}
+}
+
+func TestRpcRules_LoadRejectsInvalidRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"exclusions": [{"selectors": ["0x01"]}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := newDefaultRpcRules().load(path); err == nil {
+		t.Error("selector of invalid length must be rejected")
+	}
+}
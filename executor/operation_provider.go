@@ -39,6 +39,7 @@ func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.O
 	var (
 		transactionNumber int
 		lastOperation     bool
+		timestamps        []operation.Operation // timestamps recorded after operation.EndTransaction
 	)
 	for iter.Next() {
 		op := iter.Value()
@@ -47,9 +48,18 @@ func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.O
 		if lastOperation {
 			var ok bool
 
-			// append operation.EndBlock as well
+			// timestamps precede the operation they belong to, hence they are
+			// held back until it is known whether the operation is operation.EndBlock
+			if _, ok = op.(*operation.Timestamp); ok {
+				timestamps = append(timestamps, op)
+				continue
+			}
+
+			// append operation.EndBlock together with its timestamps as well
 			if _, ok = op.(*operation.EndBlock); ok {
+				tx = append(tx, timestamps...)
 				tx = append(tx, op)
+				timestamps = nil
 			}
 
 			if err := consumer(TransactionInfo[[]operation.Operation]{currentBlockNumber, transactionNumber, tx}); err != nil {
@@ -62,7 +72,9 @@ func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.O
 				return nil
 			}
 
-			tx = make([]operation.Operation, 0)
+			// timestamps not belonging to operation.EndBlock are carried to the next transaction
+			tx = append(make([]operation.Operation, 0), timestamps...)
+			timestamps = nil
 			lastOperation = false
 
 			// operation has been already appended, skip the rest of the loop
@@ -90,6 +102,11 @@ func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.O
 		tx = append(tx, op)
 	}
 
+	// the trace ended right after operation.EndTransaction
+	if lastOperation {
+		return consumer(TransactionInfo[[]operation.Operation]{currentBlockNumber, transactionNumber, tx})
+	}
+
 	return nil
 }
 
@@ -151,3 +151,66 @@ func TestOperationProvider_BeginTransactionSetsTransactionNumber(t *testing.T) {
 		t.Fatalf("failed to iterate through states: %v", err)
 	}
 }
+
+func TestOperationProvider_EndBlockIsUnitedWithLastTransactionOfTimedTrace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	consumer := NewMockOperationConsumer(ctrl)
+
+	cfg := &utils.Config{}
+	cfg.First = 1
+	cfg.Last = 2
+
+	cfg.TraceFile = t.TempDir() + "file"
+	rCtx, err := context.NewRecord(cfg.TraceFile, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a timestamp is recorded before every operation
+	rCtx.Timing = true
+
+	for block := uint64(1); block <= 3; block++ {
+		operation.WriteOp(rCtx, operation.NewBeginBlock(block))
+		operation.WriteOp(rCtx, operation.NewBeginTransaction(0))
+		operation.WriteOp(rCtx, operation.NewEndTransaction())
+		operation.WriteOp(rCtx, operation.NewBeginTransaction(1))
+		operation.WriteOp(rCtx, operation.NewEndTransaction())
+		operation.WriteOp(rCtx, operation.NewEndBlock())
+	}
+	rCtx.Close()
+
+	provider, err := OpenOperations(cfg)
+	if err != nil {
+		t.Fatalf("failed to open trace file: %v", err)
+	}
+	defer provider.Close()
+
+	// expectLastOp checks the last operation and that each transaction begins with a timestamp
+	expectLastOp := func(lastId byte) func(int, int, []operation.Operation) error {
+		return func(block int, tx int, ops []operation.Operation) error {
+			if got := ops[0].GetId(); got != operation.TimestampID {
+				t.Errorf("unexpected first operation of block %v, tx %v; got: %v, want: %v", block, tx, operation.GetLabel(got), operation.GetLabel(operation.TimestampID))
+			}
+			if got := ops[len(ops)-1].GetId(); got != lastId {
+				t.Errorf("unexpected last operation of block %v, tx %v; got: %v, want: %v", block, tx, operation.GetLabel(got), operation.GetLabel(lastId))
+			}
+			return nil
+		}
+	}
+
+	gomock.InOrder(
+		consumer.EXPECT().Consume(1, 0, gomock.Any()).DoAndReturn(expectLastOp(operation.EndTransactionID)),
+		consumer.EXPECT().Consume(1, 1, gomock.Any()).DoAndReturn(expectLastOp(operation.EndBlockID)),
+		consumer.EXPECT().Consume(2, 0, gomock.Any()).DoAndReturn(expectLastOp(operation.EndTransactionID)),
+		consumer.EXPECT().Consume(2, 1, gomock.Any()).DoAndReturn(expectLastOp(operation.EndBlockID)),
+	)
+
+	if err := provider.Run(1, 2, toOperationConsumer(consumer)); err != nil {
+		t.Fatalf("failed to iterate through states: %v", err)
+	}
+
+	// a single transaction is requested by replay_substate
+	consumer.EXPECT().Consume(1, 0, gomock.Any()).DoAndReturn(expectLastOp(operation.EndTransactionID))
+	if err := provider.Run(1, 1, toOperationConsumer(consumer)); err != nil {
+		t.Fatalf("failed to iterate through states: %v", err)
+	}
+}
@@ -98,11 +98,6 @@ func (r *rpcRequestProvider) Run(from int, to int, consumer Consumer[*rpc.Reques
 			return errors.New("iterator returned nil request")
 		}
 
-		// get logs is not yet implemented, skip these for now
-		if req.Query.MethodBase == "getLogs" {
-			continue
-		}
-
 		req.DecodeInfo()
 		// are we skipping requests?
 		if req.RecordedBlock < from {
@@ -157,11 +152,6 @@ func (r *rpcRequestProvider) processFirst(from int, consumer Consumer[*rpc.Reque
 			return nil
 		}
 
-		// get logs is not yet implemented, skip these for now
-		if req.Query.MethodBase == "getLogs" {
-			return nil
-		}
-
 		if err := consumer(TransactionInfo[*rpc.RequestAndResults]{req.RecordedBlock, 0, req}); err != nil {
 			return err
 		}
@@ -136,7 +136,7 @@ func TestRPCRequestProvider_ErrorReturnedByIteratorEndsTheApp(t *testing.T) {
 	}
 }
 
-func TestRPCRequestProvider_GetLogMethodDoesNotEndIteration(t *testing.T) {
+func TestRPCRequestProvider_GetLogMethodIsPassedToConsumer(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	consumer := NewMockRPCReqConsumer(ctrl)
 	i := rpc.NewMockIterator(ctrl)
@@ -151,9 +151,11 @@ func TestRPCRequestProvider_GetLogMethodDoesNotEndIteration(t *testing.T) {
 		i.EXPECT().Next().Return(true),
 		i.EXPECT().Error().Return(nil),
 		i.EXPECT().Value().Return(logResp),
+		consumer.EXPECT().Consume(10, gomock.Any(), logResp),
 		i.EXPECT().Next().Return(true),
 		i.EXPECT().Error().Return(nil),
 		i.EXPECT().Value().Return(logResp),
+		consumer.EXPECT().Consume(10, gomock.Any(), logResp),
 		i.EXPECT().Next().Return(false),
 		i.EXPECT().Close(),
 	)
@@ -19,6 +19,7 @@ package profile
 import (
 	"fmt"
 	"math"
+	"math/bits"
 	"os"
 	"sort"
 	"strings"
@@ -34,9 +35,59 @@ type Stats struct {
 	opVariance    map[byte]float64       // duration variance
 	opLabel       map[byte]string        // operation names
 	opOrder       []byte                 // order of map keys
+	opHistogram   map[byte]*histogram    // duration histogram for percentiles
 	csv           string                 // csv file containing profiling data
 	writeToFile   bool                   // if true print profiling results to a file
 	hasHeader     bool                   // if write to a file, header prints once
+	percentiles   bool                   // if true print percentiles of durations
+}
+
+// Durations are counted in logarithmic buckets for computing percentiles.
+// Each power of two is split into histogramSubBuckets buckets such that
+// the relative error of a percentile is bounded by 1/histogramSubBuckets.
+const (
+	histogramSubBucketBits = 4
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	histogramBuckets       = 64*histogramSubBuckets + 1
+)
+
+// printedPercentiles are the percentiles printed if percentiles are enabled.
+var printedPercentiles = []float64{0.5, 0.9, 0.99, 0.999}
+
+// histogram counts durations in logarithmic buckets.
+type histogram [histogramBuckets]uint64
+
+// histogramBucket computes the bucket of a duration. Bucket zero contains
+// non-positive durations.
+func histogramBucket(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	ns := uint64(d)
+	exp := bits.Len64(ns) - 1
+	// the sub-bucket is given by the bits following the most significant bit
+	var sub uint64
+	if exp >= histogramSubBucketBits {
+		sub = (ns >> (exp - histogramSubBucketBits)) & (histogramSubBuckets - 1)
+	} else {
+		sub = (ns << (histogramSubBucketBits - exp)) & (histogramSubBuckets - 1)
+	}
+	return exp*histogramSubBuckets + int(sub) + 1
+}
+
+// histogramUpperBound computes the largest duration of a bucket.
+func histogramUpperBound(bucket int) time.Duration {
+	if bucket <= 0 {
+		return 0
+	}
+	exp := (bucket - 1) / histogramSubBuckets
+	sub := uint64((bucket - 1) % histogramSubBuckets)
+	// lower bound of the next bucket minus one
+	next := float64(uint64(histogramSubBuckets)+sub+1) * math.Ldexp(1, exp-histogramSubBucketBits)
+	if next >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(math.Ceil(next)) - 1
 }
 
 func NewStats(filename string) *Stats {
@@ -57,6 +108,35 @@ func (ps *Stats) Reset() {
 	ps.opMinDuration = make(map[byte]time.Duration)
 	ps.opMaxDuration = make(map[byte]time.Duration)
 	ps.opVariance = make(map[byte]float64)
+	ps.opHistogram = make(map[byte]*histogram)
+}
+
+// EnablePercentiles enables printing percentiles of the operation durations.
+func (ps *Stats) EnablePercentiles() {
+	ps.percentiles = true
+}
+
+// Percentile returns an upper bound of the p-th percentile (0 < p <= 1) of the
+// durations of an operation. The bound is at most 1/16 above the exact value
+// and never exceeds the maximum observed duration.
+func (ps *Stats) Percentile(id byte, p float64) time.Duration {
+	n := ps.opFrequency[id]
+	h, found := ps.opHistogram[id]
+	if n == 0 || !found {
+		return 0
+	}
+	rank := uint64(math.Ceil(p * float64(n)))
+	if rank < 1 {
+		rank = 1
+	}
+	count := uint64(0)
+	for bucket, freq := range h {
+		count += freq
+		if count >= rank {
+			return min(histogramUpperBound(bucket), ps.opMaxDuration[id])
+		}
+	}
+	return ps.opMaxDuration[id]
 }
 
 // Profiling records runtime and calculates statistics after
@@ -91,6 +171,14 @@ func (ps *Stats) Profile(id byte, elapsed time.Duration) {
 		ps.opVariance[id] = 0.0
 	}
 
+	// update duration histogram
+	h, found := ps.opHistogram[id]
+	if !found {
+		h = new(histogram)
+		ps.opHistogram[id] = h
+	}
+	h[histogramBucket(elapsed)]++
+
 	// update execution frequency
 	ps.opFrequency[id] = n + 1
 
@@ -114,7 +202,13 @@ func (ps *Stats) PrintProfiling(first uint64, last uint64) error {
 	)
 	timeUnit := float64(time.Microsecond)
 	if !ps.hasHeader {
-		builder.WriteString("id, first, last, n, mean(us), std(us), min(us), max(us)\n")
+		builder.WriteString("id, first, last, n, mean(us), std(us), min(us), max(us)")
+		if ps.percentiles {
+			for _, p := range printedPercentiles {
+				fmt.Fprintf(&builder, ", p%v(us)", p*100)
+			}
+		}
+		builder.WriteString("\n")
 		if ps.writeToFile {
 			ps.hasHeader = true
 		}
@@ -127,7 +221,13 @@ func (ps *Stats) PrintProfiling(first uint64, last uint64) error {
 			std := math.Sqrt(ps.opVariance[id]) / timeUnit
 			min := float64(ps.opMinDuration[id]) / timeUnit
 			max := float64(ps.opMaxDuration[id]) / timeUnit
-			fmt.Fprintf(&builder, "%v,%v,%v,%v,%v,%v,%v,%v\n", label, first, last, n, mean, std, min, max)
+			fmt.Fprintf(&builder, "%v,%v,%v,%v,%v,%v,%v,%v", label, first, last, n, mean, std, min, max)
+			if ps.percentiles {
+				for _, p := range printedPercentiles {
+					fmt.Fprintf(&builder, ",%v", float64(ps.Percentile(id, p))/timeUnit)
+				}
+			}
+			builder.WriteString("\n")
 
 			total += float64(ps.opDuration[id])
 		}
@@ -0,0 +1,57 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+package profile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats_PercentilesAreWithinBucketResolution(t *testing.T) {
+	ps := NewStats("")
+	for i := 1; i <= 1000; i++ {
+		ps.Profile(0, time.Duration(i)*time.Microsecond)
+	}
+
+	tests := map[float64]time.Duration{
+		0.5:  500 * time.Microsecond,
+		0.9:  900 * time.Microsecond,
+		0.99: 990 * time.Microsecond,
+		1:    1000 * time.Microsecond,
+	}
+	for p, want := range tests {
+		got := ps.Percentile(0, p)
+		if got < want || float64(got) > float64(want)*(1+1.0/histogramSubBuckets) {
+			t.Errorf("unexpected p%v, got %v, want %v", p*100, got, want)
+		}
+	}
+}
+
+func TestStats_PercentileOfUnknownOperationIsZero(t *testing.T) {
+	ps := NewStats("")
+	if got := ps.Percentile(1, 0.5); got != 0 {
+		t.Errorf("unexpected percentile, got %v", got)
+	}
+}
+
+func TestStats_HistogramBucketsContainTheirUpperBound(t *testing.T) {
+	for _, d := range []time.Duration{1, 3, 17, 1000, time.Second, time.Hour} {
+		bucket := histogramBucket(d)
+		if bound := histogramUpperBound(bucket); bound < d || histogramBucket(bound) != bucket {
+			t.Errorf("invalid upper bound %v of bucket %v for duration %v", bound, bucket, d)
+		}
+	}
+}
@@ -157,12 +157,29 @@ func (e *EvmExecutor) newEVM(msg *core.Message, hashErr *error) *vm.EVM {
 
 // sendCall executes the call method in the EvmExecutor with given archive
 func (e *EvmExecutor) sendCall() (*core.ExecutionResult, error) {
+	executionResult, err := e.applyMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	if executionResult.Err != nil {
+		return nil, fmt.Errorf("execution returned err; %w", executionResult.Err)
+	}
+
+	return executionResult, nil
+}
+
+// applyMessage executes message created from the request arguments in the EVM.
+// Error is returned only if the message could not be applied at all, failed
+// executions are reported within the execution result.
+func (e *EvmExecutor) applyMessage() (*core.ExecutionResult, error) {
 	var (
 		gp              *core.GasPool
 		executionResult *core.ExecutionResult
 		err             error
 		msg             *core.Message
 		evm             *vm.EVM
+		hashErr         error
 	)
 
 	gp = new(core.GasPool).AddGas(math.MaxUint64) // based in opera
@@ -171,27 +188,23 @@ func (e *EvmExecutor) sendCall() (*core.ExecutionResult, error) {
 		return nil, err
 	}
 
-	var hashErr *error
-	evm = e.newEVM(msg, hashErr)
+	evm = e.newEVM(msg, &hashErr)
 
 	executionResult, err = core.ApplyMessage(evm, msg, gp)
-	if executionResult.Err != nil {
-		return nil, fmt.Errorf("execution returned err; %w", executionResult.Err)
+	if err != nil {
+		return nil, fmt.Errorf("err: %v (supplied gas %v)", err, uint64(*e.args.Gas))
 	}
 
 	if hashErr != nil {
-		return nil, fmt.Errorf("cannot get state hash; %w", *hashErr)
+		return nil, fmt.Errorf("cannot get state hash; %w", hashErr)
 	}
 
 	// If the timer caused an abort, return an appropriate error message
 	if evm.Cancelled() {
 		return nil, fmt.Errorf("execution aborted: timeout")
 	}
-	if err != nil {
-		return executionResult, fmt.Errorf("err: %v (supplied gas %v)", err, e.args.Gas)
-	}
-	return executionResult, nil
 
+	return executionResult, nil
 }
 
 // sendEstimateGas executes estimateGas method in the EvmExecutor
@@ -239,12 +252,15 @@ func (e *EvmExecutor) sendEstimateGas() (hexutil.Uint64, error) {
 	return hexutil.Uint64(hi), nil
 }
 
-// executable tries to execute call with given gas into EVM. This func is used for estimateGas calculation
+// executable tries to execute call with given gas into EVM. This func is used for estimateGas calculation.
+// Every attempt is reverted, so the archive stays untouched for the following attempts.
 func (e *EvmExecutor) executable(gas uint64) (bool, *core.ExecutionResult, error) {
 	e.args.Gas = (*hexutil.Uint64)(&gas)
 
-	result, err := e.sendCall()
+	snapshot := e.archive.Snapshot()
+	defer e.archive.RevertToSnapshot(snapshot)
 
+	result, err := e.applyMessage()
 	if err != nil {
 		if strings.Contains(err.Error(), "intrinsic gas too low") {
 			return true, nil, nil // Special case, raise gas limit
@@ -18,7 +18,9 @@ package rpc
 
 import (
 	"encoding/binary"
-	"strings"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"unsafe"
 
 	"github.com/Fantom-foundation/Aida/state"
@@ -26,12 +28,12 @@ import (
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
-// TODO FIX!
-const falsyContract = "0xe0c38b2a8d09aad53f1c67734b9a95e43d5981c0"
-
-func Execute(block uint64, rec *RequestAndResults, archive state.NonCommittableStateDB, cfg *utils.Config) (txcontext.Result, error) {
+// Execute given request against the archive. Logs for getLogs requests are read from given LogReader,
+// if it is nil, getLogs requests are not executed.
+func Execute(block uint64, rec *RequestAndResults, archive state.NonCommittableStateDB, logs LogReader, cfg *utils.Config) (txcontext.Result, error) {
 	switch rec.Query.MethodBase {
 	case "getBalance":
 		return executeGetBalance(rec.Query.Params[0], archive), nil
@@ -45,20 +47,48 @@ func Execute(block uint64, rec *RequestAndResults, archive state.NonCommittableS
 		if err != nil {
 			return nil, err
 		}
-		// calls to this contract are excluded for now,
-		// this contract causes issues in validation
-		if strings.Compare(falsyContract, strings.ToLower(evm.args.To.String())) == 0 {
-			rec.SkipValidation = true
-		}
 		return executeCall(evm), nil
 
 	case "estimateGas":
-		// estimateGas is currently not suitable for rpc replay since the estimation  in geth is always calculated for current state
-		// that means recorded result and result returned by StateDB are not comparable
+		// the estimation in geth is always calculated for current state, hence the recorded result is only
+		// comparable if the estimation is replayed against the archive with context of the recorded block
+		if !cfg.RpcEstimateGas || rec.Timestamp == 0 {
+			return nil, nil
+		}
+		evm, err := newEvmExecutor(block, archive, cfg, rec.Query.Params[0].(map[string]interface{}), rec.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		return executeEstimateGas(evm), nil
 	case "getCode":
 		return executeGetCode(rec.Query.Params[0], archive), nil
 	case "getStorageAt":
 		return executeGetStorageAt(rec.Query.Params, archive), nil
+	case "getProof":
+		res, err := executeGetProof(rec.Query.Params, archive)
+		if errors.Is(err, state.ErrProofsNotSupported) {
+			rec.SkipValidation = true
+			return nil, nil
+		}
+		if err != nil {
+			return &result{err: err}, nil
+		}
+		return res, nil
+	case "getLogs":
+		if logs == nil || len(rec.Query.Params) == 0 {
+			return nil, nil
+		}
+		filter, err := parseLogFilter(rec.Query.Params[0], block)
+		if err != nil || filter.to < filter.from || filter.to-filter.from > maxLogBlockRange {
+			// filters which cannot be answered from substates are not validated
+			rec.SkipValidation = true
+			return nil, nil
+		}
+		res, err := executeGetLogs(filter, logs)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
 	default:
 		break
 	}
@@ -118,9 +148,13 @@ func executeCall(evm *EvmExecutor) *result {
 // executeEstimateGas into EvmExecutor which calculates gas needed for a transaction
 func executeEstimateGas(evm *EvmExecutor) *result {
 	gas, err := evm.sendEstimateGas()
+	if err != nil {
+		return &result{
+			err: err,
+		}
+	}
 	return &result{
 		result: littleendian.Uint64ToBytes(uint64(gas)),
-		err:    err,
 	}
 }
 
@@ -141,3 +175,28 @@ func executeGetStorageAt(params []interface{}, archive state.VmStateDB) *result
 		result: archive.GetState(address, hash).Bytes(),
 	}
 }
+
+// executeGetLogs collects logs matching the filter from every block within its range
+func executeGetLogs(filter *logFilter, reader LogReader) (*result, error) {
+	logs := make([]*types.Log, 0)
+	for block := filter.from; block <= filter.to; block++ {
+		blockLogs, err := reader.GetBlockLogs(block)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range blockLogs {
+			if filter.matches(l) {
+				logs = append(logs, l)
+			}
+		}
+	}
+
+	res, err := json.Marshal(logs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal logs; %w", err)
+	}
+
+	return &result{
+		result: res,
+	}, nil
+}
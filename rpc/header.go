@@ -57,6 +57,41 @@ import (
 // |                 CRC8 Checksum                 |
 // +-----+-----+-----+-----+-----+-----+-----+-----+
 
+// Record Header Structure v2 (min 20 bytes, max 23 bytes per data):
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// | ERR | HiQ | HiR |  Version  |    Reserved     |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |                   Namespace                   |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |                  Call Method                  |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |        Query Size Hi (skip if HiQ = 0)        |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |                 Query Size Lo                 |
+// |                  (16 bits)                    |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |               Response Size Hi                |
+// |     (16 bits; skip if HiR = 0 OR ERR = 1)     |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |   Response Size Lo OR Error Code if ERR = 1   |
+// |                  (16 bits)                    |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |            Response Block Number              |
+// |                  (32 bits)                    |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |           Response Block Timestamp            |
+// |                  (64 bits)                    |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+// |                 CRC8 Checksum                 |
+// +-----+-----+-----+-----+-----+-----+-----+-----+
+
+const (
+	// HeaderV1 is the original header version with namespace and method packed into 7 bits.
+	HeaderV1 byte = 1
+	// HeaderV2 is the header version with namespace and method stored in full bytes.
+	HeaderV2 byte = 2
+)
+
 // maxQuerySizeAllowed represents the maximal size of a recordable query; 4 bits (Hi) + 8 bits (HiQ = 1) + 8 bits (Lo)
 const maxQuerySizeAllowed = 0xFFFFF
 
@@ -66,7 +101,14 @@ const maxShortQuery = 0xFFF
 // maxShortResponse represents the longest response payload still considered as short (16 bits uint).
 const maxShortResponse = 0xFFFF
 
-const headerSize = 21
+// maxQuerySizeAllowedV2 represents the maximal size of a recordable query in v2 header; 8 bits (HiQ = 1) + 16 bits (Lo)
+const maxQuerySizeAllowedV2 = 0xFFFFFF
+
+// maxShortQueryV2 represents the longest request query still considered as short in v2 header (16 bits Lo).
+const maxShortQueryV2 = 0xFFFF
+
+// headerSize is the maximal size of a header of any version
+const headerSize = 23
 
 // Header represents a single data header on a virtual recording tape represented by a Reader/Writer.
 type Header struct {
@@ -92,8 +134,8 @@ var namespaceDictionary = map[string]byte{
 
 // methodDictionary represents a dictionary of methods by namespace for encoding.
 // Unlisted methods are not recorded.
-// Please note the header encodes the method into 4 bits,
-// e.g., the maximal method ID is limited to 15.
+// Please note the v1 header encodes the method into 4 bits,
+// e.g., the maximal method ID is limited to 15. New methods are added to the v2 methodRegistry.
 var methodDictionary = map[byte]map[string]byte{
 	1 << 0: {
 		/* eth+ftm namespaces */
@@ -108,31 +150,108 @@ var methodDictionary = map[byte]map[string]byte{
 	},
 }
 
+// namespaceRegistry represents a registry of call namespaces for encoding v2 headers.
+// IDs are part of the recording format, registered IDs must never be changed or reused.
+// Unlisted namespaces are not recorded.
+var namespaceRegistry = map[string]byte{
+	"eth":   1,
+	"ftm":   2,
+	"debug": 3,
+	"sonic": 4,
+}
+
+// ethMethodRegistry represents methods of the eth namespace and its copies.
+var ethMethodRegistry = map[string]byte{
+	"call":                  1,
+	"estimateGas":           2,
+	"getBalance":            3,
+	"getCode":               4,
+	"getStorageAt":          5,
+	"getTransactionCount":   6,
+	"getLogs":               7,
+	"getProof":              8,
+	"createAccessList":      9,
+	"getBlockByNumber":      10,
+	"getBlockByHash":        11,
+	"getTransactionByHash":  12,
+	"getTransactionReceipt": 13,
+	"blockNumber":           14,
+	"chainId":               15,
+	"gasPrice":              16,
+	"currentEpoch":          17,
+	"getRules":              18,
+}
+
+// methodRegistry represents a registry of methods by namespace for encoding v2 headers.
+// IDs are part of the recording format, registered IDs must never be changed or reused.
+// Unlisted methods are not recorded.
+var methodRegistry = map[byte]map[string]byte{
+	1: ethMethodRegistry, // eth
+	2: ethMethodRegistry, // ftm is a copy of the eth namespace
+	3: { // debug
+		"traceCall":          1,
+		"traceTransaction":   2,
+		"traceBlockByNumber": 3,
+	},
+	4: ethMethodRegistry, // sonic is a copy of the eth namespace
+}
+
 // checksumTable is the table used to calculate the header checksum.
 var checksumTable = crc8.MakeTable(crc8.CRC8_CDMA2000)
 
 // CanRecord compares namespace and function name against Header functions table
-// to verify if the function can be encoded to a data header.
+// to verify if the function can be encoded to a v1 data header.
 func CanRecord(namespace, method string) bool {
-	ns, ok := namespaceDictionary[namespace]
+	return CanRecordVersion(HeaderV1, namespace, method)
+}
+
+// CanRecordVersion compares namespace and function name against functions table of given header version
+// to verify if the function can be encoded to a data header.
+func CanRecordVersion(version byte, namespace, method string) bool {
+	namespaces, methods := dictionaries(version)
+	ns, ok := namespaces[namespace]
 	if !ok {
 		return false
 	}
 
-	_, ok = methodDictionary[ns][method]
+	_, ok = methods[ns][method]
 	return ok
 }
 
+// dictionaries returns namespace and method dictionaries used by given header version.
+func dictionaries(version byte) (map[string]byte, map[byte]map[string]byte) {
+	if version == HeaderV2 {
+		return namespaceRegistry, methodRegistry
+	}
+	return namespaceDictionary, methodDictionary
+}
+
+// SetVersion configures the version of the header. Version must be set before the method,
+// since the method encoding depends on it. Headers without version are written as v1.
+func (h *Header) SetVersion(version byte) error {
+	if version != HeaderV1 && version != HeaderV2 {
+		return fmt.Errorf("unsupported header version %d", version)
+	}
+	h.version = version
+	return nil
+}
+
+// Version returns the version of the header.
+func (h *Header) Version() byte {
+	return h.version
+}
+
 // SetMethod sets a call namespace and method into the header.
 func (h *Header) SetMethod(namespace string, method string) error {
 	var ok bool
 
-	h.namespace, ok = namespaceDictionary[namespace]
+	namespaces, methods := dictionaries(h.version)
+	h.namespace, ok = namespaces[namespace]
 	if !ok {
 		return fmt.Errorf("namespace '%s' not recorded", namespace)
 	}
 
-	h.method, ok = methodDictionary[h.namespace][method]
+	h.method, ok = methods[h.namespace][method]
 	if !ok {
 		return fmt.Errorf("method '%s' of namespace '%s' not recorded", method, namespace)
 	}
@@ -146,11 +265,17 @@ func (h *Header) Namespace() (string, error) {
 		return "", fmt.Errorf("namespace not initialized")
 	}
 
-	for n, i := range namespaceDictionary {
-		if h.namespace == i {
-			return n, nil
+	// v1 namespaces share their IDs, the first one in alphabetical order is returned to stay deterministic
+	namespaces, _ := dictionaries(h.version)
+	var res string
+	for n, i := range namespaces {
+		if h.namespace == i && (res == "" || n < res) {
+			res = n
 		}
 	}
+	if res != "" {
+		return res, nil
+	}
 
 	return "", fmt.Errorf("unknown namespace set")
 }
@@ -161,7 +286,8 @@ func (h *Header) Method() (string, error) {
 		return "", fmt.Errorf("namespace or method not initialized")
 	}
 
-	for n, i := range methodDictionary[h.namespace] {
+	_, methods := dictionaries(h.version)
+	for n, i := range methods[h.namespace] {
 		if h.method == i {
 			return n, nil
 		}
@@ -192,13 +318,18 @@ func (h *Header) BlockTimestamp() uint64 {
 
 // SetQueryLength configures the query length.
 func (h *Header) SetQueryLength(ql int) error {
+	maxAllowed, maxShort := maxQuerySizeAllowed, maxShortQuery // short query is 8 bits (Lo) + 4 bits (Hi)
+	if h.version == HeaderV2 {
+		maxAllowed, maxShort = maxQuerySizeAllowedV2, maxShortQueryV2 // short query is 16 bits (Lo)
+	}
+
 	// we have to skip queries too big to be stored
-	if ql > maxQuerySizeAllowed {
-		return fmt.Errorf("query too big; expected max %d bytes, received %d", maxQuerySizeAllowed, ql)
+	if ql > maxAllowed {
+		return fmt.Errorf("query too big; expected max %d bytes, received %d", maxAllowed, ql)
 	}
 
 	h.querySize = int32(ql)
-	h.isLongQuery = h.querySize > maxShortQuery // short query is 8 bits (Lo) + 4 bits (Hi)
+	h.isLongQuery = h.querySize > int32(maxShort)
 	return nil
 }
 
@@ -248,7 +379,12 @@ func (h *Header) ResponseLength() int32 {
 func (h *Header) WriteTo(out io.Writer) (int64, error) {
 	hdr := make([]byte, headerSize)
 
-	offset := h.codeQuery(hdr)
+	var offset int
+	if h.version == HeaderV2 {
+		offset = h.codeQueryV2(hdr)
+	} else {
+		offset = h.codeQuery(hdr)
+	}
 
 	if h.isError {
 		offset += h.codeError(hdr, offset)
@@ -288,6 +424,24 @@ func (h *Header) codeQuery(hdr []byte) int {
 	return 4 // long query, the Size Hi byte is present
 }
 
+// codeQueryV2 encodes query part of the v2 header into the given buffer returning the number of bytes used.
+func (h *Header) codeQueryV2(hdr []byte) int {
+	hdr[0] = HeaderV2 << 3
+	hdr[1] = h.namespace
+	hdr[2] = h.method
+
+	// add query size; 16 bits (64kB) for short, or 24 bits (~16MB) for long signaled by HiQ flag
+	if !h.isLongQuery {
+		binary.BigEndian.PutUint16(hdr[3:5], uint16(h.querySize))
+		return 5 // short query, omit the Size Hi byte
+	}
+
+	hdr[0] |= 1 << 6
+	hdr[3] = byte(h.querySize >> 16)
+	binary.BigEndian.PutUint16(hdr[4:6], uint16(h.querySize))
+	return 6 // long query, the Size Hi byte is present
+}
+
 // codeError encodes error response part of the header into the given buffer returning the number of bytes used.
 // Note: Error response uses Response Size Lo field to store the error code.
 func (h *Header) codeError(hdr []byte, offset int) int {
@@ -324,7 +478,7 @@ func (h *Header) ReadFrom(r io.Reader) (int64, error) {
 
 // readFrom reads the header from Reader and pre-decodes internal flags.
 func (h *Header) readFrom(r io.Reader) ([]byte, error) {
-	hdr := make([]byte, 21)
+	hdr := make([]byte, headerSize)
 	var err error
 
 	// read the first byte to get the idea of how long the header is
@@ -341,7 +495,9 @@ func (h *Header) readFrom(r io.Reader) ([]byte, error) {
 	// calculate the total header size based on received flags
 	var size int
 	switch h.version {
-	case 1:
+	case HeaderV2:
+		size = 20
+	case HeaderV1:
 		size = 18
 	default:
 		size = 10
@@ -376,16 +532,11 @@ func (h *Header) readFrom(r io.Reader) ([]byte, error) {
 
 // decodeFields decodes data fields from the given loaded binary header.
 func (h *Header) decodeFields(hdr []byte) {
-	h.namespace = hdr[0] & 0x7
-	h.method = hdr[1] >> 4
-
 	var offset int
-	if h.isLongQuery {
-		h.querySize = int32(hdr[1]&0xF)<<16 | int32(hdr[2])<<8 | int32(hdr[3])
-		offset = 4
+	if h.version == HeaderV2 {
+		offset = h.decodeQueryV2(hdr)
 	} else {
-		h.querySize = int32(hdr[1]&0xF)<<8 | int32(hdr[2])
-		offset = 3
+		offset = h.decodeQuery(hdr)
 	}
 
 	if h.isLongResult {
@@ -404,7 +555,33 @@ func (h *Header) decodeFields(hdr []byte) {
 	h.blockID = uint64(binary.BigEndian.Uint32(hdr[offset : offset+4]))
 
 	switch h.version {
-	case 1:
+	case HeaderV1, HeaderV2:
 		h.blockTimestamp = binary.BigEndian.Uint64(hdr[offset+4 : offset+12])
 	}
 }
+
+// decodeQuery decodes query part of v0 and v1 headers returning the number of bytes used.
+func (h *Header) decodeQuery(hdr []byte) int {
+	h.namespace = hdr[0] & 0x7
+	h.method = hdr[1] >> 4
+
+	if h.isLongQuery {
+		h.querySize = int32(hdr[1]&0xF)<<16 | int32(hdr[2])<<8 | int32(hdr[3])
+		return 4
+	}
+	h.querySize = int32(hdr[1]&0xF)<<8 | int32(hdr[2])
+	return 3
+}
+
+// decodeQueryV2 decodes query part of v2 header returning the number of bytes used.
+func (h *Header) decodeQueryV2(hdr []byte) int {
+	h.namespace = hdr[1]
+	h.method = hdr[2]
+
+	if h.isLongQuery {
+		h.querySize = int32(hdr[3])<<16 | int32(binary.BigEndian.Uint16(hdr[4:6]))
+		return 6
+	}
+	h.querySize = int32(binary.BigEndian.Uint16(hdr[3:5]))
+	return 5
+}
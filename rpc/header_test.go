@@ -0,0 +1,178 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestHeader_WriteAndReadAreSymmetric(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   byte
+		namespace string
+		method    string
+		query     int
+		response  int
+		errCode   int
+	}{
+		{"v1-short", HeaderV1, "eth", "getBalance", 100, 200, 0},
+		{"v1-long", HeaderV1, "eth", "call", maxShortQuery + 1, maxShortResponse + 1, 0},
+		{"v1-error", HeaderV1, "eth", "getLogs", 100, 0, -32000},
+		{"v2-short", HeaderV2, "debug", "traceCall", 100, 200, 0},
+		{"v2-long", HeaderV2, "sonic", "getBlockByNumber", maxShortQueryV2 + 1, maxShortResponse + 1, 0},
+		{"v2-error", HeaderV2, "eth", "createAccessList", maxQuerySizeAllowedV2, 0, -32602},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := new(Header)
+			if err := h.SetVersion(test.version); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.SetMethod(test.namespace, test.method); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.SetQueryLength(test.query); err != nil {
+				t.Fatal(err)
+			}
+			if test.errCode != 0 {
+				h.SetError(test.errCode)
+			} else {
+				h.SetResponseLength(test.response)
+			}
+			h.SetBlockID(12345)
+			h.SetBlockTimestamp(67890)
+
+			buf := new(bytes.Buffer)
+			if _, err := h.WriteTo(buf); err != nil {
+				t.Fatalf("cannot write header; %v", err)
+			}
+
+			got := new(Header)
+			if _, err := got.ReadFrom(buf); err != nil {
+				t.Fatalf("cannot read header; %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("header was not read completely, %d bytes left", buf.Len())
+			}
+
+			if got.Version() != test.version {
+				t.Errorf("unexpected version, got %v, want %v", got.Version(), test.version)
+			}
+			if ns, err := got.Namespace(); err != nil || ns != test.namespace {
+				t.Errorf("unexpected namespace, got %v (%v), want %v", ns, err, test.namespace)
+			}
+			if m, err := got.Method(); err != nil || m != test.method {
+				t.Errorf("unexpected method, got %v (%v), want %v", m, err, test.method)
+			}
+			if got.QueryLength() != int32(test.query) {
+				t.Errorf("unexpected query length, got %v, want %v", got.QueryLength(), test.query)
+			}
+			if got.ResponseLength() != int32(test.response) {
+				t.Errorf("unexpected response length, got %v, want %v", got.ResponseLength(), test.response)
+			}
+			if got.ErrorCode() != test.errCode {
+				t.Errorf("unexpected error code, got %v, want %v", got.ErrorCode(), test.errCode)
+			}
+			if got.BlockID() != 12345 || got.BlockTimestamp() != 67890 {
+				t.Errorf("unexpected block, got %v at %v", got.BlockID(), got.BlockTimestamp())
+			}
+		})
+	}
+}
+
+func TestHeader_HeaderWithoutVersionIsWrittenAsV1(t *testing.T) {
+	h := new(Header)
+	if err := h.SetMethod("ftm", "getCode"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := h.WriteTo(buf); err != nil {
+		t.Fatalf("cannot write header; %v", err)
+	}
+
+	got := new(Header)
+	if _, err := got.ReadFrom(buf); err != nil {
+		t.Fatalf("cannot read header; %v", err)
+	}
+	if got.Version() != HeaderV1 {
+		t.Errorf("unexpected version %v", got.Version())
+	}
+}
+
+func TestHeader_NewMethodsCanOnlyBeRecordedInV2(t *testing.T) {
+	for _, call := range [][2]string{{"debug", "traceCall"}, {"eth", "createAccessList"}, {"eth", "getBlockByNumber"}, {"sonic", "getBalance"}} {
+		if CanRecord(call[0], call[1]) {
+			t.Errorf("%s_%s must not be recordable in v1", call[0], call[1])
+		}
+		if !CanRecordVersion(HeaderV2, call[0], call[1]) {
+			t.Errorf("%s_%s must be recordable in v2", call[0], call[1])
+		}
+	}
+}
+
+func TestHeader_SetVersionRejectsUnknownVersion(t *testing.T) {
+	if err := new(Header).SetVersion(3); err == nil {
+		t.Error("unknown version must be rejected")
+	}
+}
+
+func TestIterator_DecodesBothHeaderVersions(t *testing.T) {
+	buf := new(bytes.Buffer)
+	for _, version := range []byte{HeaderV1, HeaderV2} {
+		query := []byte(`["0x1","latest"]`)
+		response := []byte(`"0x2"`)
+
+		h := new(Header)
+		_ = h.SetVersion(version)
+		if err := h.SetMethod("eth", "getBalance"); err != nil {
+			t.Fatal(err)
+		}
+		_ = h.SetQueryLength(len(query))
+		h.SetResponseLength(len(response))
+		h.SetBlockID(uint64(version))
+		if _, err := h.WriteTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(query)
+		buf.Write(response)
+	}
+
+	iter := newIterator(context.Background(), io.NopCloser(buf), 10)
+	defer iter.Close()
+
+	for _, version := range []byte{HeaderV1, HeaderV2} {
+		if !iter.Next() {
+			t.Fatalf("missing record of version %v; %v", version, iter.Error())
+		}
+		req := iter.Value()
+		if req.Query.Method != "eth_getBalance" || req.Response.BlockID != uint64(version) {
+			t.Errorf("unexpected record %v, %v", req.Query.Method, req.Response.BlockID)
+		}
+		if string(req.Response.Result) != `"0x2"` {
+			t.Errorf("unexpected result %s", req.Response.Result)
+		}
+	}
+	if iter.Next() {
+		t.Error("iterator must be exhausted")
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	substatecontext "github.com/Fantom-foundation/Aida/txcontext/substate"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxLogBlockRange limits the number of blocks a single getLogs request can span.
+// Requests over a larger range are not replayed.
+const maxLogBlockRange = 10_000
+
+// LogReader provides logs emitted by transactions of a block.
+type LogReader interface {
+	// GetBlockLogs returns all logs of given block ordered by transaction and log index.
+	GetBlockLogs(block uint64) ([]*types.Log, error)
+}
+
+// NewSubstateLogReader returns LogReader which reconstructs logs from substate receipts.
+func NewSubstateLogReader(sdb db.SubstateDB) LogReader {
+	return &substateLogReader{sdb: sdb}
+}
+
+type substateLogReader struct {
+	sdb db.SubstateDB
+}
+
+// GetBlockLogs reads all substates of given block and collects logs from their receipts.
+// Derived fields (block number, transaction and log index) are filled in since they are not part of the receipt.
+func (r *substateLogReader) GetBlockLogs(block uint64) ([]*types.Log, error) {
+	substates, err := r.sdb.GetBlockSubstates(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get substates of block %v; %w", block, err)
+	}
+
+	txs := make([]int, 0, len(substates))
+	for tx := range substates {
+		txs = append(txs, tx)
+	}
+	sort.Ints(txs)
+
+	var logs []*types.Log
+	for _, tx := range txs {
+		ss := substates[tx]
+		if ss.Result == nil {
+			continue
+		}
+		for _, l := range substatecontext.NewReceipt(ss.Result).GetLogs() {
+			l.BlockNumber = block
+			l.TxIndex = uint(tx)
+			l.Index = uint(len(logs))
+			logs = append(logs, l)
+		}
+	}
+
+	return logs, nil
+}
+
+// logFilter represents filter object of getLogs request.
+type logFilter struct {
+	from, to  uint64
+	addresses []common.Address
+	topics    [][]common.Hash
+}
+
+// parseLogFilter decodes filter object of getLogs request. Block tags are resolved against given block.
+func parseLogFilter(param interface{}, block uint64) (*logFilter, error) {
+	m, ok := param.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected filter type %T", param)
+	}
+
+	if _, ok = m["blockHash"]; ok {
+		return nil, errors.New("filtering by block hash is not supported")
+	}
+
+	var (
+		f   = new(logFilter)
+		err error
+	)
+	if f.from, err = parseLogFilterBlock(m["fromBlock"], block); err != nil {
+		return nil, fmt.Errorf("cannot parse fromBlock; %w", err)
+	}
+	if f.to, err = parseLogFilterBlock(m["toBlock"], block); err != nil {
+		return nil, fmt.Errorf("cannot parse toBlock; %w", err)
+	}
+	if f.to > block {
+		f.to = block
+	}
+
+	switch addr := m["address"].(type) {
+	case nil:
+	case string:
+		f.addresses = append(f.addresses, common.HexToAddress(addr))
+	case []interface{}:
+		for _, a := range addr {
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected address type %T", a)
+			}
+			f.addresses = append(f.addresses, common.HexToAddress(s))
+		}
+	default:
+		return nil, fmt.Errorf("unexpected address type %T", addr)
+	}
+
+	if m["topics"] != nil {
+		topics, ok := m["topics"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected topics type %T", m["topics"])
+		}
+		for _, t := range topics {
+			// nil matches any topic at given position
+			var position []common.Hash
+			switch topic := t.(type) {
+			case nil:
+			case string:
+				position = append(position, common.HexToHash(topic))
+			case []interface{}:
+				for _, alternative := range topic {
+					s, ok := alternative.(string)
+					if !ok {
+						return nil, fmt.Errorf("unexpected topic type %T", alternative)
+					}
+					position = append(position, common.HexToHash(s))
+				}
+			default:
+				return nil, fmt.Errorf("unexpected topic type %T", topic)
+			}
+			f.topics = append(f.topics, position)
+		}
+	}
+
+	return f, nil
+}
+
+// parseLogFilterBlock decodes block number or block tag, missing block defaults to given block.
+func parseLogFilterBlock(param interface{}, block uint64) (uint64, error) {
+	if param == nil {
+		return block, nil
+	}
+	str, ok := param.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected block type %T", param)
+	}
+	switch str {
+	case "latest", "pending", "safe", "finalized":
+		return block, nil
+	case "earliest":
+		return 0, nil
+	default:
+		return hexutil.DecodeUint64(str)
+	}
+}
+
+// matches returns true if given log satisfies address and topic criteria of the filter.
+func (f *logFilter) matches(l *types.Log) bool {
+	if len(f.addresses) > 0 {
+		found := false
+		for _, a := range f.addresses {
+			if a == l.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.topics) > len(l.Topics) {
+		return false
+	}
+	for i, position := range f.topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, t := range position {
+			if t == l.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type testLogReader map[uint64][]*types.Log
+
+func (r testLogReader) GetBlockLogs(block uint64) ([]*types.Log, error) {
+	return r[block], nil
+}
+
+func TestLogFilter_ParseResolvesBlockTagsAndCriteria(t *testing.T) {
+	param := map[string]interface{}{
+		"fromBlock": "0xa",
+		"toBlock":   "latest",
+		"address":   []interface{}{"0x1", "0x2"},
+		"topics":    []interface{}{nil, "0x3", []interface{}{"0x4", "0x5"}},
+	}
+
+	f, err := parseLogFilter(param, 20)
+	if err != nil {
+		t.Fatalf("cannot parse filter; %v", err)
+	}
+
+	if f.from != 10 || f.to != 20 {
+		t.Errorf("unexpected block range %v-%v", f.from, f.to)
+	}
+	if got, want := len(f.addresses), 2; got != want {
+		t.Errorf("unexpected number of addresses, got %v, want %v", got, want)
+	}
+	if got, want := len(f.topics), 3; got != want {
+		t.Fatalf("unexpected number of topic positions, got %v, want %v", got, want)
+	}
+	if len(f.topics[0]) != 0 || len(f.topics[1]) != 1 || len(f.topics[2]) != 2 {
+		t.Errorf("unexpected topics %v", f.topics)
+	}
+}
+
+func TestLogFilter_ParseFailsForBlockHash(t *testing.T) {
+	param := map[string]interface{}{
+		"blockHash": "0x1",
+	}
+
+	if _, err := parseLogFilter(param, 20); err == nil {
+		t.Fatal("parsing filter with block hash must fail")
+	}
+}
+
+func TestLogFilter_MatchesAddressAndTopics(t *testing.T) {
+	f := &logFilter{
+		addresses: []common.Address{{1}},
+		topics:    [][]common.Hash{nil, {{2}, {3}}},
+	}
+
+	tests := []struct {
+		name string
+		log  *types.Log
+		want bool
+	}{
+		{"matching", &types.Log{Address: common.Address{1}, Topics: []common.Hash{{9}, {3}}}, true},
+		{"wrong address", &types.Log{Address: common.Address{2}, Topics: []common.Hash{{9}, {3}}}, false},
+		{"wrong topic", &types.Log{Address: common.Address{1}, Topics: []common.Hash{{9}, {4}}}, false},
+		{"missing topic", &types.Log{Address: common.Address{1}, Topics: []common.Hash{{9}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := f.matches(test.log); got != test.want {
+				t.Errorf("unexpected match, got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExecuteGetLogs_CollectsMatchingLogsOfWholeRange(t *testing.T) {
+	reader := testLogReader{
+		1: {{Address: common.Address{1}, Topics: []common.Hash{}, BlockNumber: 1}},
+		2: {{Address: common.Address{2}, Topics: []common.Hash{}, BlockNumber: 2}, {Address: common.Address{1}, Topics: []common.Hash{}, BlockNumber: 2}},
+		3: {{Address: common.Address{1}, Topics: []common.Hash{}, BlockNumber: 3}},
+	}
+	f := &logFilter{from: 1, to: 2, addresses: []common.Address{{1}}}
+
+	res, err := executeGetLogs(f, reader)
+	if err != nil {
+		t.Fatalf("cannot execute getLogs; %v", err)
+	}
+
+	var logs []*types.Log
+	if err = json.Unmarshal(res.result, &logs); err != nil {
+		t.Fatalf("cannot unmarshal result; %v", err)
+	}
+	if got, want := len(logs), 2; got != want {
+		t.Fatalf("unexpected number of logs, got %v, want %v", got, want)
+	}
+	if logs[0].BlockNumber != 1 || logs[1].BlockNumber != 2 {
+		t.Errorf("unexpected logs %v", logs)
+	}
+}
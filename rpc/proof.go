@@ -0,0 +1,188 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// AccountResult is the response of getProof request.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is a proof of single storage slot within the getProof response.
+type StorageResult struct {
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// newAccountResult converts proof created by the StateDB into the getProof response.
+func newAccountResult(proof *state.AccountProof) *AccountResult {
+	res := &AccountResult{
+		Address:      proof.Address,
+		AccountProof: toHexBytes(proof.Proof),
+		Balance:      (*hexutil.Big)(proof.Balance.ToBig()),
+		CodeHash:     proof.CodeHash,
+		Nonce:        hexutil.Uint64(proof.Nonce),
+		StorageHash:  proof.StorageHash,
+		StorageProof: make([]StorageResult, 0, len(proof.StorageProof)),
+	}
+	for _, sp := range proof.StorageProof {
+		res.StorageProof = append(res.StorageProof, StorageResult{
+			Key:   sp.Key.Hex(),
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(sp.Value.Bytes())),
+			Proof: toHexBytes(sp.Proof),
+		})
+	}
+	return res
+}
+
+// Verify checks that the account proof and all storage proofs are valid for given
+// state root and that they certify the values claimed by the response.
+func (r *AccountResult) Verify(root common.Hash) error {
+	value, err := trie.VerifyProof(root, crypto.Keccak256(r.Address.Bytes()), newProofDb(r.AccountProof))
+	if err != nil {
+		return fmt.Errorf("invalid account proof of %v; %w", r.Address, err)
+	}
+
+	account := types.NewEmptyStateAccount()
+	// missing accounts are proven by a proof of absence
+	if value != nil {
+		if err = rlp.DecodeBytes(value, account); err != nil {
+			return fmt.Errorf("cannot decode account %v; %w", r.Address, err)
+		}
+	}
+
+	if r.Balance == nil || account.Balance.ToBig().Cmp(r.Balance.ToInt()) != 0 {
+		return fmt.Errorf("unexpected balance of %v; proven %v, claimed %v", r.Address, account.Balance, r.Balance)
+	}
+	if account.Nonce != uint64(r.Nonce) {
+		return fmt.Errorf("unexpected nonce of %v; proven %v, claimed %v", r.Address, account.Nonce, uint64(r.Nonce))
+	}
+	if !bytes.Equal(account.CodeHash, r.CodeHash.Bytes()) && !(value == nil && r.CodeHash == (common.Hash{})) {
+		return fmt.Errorf("unexpected code hash of %v; proven %x, claimed %v", r.Address, account.CodeHash, r.CodeHash)
+	}
+	if account.Root != r.StorageHash && !(value == nil && r.StorageHash == (common.Hash{})) {
+		return fmt.Errorf("unexpected storage hash of %v; proven %v, claimed %v", r.Address, account.Root, r.StorageHash)
+	}
+
+	for _, sp := range r.StorageProof {
+		key := common.HexToHash(sp.Key)
+		// slots of an empty storage are proven by the empty root itself
+		if account.Root == types.EmptyRootHash && len(sp.Proof) == 0 {
+			if sp.Value == nil || sp.Value.ToInt().Sign() != 0 {
+				return fmt.Errorf("unexpected value of %v at %v; proven 0, claimed %v", key, r.Address, sp.Value)
+			}
+			continue
+		}
+		value, err = trie.VerifyProof(account.Root, crypto.Keccak256(key.Bytes()), newProofDb(sp.Proof))
+		if err != nil {
+			return fmt.Errorf("invalid storage proof of %v at %v; %w", key, r.Address, err)
+		}
+
+		proven := new(big.Int)
+		if value != nil {
+			var content []byte
+			if err = rlp.DecodeBytes(value, &content); err != nil {
+				return fmt.Errorf("cannot decode storage %v at %v; %w", key, r.Address, err)
+			}
+			proven.SetBytes(content)
+		}
+		if sp.Value == nil || proven.Cmp(sp.Value.ToInt()) != 0 {
+			return fmt.Errorf("unexpected value of %v at %v; proven %v, claimed %v", key, r.Address, proven, sp.Value)
+		}
+	}
+
+	return nil
+}
+
+// executeGetProof request into given archive and send result to comparator
+func executeGetProof(params []interface{}, archive state.NonCommittableStateDB) (*result, error) {
+	if len(params) < 2 {
+		return nil, errors.New("missing getProof params")
+	}
+
+	address, ok := params[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected address type %T", params[0])
+	}
+	rawKeys, ok := params[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected storage keys type %T", params[1])
+	}
+	keys := make([]common.Hash, 0, len(rawKeys))
+	for _, k := range rawKeys {
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected storage key type %T", k)
+		}
+		keys = append(keys, common.HexToHash(key))
+	}
+
+	proof, err := archive.GetProof(common.HexToAddress(address), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := json.Marshal(newAccountResult(proof))
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal proof; %w", err)
+	}
+
+	return &result{
+		result: res,
+	}, nil
+}
+
+// newProofDb creates a database of trie nodes usable for proof verification.
+func newProofDb(proof []hexutil.Bytes) *memorydb.Database {
+	db := memorydb.New()
+	for _, node := range proof {
+		// memorydb never returns an error
+		_ = db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+func toHexBytes(proof [][]byte) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, 0, len(proof))
+	for _, node := range proof {
+		res = append(res, node)
+	}
+	return res
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+	"go.uber.org/mock/gomock"
+)
+
+// proofCollector collects proof nodes in the order they are written by the trie.
+type proofCollector struct {
+	nodes []hexutil.Bytes
+}
+
+func (c *proofCollector) Put(_ []byte, value []byte) error {
+	c.nodes = append(c.nodes, common.CopyBytes(value))
+	return nil
+}
+
+func (c *proofCollector) Delete([]byte) error {
+	return nil
+}
+
+// makeTestAccountResult creates a valid getProof response of an account with a single storage slot.
+func makeTestAccountResult(t *testing.T) (*AccountResult, common.Hash) {
+	db := triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil)
+	address := common.Address{1}
+	key := common.Hash{2}
+	value := common.Hash{31: 3}
+
+	storage := trie.NewEmpty(db)
+	encodedValue, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(value.Bytes()))
+	storage.MustUpdate(crypto.Keccak256(key.Bytes()), encodedValue)
+	storageRoot := storage.Hash()
+	storageProof := new(proofCollector)
+	if err := storage.Prove(crypto.Keccak256(key.Bytes()), storageProof); err != nil {
+		t.Fatalf("cannot prove storage; %v", err)
+	}
+
+	account := &types.StateAccount{
+		Nonce:    5,
+		Balance:  uint256.NewInt(10),
+		Root:     storageRoot,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	}
+	encodedAccount, _ := rlp.EncodeToBytes(account)
+	accounts := trie.NewEmpty(db)
+	accounts.MustUpdate(crypto.Keccak256(address.Bytes()), encodedAccount)
+	accounts.MustUpdate(crypto.Keccak256(common.Address{9}.Bytes()), encodedAccount)
+	root := accounts.Hash()
+	accountProof := new(proofCollector)
+	if err := accounts.Prove(crypto.Keccak256(address.Bytes()), accountProof); err != nil {
+		t.Fatalf("cannot prove account; %v", err)
+	}
+
+	return &AccountResult{
+		Address:      address,
+		AccountProof: accountProof.nodes,
+		Balance:      (*hexutil.Big)(account.Balance.ToBig()),
+		CodeHash:     types.EmptyCodeHash,
+		Nonce:        hexutil.Uint64(account.Nonce),
+		StorageHash:  storageRoot,
+		StorageProof: []StorageResult{{
+			Key:   key.Hex(),
+			Value: (*hexutil.Big)(value.Big()),
+			Proof: storageProof.nodes,
+		}},
+	}, root
+}
+
+func TestAccountResult_VerifyAcceptsValidProof(t *testing.T) {
+	res, root := makeTestAccountResult(t)
+	if err := res.Verify(root); err != nil {
+		t.Errorf("valid proof must be accepted; %v", err)
+	}
+}
+
+func TestAccountResult_VerifyRejectsWrongRoot(t *testing.T) {
+	res, _ := makeTestAccountResult(t)
+	if err := res.Verify(common.Hash{1}); err == nil {
+		t.Error("proof must be rejected for different root")
+	}
+}
+
+func TestAccountResult_VerifyRejectsWrongClaims(t *testing.T) {
+	tests := map[string]func(*AccountResult){
+		"balance": func(r *AccountResult) { r.Balance = (*hexutil.Big)(common.Big1) },
+		"nonce":   func(r *AccountResult) { r.Nonce = 1 },
+		"storage": func(r *AccountResult) { r.StorageProof[0].Value = (*hexutil.Big)(common.Big1) },
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, root := makeTestAccountResult(t)
+			modify(res)
+			if err := res.Verify(root); err == nil {
+				t.Error("proof must be rejected")
+			}
+		})
+	}
+}
+
+func TestExecute_GetProofIsSkippedIfProofsAreNotSupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+	archive.EXPECT().GetProof(common.HexToAddress("0x1"), []common.Hash{common.HexToHash("0x2")}).Return(nil, state.ErrProofsNotSupported)
+
+	rec := &RequestAndResults{
+		Query: &Body{
+			MethodBase: "getProof",
+			Params:     []interface{}{"0x1", []interface{}{"0x2"}, "latest"},
+		},
+	}
+	res, err := Execute(1, rec, archive, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if res != nil {
+		t.Errorf("unexpected result %v", res)
+	}
+	if !rec.SkipValidation {
+		t.Error("request must be skipped")
+	}
+}
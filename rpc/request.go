@@ -34,6 +34,8 @@ type RequestAndResults struct {
 	IsRecovered                   bool
 	RecordedBlock, RequestedBlock int
 	Timestamp                     uint64
+	MismatchType                  string        // type of mismatch found by the comparator, empty if results match
+	ExecutionTime                 time.Duration // time spent executing the request on the StateDB
 }
 
 // Body represents a decoded payload of a balancer.
@@ -92,7 +94,14 @@ func (r *RequestAndResults) findRequestedBlock() {
 		return
 	}
 
-	str := r.Query.Params[l-1].(string)
+	// the last param is not a block for every method (e.g. getBlockByNumber),
+	// such requests are executed at the recorded block
+	str, ok := r.Query.Params[l-1].(string)
+	if !ok {
+		r.RequestedBlock = r.RecordedBlock
+		return
+	}
+
 	switch str {
 	case "pending":
 		// validation for pending requests does not work, skip them
@@ -105,7 +114,11 @@ func (r *RequestAndResults) findRequestedBlock() {
 		r.RequestedBlock = 0
 
 	default:
-		// botched params are not recorded, so this will  never panic
-		r.RequestedBlock = int(hexutil.MustDecodeUint64(str))
+		block, err := hexutil.DecodeUint64(str)
+		if err != nil {
+			r.RequestedBlock = r.RecordedBlock
+			return
+		}
+		r.RequestedBlock = int(block)
 	}
 }
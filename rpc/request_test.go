@@ -25,6 +25,24 @@ func TestRequestAndResults_DecodeInfoPendingBlocksSkipValidation(t *testing.T) {
 	}
 }
 
+func TestRequestAndResults_DecodeInfoUsesRecordedBlockIfLastParamIsNotBlock(t *testing.T) {
+	req := &RequestAndResults{
+		Response: &Response{
+			BlockID: 10,
+		},
+		Query: &Body{
+			Params: []interface{}{
+				"0x5", false,
+			},
+		},
+	}
+
+	req.DecodeInfo()
+	if got, want := req.RequestedBlock, 10; got != want {
+		t.Errorf("unexpected requested block, got %v, want %v", got, want)
+	}
+}
+
 var r = &RequestAndResults{
 	Response: &Response{
 		BlockID: 10,
@@ -0,0 +1,167 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// recordingFilePattern is the name pattern of files created by the FileWriter.
+// Sequence numbers are zero-padded so that files are listed in the order of recording.
+const recordingFilePattern = "rpc-recording-%06d.dat"
+
+// FileWriter writes API calls into recording files inside a directory.
+// The output is rotated into a new file once the current one exceeds the size limit.
+type FileWriter struct {
+	dir     string
+	maxSize int64
+	version byte
+	mu      sync.Mutex
+	f       *os.File
+	out     *bufio.Writer
+	size    int64
+	seq     int
+}
+
+// NewFileWriter creates new instance of the file writer producing recordings of given header version.
+func NewFileWriter(dir string, maxSize int64, version byte) (*FileWriter, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid maximum file size %v", maxSize)
+	}
+	if version != HeaderV1 && version != HeaderV2 {
+		return nil, fmt.Errorf("unknown header version %v", version)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create output directory %v; %w", dir, err)
+	}
+
+	return &FileWriter{
+		dir:     dir,
+		maxSize: maxSize,
+		version: version,
+	}, nil
+}
+
+// Write appends given API call to the recording. The record is encoded in the same way
+// it is decoded by the iterator, i.e. the header followed by the params and the result.
+func (w *FileWriter) Write(rec *RequestAndResults) error {
+	hdr := new(Header)
+	if err := hdr.SetVersion(w.version); err != nil {
+		return err
+	}
+	if err := hdr.SetMethod(rec.Query.Namespace, rec.Query.MethodBase); err != nil {
+		return err
+	}
+
+	params := rec.ParamsRaw
+	if len(params) == 0 {
+		params = []byte("[]")
+	}
+	if err := hdr.SetQueryLength(len(params)); err != nil {
+		return err
+	}
+
+	var result []byte
+	switch {
+	case rec.Error != nil:
+		hdr.SetError(rec.Error.Error.Code)
+		hdr.SetBlockID(rec.Error.BlockID)
+		hdr.SetBlockTimestamp(rec.Error.Timestamp)
+	case rec.Response != nil:
+		result = rec.Response.Result
+		hdr.SetResponseLength(len(result))
+		hdr.SetBlockID(rec.Response.BlockID)
+		hdr.SetBlockTimestamp(rec.Response.Timestamp)
+	default:
+		return errors.New("record has neither response nor error")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := hdr.WriteTo(w.out)
+	if err != nil {
+		return fmt.Errorf("cannot write header; %w", err)
+	}
+	w.size += n
+
+	for _, payload := range [][]byte{params, result} {
+		m, err := w.out.Write(payload)
+		if err != nil {
+			return fmt.Errorf("cannot write payload; %w", err)
+		}
+		w.size += int64(m)
+	}
+
+	if w.size >= w.maxSize {
+		return w.closeFile()
+	}
+	return nil
+}
+
+// Close flushes and closes the current recording file.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+	return w.closeFile()
+}
+
+// rotate opens next recording file. Existing files are never overwritten.
+func (w *FileWriter) rotate() error {
+	for {
+		w.seq++
+		path := filepath.Join(w.dir, fmt.Sprintf(recordingFilePattern, w.seq))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot create recording file %v; %w", path, err)
+		}
+
+		w.f = f
+		w.out = bufio.NewWriter(f)
+		w.size = 0
+		return nil
+	}
+}
+
+// closeFile flushes buffered data and closes the current recording file.
+func (w *FileWriter) closeFile() error {
+	f := w.f
+	w.f = nil
+	if err := w.out.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot flush recording file %v; %w", f.Name(), err)
+	}
+	return f.Close()
+}
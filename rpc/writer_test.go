@@ -0,0 +1,132 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWriter_RecordingCanBeReadBack(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewFileWriter(dir, 1<<20, HeaderV2)
+	if err != nil {
+		t.Fatalf("cannot create writer; %v", err)
+	}
+
+	records := []*RequestAndResults{
+		{
+			Query:     &Body{Namespace: "eth", MethodBase: "getBalance"},
+			ParamsRaw: []byte(`["0x1","latest"]`),
+			Response:  &Response{BlockID: 5, Timestamp: 6, Result: json.RawMessage(`"0x10"`)},
+		},
+		{
+			Query:     &Body{Namespace: "debug", MethodBase: "traceCall"},
+			ParamsRaw: []byte(`[{"to":"0x1"},"0x4"]`),
+			Error:     &ErrorResponse{BlockID: 7, Timestamp: 8, Error: ErrorMessage{Code: -32000}},
+		},
+	}
+	for _, rec := range records {
+		if err = w.Write(rec); err != nil {
+			t.Fatalf("cannot write record; %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("cannot close writer; %v", err)
+	}
+
+	iter, err := NewFileReader(context.Background(), filepath.Join(dir, "rpc-recording-000001.dat"))
+	if err != nil {
+		t.Fatalf("cannot open recording; %v", err)
+	}
+	defer iter.Close()
+
+	if !iter.Next() {
+		t.Fatalf("missing first record; %v", iter.Error())
+	}
+	got := iter.Value()
+	if got.Query.Method != "eth_getBalance" || string(got.ParamsRaw) != `["0x1","latest"]` {
+		t.Errorf("unexpected query %v %s", got.Query.Method, got.ParamsRaw)
+	}
+	if got.Response == nil || string(got.Response.Result) != `"0x10"` || got.Response.BlockID != 5 || got.Response.Timestamp != 6 {
+		t.Errorf("unexpected response %v", got.Response)
+	}
+
+	if !iter.Next() {
+		t.Fatalf("missing second record; %v", iter.Error())
+	}
+	got = iter.Value()
+	if got.Query.Method != "debug_traceCall" {
+		t.Errorf("unexpected method %v", got.Query.Method)
+	}
+	if got.Error == nil || got.Error.Error.Code != -32000 || got.Error.BlockID != 7 {
+		t.Errorf("unexpected error %v", got.Error)
+	}
+
+	if iter.Next() {
+		t.Error("recording must contain only two records")
+	}
+}
+
+func TestFileWriter_RotatesFilesBySize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewFileWriter(dir, 50, HeaderV2)
+	if err != nil {
+		t.Fatalf("cannot create writer; %v", err)
+	}
+
+	rec := &RequestAndResults{
+		Query:     &Body{Namespace: "eth", MethodBase: "blockNumber"},
+		ParamsRaw: []byte(`[]`),
+		Response:  &Response{BlockID: 1, Result: json.RawMessage(`"0x1"`)},
+	}
+	// every record has 27 bytes, so each file holds two of them
+	for i := 0; i < 5; i++ {
+		if err = w.Write(rec); err != nil {
+			t.Fatalf("cannot write record; %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("cannot close writer; %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read dir; %v", err)
+	}
+	if got, want := len(files), 3; got != want {
+		t.Fatalf("unexpected number of files, got %v, want %v", got, want)
+	}
+
+	count := 0
+	for _, f := range files {
+		iter, err := NewFileReader(context.Background(), filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Fatalf("cannot open recording; %v", err)
+		}
+		for iter.Next() {
+			count++
+		}
+		iter.Close()
+	}
+	if count != 5 {
+		t.Errorf("unexpected number of records, got %v, want 5", count)
+	}
+}
@@ -102,14 +102,16 @@ func MakeCarmenStateDB(
 
 	return &carmenHeadState{
 		carmenStateDB: carmenStateDB{
-			db: db,
+			db:     db,
+			proofs: schema == 5 && archiveType == "s5",
 		},
 	}, nil
 }
 
 type carmenStateDB struct {
-	db    carmen.Database
-	txCtx carmen.TransactionContext
+	db     carmen.Database
+	txCtx  carmen.TransactionContext
+	proofs bool // archive produces witness proofs (S5 schema)
 }
 
 type carmenHeadState struct {
@@ -419,7 +421,8 @@ func (s *carmenHeadState) GetArchiveState(block uint64) (NonCommittableStateDB,
 
 	return &carmenHistoricState{
 		carmenStateDB: carmenStateDB{
-			db: s.db,
+			db:     s.db,
+			proofs: s.proofs,
 		},
 		blkCtx:    historicBlkCtx,
 		blkNumber: block,
@@ -452,6 +455,63 @@ func (s *carmenStateDB) GetShadowDB() StateDB {
 	return nil
 }
 
+// GetProof creates a witness proof using the archive of Carmen. Proofs are only supported by the S5 schema.
+func (s *carmenHistoricState) GetProof(addr common.Address, keys []common.Hash) (*AccountProof, error) {
+	if !s.proofs {
+		return nil, ErrProofsNotSupported
+	}
+
+	carmenKeys := make([]carmen.Key, len(keys))
+	for i, key := range keys {
+		carmenKeys[i] = carmen.Key(key)
+	}
+
+	proof, err := s.blkCtx.GetProof(carmen.Address(addr), carmenKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get proof of %v; %w", addr, err)
+	}
+
+	root, err := s.GetHash()
+	if err != nil {
+		return nil, err
+	}
+
+	accountElements, storageHash, complete := proof.GetAccountElements(carmen.Hash(root), carmen.Address(addr))
+	if !complete {
+		return nil, fmt.Errorf("account proof of %v is incomplete", addr)
+	}
+
+	res := &AccountProof{
+		Address:     addr,
+		Proof:       make([][]byte, 0, len(accountElements)),
+		Balance:     s.GetBalance(addr),
+		Nonce:       s.GetNonce(addr),
+		CodeHash:    s.GetCodeHash(addr),
+		StorageHash: common.Hash(storageHash),
+	}
+	for _, element := range accountElements {
+		res.Proof = append(res.Proof, element.ToBytes())
+	}
+
+	for _, key := range keys {
+		storageElements, complete := proof.GetStorageElements(carmen.Hash(root), carmen.Address(addr), carmen.Key(key))
+		if !complete {
+			return nil, fmt.Errorf("storage proof of %v at %v is incomplete", key, addr)
+		}
+		storageProof := StorageProof{
+			Key:   key,
+			Value: s.GetState(addr, key),
+			Proof: make([][]byte, 0, len(storageElements)),
+		}
+		for _, element := range storageElements {
+			storageProof.Proof = append(storageProof.Proof, element.ToBytes())
+		}
+		res.StorageProof = append(res.StorageProof, storageProof)
+	}
+
+	return res, nil
+}
+
 func (s *carmenHistoricState) Release() error {
 	return s.blkCtx.Close()
 }
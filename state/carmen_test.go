@@ -837,3 +837,11 @@ func TestCarmenState_GetShadowDB(t *testing.T) {
 		})
 	}
 }
+
+// TestCarmenState_GetProofIsNotSupportedWithoutS5Archive tests that archives of other schemas report missing proof support
+func TestCarmenState_GetProofIsNotSupportedWithoutS5Archive(t *testing.T) {
+	s := &carmenHistoricState{}
+	if _, err := s.GetProof(common.Address{1}, []common.Hash{{2}}); !errors.Is(err, ErrProofsNotSupported) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
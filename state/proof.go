@@ -0,0 +1,46 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// ErrProofsNotSupported is returned by state implementations which cannot produce witness proofs.
+var ErrProofsNotSupported = errors.New("proofs are not supported by this DB implementation")
+
+// AccountProof is a witness proof of an account and a set of its storage slots.
+// Proofs consist of RLP encoded Merkle-Patricia trie nodes ordered from the root.
+type AccountProof struct {
+	Address      common.Address
+	Proof        [][]byte
+	Balance      *uint256.Int
+	Nonce        uint64
+	CodeHash     common.Hash
+	StorageHash  common.Hash
+	StorageProof []StorageProof
+}
+
+// StorageProof is a witness proof of a single storage slot within the storage trie of an account.
+type StorageProof struct {
+	Key   common.Hash
+	Value common.Hash
+	Proof [][]byte
+}
@@ -407,6 +407,12 @@ func (s *loggingVmStateDb) GetStorageRoot(addr common.Address) common.Hash {
 	return res
 }
 
+func (s *loggingNonCommittableStateDb) GetProof(addr common.Address, keys []common.Hash) (*state.AccountProof, error) {
+	res, err := s.nonCommittableStateDB.GetProof(addr, keys)
+	s.writeLog("GetProof, %v, %v, %v", addr, keys, err)
+	return res, err
+}
+
 func (s *loggingNonCommittableStateDb) Release() error {
 	s.writeLog("Release")
 	s.nonCommittableStateDB.Release()
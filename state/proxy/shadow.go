@@ -244,6 +244,11 @@ func (s *shadowNonCommittableStateDb) GetHash() (common.Hash, error) {
 	return s.prime.GetHash()
 }
 
+// GetProof returns proof of the prime DB, proofs of both DBs are only comparable if their hashes are compatible.
+func (s *shadowNonCommittableStateDb) GetProof(addr common.Address, keys []common.Hash) (*state.AccountProof, error) {
+	return s.prime.GetProof(addr, keys)
+}
+
 func (s *shadowStateDb) Close() error {
 	return s.getError("Close", func(s state.StateDB) error { return s.Close() })
 }
@@ -133,6 +133,11 @@ type NonCommittableStateDB interface {
 	// through the VmStateDB interface on the state.
 	GetHash() (common.Hash, error)
 
+	// GetProof creates a witness proof of given account and its storage slots certified
+	// by the hash returned by GetHash. Only implementations maintaining Ethereum compatible
+	// tries support proofs, others return ErrProofsNotSupported.
+	GetProof(common.Address, []common.Hash) (*AccountProof, error)
+
 	// Release frees resources bound by this view. Release should be called on every
 	// instance once all operations have been completed. Once released, no further
 	// operations on the respective instance are allowed.
@@ -885,6 +885,21 @@ func (mr *MockNonCommittableStateDBMockRecorder) GetNonce(arg0 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNonce", reflect.TypeOf((*MockNonCommittableStateDB)(nil).GetNonce), arg0)
 }
 
+// GetProof mocks base method.
+func (m *MockNonCommittableStateDB) GetProof(arg0 common.Address, arg1 []common.Hash) (*AccountProof, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProof", arg0, arg1)
+	ret0, _ := ret[0].(*AccountProof)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProof indicates an expected call of GetProof.
+func (mr *MockNonCommittableStateDBMockRecorder) GetProof(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProof", reflect.TypeOf((*MockNonCommittableStateDB)(nil).GetProof), arg0, arg1)
+}
+
 // GetRefund mocks base method.
 func (m *MockNonCommittableStateDB) GetRefund() uint64 {
 	m.ctrl.T.Helper()
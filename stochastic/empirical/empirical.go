@@ -0,0 +1,78 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package empirical
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Package for distributions on the unit interval given by a piecewise linear
+// empirical cumulative distribution function.
+
+// Check checks whether the points form a cumulative distribution function on
+// the unit interval, i.e., the points are non-decreasing and range from (0,0)
+// to (1,1).
+func Check(points [][2]float64) error {
+	if len(points) < 2 {
+		return fmt.Errorf("empirical distribution requires at least two points")
+	}
+	if points[0] != [2]float64{0, 0} || points[len(points)-1] != [2]float64{1, 1} {
+		return fmt.Errorf("empirical distribution must range from (0,0) to (1,1)")
+	}
+	for i := 1; i < len(points); i++ {
+		if points[i][0] < points[i-1][0] || points[i][1] < points[i-1][1] {
+			return fmt.Errorf("points of empirical distribution must be non-decreasing")
+		}
+	}
+	return nil
+}
+
+// Cdf evaluates the cumulative distribution function by linear interpolation.
+func Cdf(points [][2]float64, x float64) float64 {
+	i := sort.Search(len(points), func(i int) bool { return points[i][0] >= x })
+	switch {
+	case i == len(points):
+		return points[len(points)-1][1]
+	case i == 0 || points[i][0] == x:
+		return points[i][1]
+	}
+	x0, y0 := points[i-1][0], points[i-1][1]
+	x1, y1 := points[i][0], points[i][1]
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
+
+// Quantile is the inverse cumulative distribution function.
+func Quantile(points [][2]float64, p float64) float64 {
+	i := sort.Search(len(points), func(i int) bool { return points[i][1] >= p })
+	switch {
+	case i == len(points):
+		return points[len(points)-1][0]
+	case i == 0 || points[i][1] == p:
+		return points[i][0]
+	}
+	x0, y0 := points[i-1][0], points[i-1][1]
+	x1, y1 := points[i][0], points[i][1]
+	return x0 + (x1-x0)*(p-y0)/(y1-y0)
+}
+
+// DiscreteSample samples the distribution and discretizes the result for numbers in the range between 0 and n-1.
+func DiscreteSample(rg *rand.Rand, points [][2]float64, n int64) int64 {
+	v := int64(float64(n) * Quantile(points, rg.Float64()))
+	return min(max(v, 0), n-1)
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package empirical
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestCdfQuantile checks the evaluation and the inversion of a piecewise linear CDF.
+func TestCdfQuantile(t *testing.T) {
+	points := [][2]float64{{0, 0}, {0.1, 0.5}, {0.5, 0.9}, {1, 1}}
+	for _, c := range [][2]float64{{0, 0}, {0.05, 0.25}, {0.1, 0.5}, {0.3, 0.7}, {0.75, 0.95}, {1, 1}} {
+		if got := Cdf(points, c[0]); math.Abs(got-c[1]) > 1e-12 {
+			t.Errorf("unexpected CDF %v at %v, want %v", got, c[0], c[1])
+		}
+		if got := Quantile(points, c[1]); math.Abs(got-c[0]) > 1e-12 {
+			t.Errorf("unexpected quantile %v of %v, want %v", got, c[1], c[0])
+		}
+	}
+}
+
+// TestCheck checks the validation of empirical distributions.
+func TestCheck(t *testing.T) {
+	if err := Check([][2]float64{{0, 0}, {0.5, 0.8}, {1, 1}}); err != nil {
+		t.Errorf("valid distribution rejected; %v", err)
+	}
+	for _, points := range [][][2]float64{
+		nil,
+		{{0, 0}},
+		{{0, 0.1}, {1, 1}},
+		{{0, 0}, {1, 0.9}},
+		{{0, 0}, {0.5, 0.8}, {0.4, 0.9}, {1, 1}},
+		{{0, 0}, {0.5, 0.8}, {0.6, 0.7}, {1, 1}},
+	} {
+		if err := Check(points); err == nil {
+			t.Errorf("invalid distribution %v accepted", points)
+		}
+	}
+}
+
+// TestDiscreteSample checks the range and the frequencies of samples.
+func TestDiscreteSample(t *testing.T) {
+	rg := rand.New(rand.NewSource(999))
+	points := [][2]float64{{0, 0}, {0.1, 0.5}, {1, 1}}
+	n := int64(10)
+	first := 0
+	steps := 100000
+	for i := 0; i < steps; i++ {
+		v := DiscreteSample(rg, points, n)
+		if v < 0 || v >= n {
+			t.Fatalf("sample %v out of range", v)
+		}
+		if v == 0 {
+			first++
+		}
+	}
+	if p := float64(first) / float64(steps); math.Abs(p-0.5) > 0.01 {
+		t.Errorf("unexpected frequency %v of first index", p)
+	}
+}
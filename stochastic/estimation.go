@@ -21,10 +21,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 
+	"github.com/Fantom-foundation/Aida/stochastic/empirical"
 	"github.com/Fantom-foundation/Aida/stochastic/exponential"
+	"github.com/Fantom-foundation/Aida/stochastic/generator"
 	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	"github.com/Fantom-foundation/Aida/stochastic/zipf"
 )
 
 // EstimationModelJSON is the output of the estimator in JSON format.
@@ -38,6 +42,21 @@ type EstimationModelJSON struct {
 	Values    EstimationStatsJSON `json:"valueStats"`
 
 	SnapshotLambda float64 `json:"snapshotLambda"`
+
+	// probabilities of the bit lengths of balance deltas, nonces, and code sizes
+	// (empty if not recorded)
+	Balances  []float64 `json:"balanceDistribution,omitempty"`
+	Nonces    []float64 `json:"nonceDistribution,omitempty"`
+	CodeSizes []float64 `json:"codeSizeDistribution,omitempty"`
+
+	// markov order and transition probabilities after contexts of preceding states
+	Order    int                `json:"order,omitempty"`
+	Contexts []ContextModelJSON `json:"contexts,omitempty"`
+
+	// number of blocks per phase and models of the phases
+	// (only estimated for a time-varying model)
+	PhaseLength uint64                `json:"phaseLength,omitempty"`
+	Phases      []EstimationModelJSON `json:"phases,omitempty"`
 }
 
 // NewEstimationModelJSON creates a new estimation model.
@@ -59,6 +78,12 @@ func NewEstimationModelJSON(d *EventRegistryJSON) EstimationModelJSON {
 		log.Fatalf("failed to approximate lambda parameter; %v", err)
 	}
 
+	// estimate a model for each phase
+	var phases []EstimationModelJSON
+	for i := range d.Phases {
+		phases = append(phases, NewEstimationModelJSON(&d.Phases[i]))
+	}
+
 	// construct JSON object for simulation
 	return EstimationModelJSON{
 		FileId:           "simulation",
@@ -68,6 +93,13 @@ func NewEstimationModelJSON(d *EventRegistryJSON) EstimationModelJSON {
 		Keys:             NewEstimationStats(&d.Keys),
 		Values:           NewEstimationStats(&d.Values),
 		SnapshotLambda:   snapshotLambda,
+		Balances:         d.Balances.Distribution(),
+		Nonces:           d.Nonces.Distribution(),
+		CodeSizes:        d.CodeSizes.Distribution(),
+		Order:            d.Order,
+		Contexts:         estimateContexts(d),
+		PhaseLength:      d.PhaseLength,
+		Phases:           phases,
 	}
 }
 
@@ -111,11 +143,25 @@ func (m *EstimationModelJSON) WriteJSON(filename string) error {
 	return nil
 }
 
+// Names of the access distributions of random accesses
+const (
+	ExponentialDistribution = "exponential"
+	UniformDistribution     = "uniform"
+	ZipfDistribution        = "zipf"
+	EmpiricalDistribution   = "empirical"
+)
+
 // EstimationStatsJSON is an estimated access statistics in JSON format.
 type EstimationStatsJSON struct {
 	NumKeys           int64     `json:"n"`
 	Lambda            float64   `json:"exponentialParameter"`
 	QueueDistribution []float64 `json:"queuingDistribution"`
+
+	// selected distribution of random accesses (exponential if empty),
+	// the exponent of the Zipf distribution and the empirical distribution
+	Distribution string       `json:"distribution,omitempty"`
+	Exponent     float64      `json:"zipfExponent,omitempty"`
+	ECdf         [][2]float64 `json:"ecdf,omitempty"`
 }
 
 // NewEstimationStats creates a new EstimationStatsJSON objects for an access statistics.
@@ -130,9 +176,80 @@ func NewEstimationStats(d *statistics.AccessJSON) EstimationStatsJSON {
 	distribution := make([]float64, len(d.Queuing.Distribution))
 	copy(distribution, d.Queuing.Distribution)
 
-	return EstimationStatsJSON{
+	stats := EstimationStatsJSON{
 		Lambda:            lambda,
 		NumKeys:           d.Counting.NumKeys,
 		QueueDistribution: distribution,
 	}
+
+	// fit alternative distributions and select the best fit
+	if len(d.Counting.ECdf) > 0 {
+		stats.Exponent, err = zipf.ApproximateExponent(d.Counting.ECdf, max(d.Counting.NumKeys, 1))
+		if err != nil {
+			log.Fatalf("failed to approximate zipf exponent; %v", err)
+		}
+		stats.ECdf = make([][2]float64, len(d.Counting.ECdf))
+		copy(stats.ECdf, d.Counting.ECdf)
+		stats.Distribution = selectDistribution(&d.Counting, &stats)
+	}
+	return stats
+}
+
+// Thresholds of the selection of access distributions
+const (
+	// likelihoodPenalty is the log-likelihood per access required for each parameter of a distribution
+	likelihoodPenalty = 0.01
+	// maxDivergence is the largest Kullback-Leibler divergence of a parametric distribution from
+	// the ECDF, the empirical distribution is selected if no parametric distribution is closer
+	maxDivergence = 0.05
+)
+
+// selectDistribution selects the parametric access distribution with the best likelihood
+// of the counting statistics penalized by the number of its parameters. The empirical
+// distribution always has the best likelihood of its own ECDF, hence it is not compared
+// by the likelihood, but selected only if the best parametric distribution diverges
+// from the ECDF by more than maxDivergence.
+func selectDistribution(d *statistics.CountingJSON, stats *EstimationStatsJSON) string {
+	n := max(d.NumKeys, 1)
+	candidates := []struct {
+		name   string
+		params int
+		mass   func(x0, x1 float64) float64
+	}{
+		{UniformDistribution, 0, func(x0, x1 float64) float64 { return x1 - x0 }},
+		{ExponentialDistribution, 1, func(x0, x1 float64) float64 { return exponential.Mass(stats.Lambda, x0, x1) }},
+		{ZipfDistribution, 1, func(x0, x1 float64) float64 { return zipf.Mass(stats.Exponent, n, x0, x1) }},
+	}
+	best, bestLl, bestScore := "", math.Inf(-1), math.Inf(-1)
+	for _, c := range candidates {
+		ll := statistics.LogLikelihood(d.ECdf, c.mass)
+		if score := ll - likelihoodPenalty*float64(c.params); score > bestScore {
+			best, bestLl, bestScore = c.name, ll, score
+		}
+	}
+
+	// the divergence is the difference of the log-likelihoods per access
+	ll := statistics.LogLikelihood(d.ECdf, func(x0, x1 float64) float64 {
+		return empirical.Cdf(d.ECdf, x1) - empirical.Cdf(d.ECdf, x0)
+	})
+	if best == "" || ll-bestLl > maxDivergence {
+		return EmpiricalDistribution
+	}
+	return best
+}
+
+// NewDistribution creates the generator of random accesses of an estimated access statistics.
+func (s *EstimationStatsJSON) NewDistribution() (generator.Distribution, error) {
+	switch s.Distribution {
+	case "", ExponentialDistribution:
+		return generator.Exponential{Lambda: s.Lambda}, nil
+	case UniformDistribution:
+		return generator.Uniform{}, nil
+	case ZipfDistribution:
+		return generator.NewZipf(s.Exponent)
+	case EmpiricalDistribution:
+		return generator.NewEmpirical(s.ECdf)
+	default:
+		return nil, fmt.Errorf("unknown distribution %v", s.Distribution)
+	}
 }
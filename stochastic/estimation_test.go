@@ -0,0 +1,121 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/stochastic/exponential"
+	"github.com/Fantom-foundation/Aida/stochastic/generator"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	"github.com/Fantom-foundation/Aida/stochastic/zipf"
+)
+
+// countingJSON produces counting statistics for a cumulative distribution function.
+func countingJSON(numKeys int64, cdf func(x float64) float64) statistics.AccessJSON {
+	ecdf := [][2]float64{}
+	for i := 0; i <= statistics.NumDistributionPoints; i++ {
+		x := float64(i) / statistics.NumDistributionPoints
+		ecdf = append(ecdf, [2]float64{x, cdf(x)})
+	}
+	return statistics.AccessJSON{
+		Counting: statistics.CountingJSON{NumKeys: numKeys, ECdf: ecdf},
+		Queuing:  statistics.QueuingJSON{Distribution: make([]float64, statistics.QueueLen)},
+	}
+}
+
+// TestSelectDistribution checks that the estimator selects the distribution
+// of the recorded accesses.
+func TestSelectDistribution(t *testing.T) {
+	tests := map[string]func(x float64) float64{
+		ExponentialDistribution: func(x float64) float64 { return exponential.Cdf(20, x) },
+		UniformDistribution:     func(x float64) float64 { return x },
+		ZipfDistribution:        func(x float64) float64 { return zipf.Cdf(1.2, 100000, x) },
+		EmpiricalDistribution: func(x float64) float64 {
+			if x < 0.5 {
+				return 1.8 * x
+			}
+			return 0.9 + 0.2*(x-0.5)
+		},
+	}
+	for want, cdf := range tests {
+		d := countingJSON(100000, cdf)
+		stats := NewEstimationStats(&d)
+		if stats.Distribution != want {
+			t.Errorf("selected %v distribution instead of %v", stats.Distribution, want)
+		}
+		if _, err := stats.NewDistribution(); err != nil {
+			t.Errorf("cannot create %v distribution; %v", want, err)
+		}
+	}
+}
+
+// sampledCountingJSON produces counting statistics of accesses sampled from a distribution of n keys.
+func sampledCountingJSON(n int64, sample func(rg *rand.Rand, n int64) int64) statistics.AccessJSON {
+	rg := rand.New(rand.NewSource(1))
+	counting := statistics.NewCounting[int64]()
+	for i := 0; i < 200_000; i++ {
+		counting.Place(sample(rg, n))
+	}
+	return statistics.AccessJSON{
+		Counting: counting.NewCountingJSON(),
+		Queuing:  statistics.QueuingJSON{Distribution: make([]float64, statistics.QueueLen)},
+	}
+}
+
+// TestSelectDistributionOfSampledAccesses checks that the estimator selects a parametric
+// distribution of sampled accesses rather than the empirical distribution of the samples.
+func TestSelectDistributionOfSampledAccesses(t *testing.T) {
+	tests := map[string]func(rg *rand.Rand, n int64) int64{
+		ExponentialDistribution: func(rg *rand.Rand, n int64) int64 { return exponential.DiscreteSample(rg, 5, n) },
+		ZipfDistribution:        func(rg *rand.Rand, n int64) int64 { return zipf.DiscreteSample(rg, 1.2, n) },
+		UniformDistribution:     func(rg *rand.Rand, n int64) int64 { return rg.Int63n(n) },
+	}
+	for want, sample := range tests {
+		d := sampledCountingJSON(1000, sample)
+		if got := NewEstimationStats(&d).Distribution; got != want {
+			t.Errorf("selected %v distribution instead of %v", got, want)
+		}
+	}
+}
+
+// TestNewDistribution checks the distributions selectable in a simulation file.
+func TestNewDistribution(t *testing.T) {
+	stats := EstimationStatsJSON{Lambda: 5, Exponent: 1.5, ECdf: [][2]float64{{0, 0}, {1, 1}}}
+	for name, want := range map[string]any{
+		"":                      generator.Exponential{Lambda: 5},
+		ExponentialDistribution: generator.Exponential{Lambda: 5},
+		UniformDistribution:     generator.Uniform{},
+		ZipfDistribution:        generator.Zipf{Exponent: 1.5},
+	} {
+		stats.Distribution = name
+		if got, err := stats.NewDistribution(); err != nil || got != want {
+			t.Errorf("unexpected distribution %v for %q; %v", got, name, err)
+		}
+	}
+	stats.Distribution = EmpiricalDistribution
+	if got, err := stats.NewDistribution(); err != nil {
+		t.Errorf("cannot create empirical distribution; %v", err)
+	} else if _, ok := got.(*generator.Empirical); !ok {
+		t.Errorf("unexpected distribution %T", got)
+	}
+	stats.Distribution = "unknown"
+	if _, err := stats.NewDistribution(); err == nil {
+		t.Errorf("unknown distribution must be rejected")
+	}
+}
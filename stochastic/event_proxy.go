@@ -59,6 +59,7 @@ func (p *EventProxy) CreateAccount(address common.Address) {
 func (p *EventProxy) SubBalance(address common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
 	// register event
 	p.registry.RegisterAddressOp(SubBalanceID, &address)
+	p.registry.RegisterBalance(amount)
 
 	// call real StateDB
 	p.db.SubBalance(address, amount, reason)
@@ -68,6 +69,7 @@ func (p *EventProxy) SubBalance(address common.Address, amount *uint256.Int, rea
 func (p *EventProxy) AddBalance(address common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
 	// register event
 	p.registry.RegisterAddressOp(AddBalanceID, &address)
+	p.registry.RegisterBalance(amount)
 
 	// call real StateDB
 	p.db.AddBalance(address, amount, reason)
@@ -95,6 +97,7 @@ func (p *EventProxy) GetNonce(address common.Address) uint64 {
 func (p *EventProxy) SetNonce(address common.Address, nonce uint64) {
 	// register event
 	p.registry.RegisterAddressOp(SetNonceID, &address)
+	p.registry.RegisterNonce(nonce)
 
 	// call real StateDB
 	p.db.SetNonce(address, nonce)
@@ -122,6 +125,7 @@ func (p *EventProxy) GetCode(address common.Address) []byte {
 func (p *EventProxy) SetCode(address common.Address, code []byte) {
 	// register event
 	p.registry.RegisterAddressOp(SetCodeID, &address)
+	p.registry.RegisterCodeSize(len(code))
 
 	// call real StateDB
 	p.db.SetCode(address, code)
@@ -187,7 +191,7 @@ func (p *EventProxy) SetTransientState(addr common.Address, key common.Hash, val
 
 func (p *EventProxy) GetTransientState(addr common.Address, key common.Hash) common.Hash {
 	p.registry.RegisterKeyOp(GetTransientStateID, &addr, &key)
-	return p.db.GetState(addr, key)
+	return p.db.GetTransientState(addr, key)
 }
 
 // SelfDestruct an account.
@@ -447,16 +451,16 @@ func (p *EventProxy) GetShadowDB() state.StateDB {
 }
 
 func (p *EventProxy) CreateContract(addr common.Address) {
-	p.registry.RegisterOp(CreateContractID)
+	p.registry.RegisterAddressOp(CreateContractID, &addr)
 	p.db.CreateContract(addr)
 }
 
 func (p *EventProxy) Selfdestruct6780(addr common.Address) {
-	p.registry.RegisterOp(SelfDestruct6780ID)
+	p.registry.RegisterAddressOp(SelfDestruct6780ID, &addr)
 	p.db.Selfdestruct6780(addr)
 }
 
 func (p *EventProxy) GetStorageRoot(addr common.Address) common.Hash {
-	p.registry.RegisterOp(CreateContractID)
+	p.registry.RegisterAddressOp(GetStorageRootID, &addr)
 	return p.db.GetStorageRoot(addr)
 }
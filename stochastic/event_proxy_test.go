@@ -0,0 +1,92 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/mock/gomock"
+)
+
+// TestEventProxyContractOperations checks that contract operations are registered
+// with their address argument and forwarded to the StateDB.
+func TestEventProxyContractOperations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	registry := NewEventRegistry()
+	proxy := NewEventProxy(db, &registry)
+
+	addr := common.HexToAddress("0x10")
+	root := common.HexToHash("0x20")
+	gomock.InOrder(
+		db.EXPECT().CreateContract(addr),
+		db.EXPECT().GetStorageRoot(addr).Return(root),
+		db.EXPECT().Selfdestruct6780(addr),
+	)
+
+	proxy.CreateContract(addr)
+	if got := proxy.GetStorageRoot(addr); got != root {
+		t.Errorf("unexpected storage root, got %v, want %v", got, root)
+	}
+	proxy.Selfdestruct6780(addr)
+
+	for _, argop := range []int{
+		EncodeArgOp(CreateContractID, statistics.NewValueID, statistics.NoArgID, statistics.NoArgID),
+		EncodeArgOp(GetStorageRootID, statistics.PreviousValueID, statistics.NoArgID, statistics.NoArgID),
+		EncodeArgOp(SelfDestruct6780ID, statistics.PreviousValueID, statistics.NoArgID, statistics.NoArgID),
+	} {
+		if registry.argOpFreq[argop] != 1 {
+			op, _, _, _ := DecodeArgOp(argop)
+			t.Errorf("operation %v was not registered with its address", opText[op])
+		}
+	}
+}
+
+// TestEventProxyTransientStorage checks that transient storage operations are
+// registered with their arguments and forwarded to the transient storage.
+func TestEventProxyTransientStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	registry := NewEventRegistry()
+	proxy := NewEventProxy(db, &registry)
+
+	addr := common.HexToAddress("0x10")
+	key := common.HexToHash("0x20")
+	value := common.HexToHash("0x30")
+	gomock.InOrder(
+		db.EXPECT().SetTransientState(addr, key, value),
+		db.EXPECT().GetTransientState(addr, key).Return(value),
+	)
+
+	proxy.SetTransientState(addr, key, value)
+	if got := proxy.GetTransientState(addr, key); got != value {
+		t.Errorf("unexpected transient value, got %v, want %v", got, value)
+	}
+
+	for _, argop := range []int{
+		EncodeArgOp(SetTransientStateID, statistics.NewValueID, statistics.NewValueID, statistics.NewValueID),
+		EncodeArgOp(GetTransientStateID, statistics.PreviousValueID, statistics.PreviousValueID, statistics.NoArgID),
+	} {
+		if registry.argOpFreq[argop] != 1 {
+			op, _, _, _ := DecodeArgOp(argop)
+			t.Errorf("operation %v was not registered with its arguments", opText[op])
+		}
+	}
+}
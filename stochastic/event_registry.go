@@ -21,9 +21,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/bits"
 	"os"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/simplify"
 
@@ -52,6 +55,23 @@ type EventRegistry struct {
 
 	// Snapshot deltas
 	snapshotFreq map[int]uint64
+
+	// Magnitudes of balance deltas, nonces, and code sizes
+	balances  statistics.Magnitude
+	nonces    statistics.Magnitude
+	codeSizes statistics.Magnitude
+
+	// Order of the Markov-Process, i.e., the number of preceding operations
+	// forming the context of an operation
+	order int
+
+	// Preceding argument-encoded operations (oldest first) and their number
+	history    markovContext
+	historyLen int
+
+	// Frequencies of argument-encoded operations following a context
+	// (only recorded for an order greater than one)
+	contextFreq map[markovContext]map[int]uint64
 }
 
 // NewEventRegistry creates a new event registry.
@@ -62,9 +82,26 @@ func NewEventRegistry() EventRegistry {
 		keys:         statistics.NewAccess[common.Hash](),
 		values:       statistics.NewAccess[common.Hash](),
 		snapshotFreq: map[int]uint64{},
+		balances:     statistics.NewMagnitude(256),
+		nonces:       statistics.NewMagnitude(64),
+		codeSizes:    statistics.NewMagnitude(bits.Len(MaxCodeSize)),
+		order:        1,
+		history:      newMarkovContext(),
+		contextFreq:  map[markovContext]map[int]uint64{},
 	}
 }
 
+// SetMarkovOrder sets the order of the Markov-Process. For an order greater
+// than one, the registry additionally counts the operations following each
+// context of preceding operations. It must be set before registering operations.
+func (r *EventRegistry) SetMarkovOrder(order int) error {
+	if order < 1 || order > MaxMarkovOrder {
+		return fmt.Errorf("markov order must be between 1 and %v", MaxMarkovOrder)
+	}
+	r.order = order
+	return nil
+}
+
 // RegisterOp registers an operation with no simulation arguments
 func (r *EventRegistry) RegisterOp(op int) {
 	if op < 0 || op >= NumOps {
@@ -157,6 +194,21 @@ func (r *EventRegistry) updateFreq(op int, addr int, key int, value int) {
 		r.transitFreq[r.prevArgOp][argOp] = r.transitFreq[r.prevArgOp][argOp] + 1
 	}
 	r.prevArgOp = argOp
+
+	// count operation after its context of preceding operations
+	if r.order > 1 {
+		if r.historyLen == r.order {
+			next, found := r.contextFreq[r.history]
+			if !found {
+				next = map[int]uint64{}
+				r.contextFreq[r.history] = next
+			}
+			next[argOp]++
+		} else {
+			r.historyLen++
+		}
+		r.history = r.history.push(argOp, r.order)
+	}
 }
 
 // RegisterSnapshotDelta counts the delta of a snapshot. The delta is
@@ -168,6 +220,21 @@ func (r *EventRegistry) RegisterSnapshotDelta(delta int) {
 	r.snapshotFreq[delta]++
 }
 
+// RegisterBalance counts the magnitude of a balance delta.
+func (r *EventRegistry) RegisterBalance(delta *uint256.Int) {
+	r.balances.Place(delta.BitLen())
+}
+
+// RegisterNonce counts the magnitude of a nonce.
+func (r *EventRegistry) RegisterNonce(nonce uint64) {
+	r.nonces.Place(bits.Len64(nonce))
+}
+
+// RegisterCodeSize counts the magnitude of a code size.
+func (r *EventRegistry) RegisterCodeSize(size int) {
+	r.codeSizes.Place(bits.Len(uint(size)))
+}
+
 // WriteJSON writes an event registry in JSON format.
 func (r *EventRegistry) WriteJSON(filename string) error {
 	f, fErr := os.Create(filename)
@@ -192,6 +259,9 @@ type EventRegistryJSON struct {
 	Operations       []string    `json:"operations"`       // name of operations with argument classes
 	StochasticMatrix [][]float64 `json:"stochasticMatrix"` // observed stochastic matrix
 
+	// transition frequencies between operations (absent in older event files)
+	TransitionFreq [][]uint64 `json:"transitionFrequencies,omitempty"`
+
 	// access statistics for contracts, keys, and values
 	Contracts statistics.AccessJSON `json:"contractStats"`
 	Keys      statistics.AccessJSON `json:"keyStats"`
@@ -199,6 +269,20 @@ type EventRegistryJSON struct {
 
 	// snapshot delta frequencies
 	SnapshotEcdf [][2]float64 `json:"snapshotEcdf"`
+
+	// magnitudes of balance deltas, nonces, and code sizes
+	Balances  statistics.MagnitudeJSON `json:"balanceStats"`
+	Nonces    statistics.MagnitudeJSON `json:"nonceStats"`
+	CodeSizes statistics.MagnitudeJSON `json:"codeSizeStats"`
+
+	// markov order and frequencies of operations following a context
+	Order    int           `json:"order,omitempty"`
+	Contexts []ContextJSON `json:"contexts,omitempty"`
+
+	// number of blocks per phase and events of the phases
+	// (only recorded for a time-varying model)
+	PhaseLength uint64              `json:"phaseLength,omitempty"`
+	Phases      []EventRegistryJSON `json:"phases,omitempty"`
 }
 
 // NewEventRegistry produces the JSON output for an event registry.
@@ -213,23 +297,17 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 		}
 	}
 
-	// Compute stochastic matrix for observable operations with their arguments
-	A := [][]float64{}
+	// Collect transition frequencies of observable operations with their arguments
+	freq := [][]uint64{}
 	for i := 0; i < numArgOps; i++ {
 		if r.argOpFreq[i] > 0 {
-			row := []float64{}
-			// find row total of row (i.e. state i)
-			total := uint64(0)
-			for j := 0; j < numArgOps; j++ {
-				total += r.transitFreq[i][j]
-			}
-			// normalize row
+			row := []uint64{}
 			for j := 0; j < numArgOps; j++ {
 				if r.argOpFreq[j] > 0 {
-					row = append(row, float64(r.transitFreq[i][j])/float64(total))
+					row = append(row, r.transitFreq[i][j])
 				}
 			}
-			A = append(A, row)
+			freq = append(freq, row)
 		}
 	}
 
@@ -295,12 +373,80 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 	return EventRegistryJSON{
 		FileId:           "events",
 		Operations:       label,
-		StochasticMatrix: A,
+		StochasticMatrix: newStochasticMatrix(freq),
+		TransitionFreq:   freq,
 		Contracts:        r.contracts.NewAccessJSON(),
 		Keys:             r.keys.NewAccessJSON(),
 		Values:           r.values.NewAccessJSON(),
 		SnapshotEcdf:     eCdf,
+		Balances:         r.balances.NewMagnitudeJSON(),
+		Nonces:           r.nonces.NewMagnitudeJSON(),
+		CodeSizes:        r.codeSizes.NewMagnitudeJSON(),
+		Order:            r.order,
+		Contexts:         newContextsJSON(r.contextFreq, r.order),
+	}
+}
+
+// newStochasticMatrix computes the stochastic matrix from transition frequencies.
+func newStochasticMatrix(freq [][]uint64) [][]float64 {
+	A := [][]float64{}
+	for i := range freq {
+		row := []float64{}
+		// find row total of row (i.e. state i)
+		total := uint64(0)
+		for j := range freq[i] {
+			total += freq[i][j]
+		}
+		// normalize row
+		for j := range freq[i] {
+			row = append(row, float64(freq[i][j])/float64(total))
+		}
+		A = append(A, row)
+	}
+	return A
+}
+
+// newContextsJSON produces the JSON output of the context frequencies sorted
+// by context for a reproducible output.
+func newContextsJSON(contextFreq map[markovContext]map[int]uint64, order int) []ContextJSON {
+	if len(contextFreq) == 0 {
+		return nil
+	}
+	label := func(argop int) string {
+		op, addr, key, value := DecodeArgOp(argop)
+		return EncodeOpcode(op, addr, key, value)
+	}
+	contexts := make([]markovContext, 0, len(contextFreq))
+	for context := range contextFreq {
+		contexts = append(contexts, context)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		for k := 0; k < order; k++ {
+			if contexts[i][k] != contexts[j][k] {
+				return contexts[i][k] < contexts[j][k]
+			}
+		}
+		return false
+	})
+
+	res := make([]ContextJSON, 0, len(contexts))
+	for _, context := range contexts {
+		c := ContextJSON{}
+		for k := 0; k < order; k++ {
+			c.Context = append(c.Context, label(context[k]))
+		}
+		next := make([]int, 0, len(contextFreq[context]))
+		for argop := range contextFreq[context] {
+			next = append(next, argop)
+		}
+		sort.Ints(next)
+		for _, argop := range next {
+			c.Next = append(c.Next, label(argop))
+			c.Freq = append(c.Freq, contextFreq[context][argop])
+		}
+		res = append(res, c)
 	}
+	return res
 }
 
 // ReadEventsJSON reads event file in JSON format.
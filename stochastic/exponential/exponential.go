@@ -36,6 +36,11 @@ func Cdf(lambda float64, x float64) float64 {
 	return (math.Exp(-lambda*x) - 1.0) / (math.Exp(-lambda) - 1.0)
 }
 
+// Mass is the probability of the truncated exponential distribution between x0 and x1.
+func Mass(lambda float64, x0 float64, x1 float64) float64 {
+	return (math.Exp(-lambda*x0) - math.Exp(-lambda*x1)) / -math.Expm1(-lambda)
+}
+
 // PiecewiseLinearCdf is a piecewise linear representation of the cumulative distribution function.
 func PiecewiseLinearCdf(lambda float64, n int) [][2]float64 {
 	// The points are equi-distantly spread, i.e., 1/n.
@@ -0,0 +1,90 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/Fantom-foundation/Aida/stochastic/empirical"
+	"github.com/Fantom-foundation/Aida/stochastic/exponential"
+	"github.com/Fantom-foundation/Aida/stochastic/zipf"
+)
+
+// Distribution samples random indexes of an index set for random accesses.
+type Distribution interface {
+	// Sample returns a random index in the range between 0 and n-1.
+	Sample(rg *rand.Rand, n int64) int64
+}
+
+// Exponential is a truncated exponential distribution of indexes.
+type Exponential struct {
+	Lambda float64 // lambda parameter of the exponential distribution
+}
+
+// Sample returns a random index of an exponential distribution.
+func (d Exponential) Sample(rg *rand.Rand, n int64) int64 {
+	return exponential.DiscreteSample(rg, d.Lambda, n)
+}
+
+// Uniform is a uniform distribution of indexes.
+type Uniform struct{}
+
+// Sample returns a uniformly distributed random index.
+func (d Uniform) Sample(rg *rand.Rand, n int64) int64 {
+	return rg.Int63n(n)
+}
+
+// Zipf is a Zipf distribution of indexes, i.e., indexes are ranks whose
+// probability follows a power law.
+type Zipf struct {
+	Exponent float64 // exponent of the power law
+}
+
+// NewZipf creates a Zipf distribution of indexes.
+func NewZipf(exponent float64) (Zipf, error) {
+	if exponent < 0 {
+		return Zipf{}, fmt.Errorf("exponent of Zipf distribution must not be negative")
+	}
+	return Zipf{exponent}, nil
+}
+
+// Sample returns a random index of a Zipf distribution.
+func (d Zipf) Sample(rg *rand.Rand, n int64) int64 {
+	return zipf.DiscreteSample(rg, d.Exponent, n)
+}
+
+// Empirical is a distribution of indexes given by a piecewise linear
+// cumulative distribution function of normalized indexes.
+type Empirical struct {
+	ecdf [][2]float64
+}
+
+// NewEmpirical creates an empirical distribution of indexes.
+func NewEmpirical(ecdf [][2]float64) (*Empirical, error) {
+	if err := empirical.Check(ecdf); err != nil {
+		return nil, err
+	}
+	points := make([][2]float64, len(ecdf))
+	copy(points, ecdf)
+	return &Empirical{points}, nil
+}
+
+// Sample returns a random index of an empirical distribution.
+func (d *Empirical) Sample(rg *rand.Rand, n int64) int64 {
+	return empirical.DiscreteSample(rg, d.ecdf, n)
+}
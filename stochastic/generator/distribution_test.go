@@ -0,0 +1,74 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+)
+
+// TestDistributionRange checks that all distributions sample indexes in range.
+func TestDistributionRange(t *testing.T) {
+	rg := rand.New(rand.NewSource(999))
+	empirical, err := NewEmpirical([][2]float64{{0, 0}, {0.2, 0.9}, {1, 1}})
+	if err != nil {
+		t.Fatalf("cannot create empirical distribution; %v", err)
+	}
+	zipf, err := NewZipf(1.2)
+	if err != nil {
+		t.Fatalf("cannot create Zipf distribution; %v", err)
+	}
+	for _, dist := range []Distribution{Exponential{5.0}, Uniform{}, zipf, empirical} {
+		for i := 0; i < 10000; i++ {
+			if v := dist.Sample(rg, 100); v < 0 || v >= 100 {
+				t.Fatalf("%T sampled index %v out of range", dist, v)
+			}
+		}
+	}
+}
+
+// TestDistributionInvalid checks that invalid parameters are rejected.
+func TestDistributionInvalid(t *testing.T) {
+	if _, err := NewZipf(-1); err == nil {
+		t.Errorf("negative exponent must be rejected")
+	}
+	if _, err := NewEmpirical([][2]float64{{0, 0}, {1, 0.5}}); err == nil {
+		t.Errorf("incomplete empirical distribution must be rejected")
+	}
+}
+
+// TestDistributedRandomAccess checks random accesses with a Zipf distribution.
+func TestDistributedRandomAccess(t *testing.T) {
+	rg := rand.New(rand.NewSource(999))
+	qpdf := make([]float64, statistics.QueueLen)
+	ra := NewDistributedRandomAccess(rg, 1000, Zipf{2.0}, qpdf)
+	low := 0
+	for i := 0; i < 1000; i++ {
+		idx := ra.NextIndex(statistics.RandomValueID)
+		if idx < 1 || idx > ra.numElem {
+			t.Fatalf("index %v out of range", idx)
+		}
+		if idx <= 100 {
+			low++
+		}
+	}
+	if low < 800 {
+		t.Errorf("Zipf distribution must favour low indexes; only %v of 1000 below 100", low)
+	}
+}
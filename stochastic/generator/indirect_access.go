@@ -88,6 +88,11 @@ func (a *IndirectAccess) DeleteIndex(k int64) error {
 	return nil
 }
 
+// SetDistribution replaces the access distribution.
+func (a *IndirectAccess) SetDistribution(dist Distribution, qpdf []float64) {
+	a.randAcc.SetDistribution(dist, qpdf)
+}
+
 // findIndex finds the index in the translation table for a given index k.
 func (a *IndirectAccess) findIndex(k int64) int64 {
 	for i := int64(0); i < int64(len(a.translation)); i++ {
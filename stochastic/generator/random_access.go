@@ -21,7 +21,6 @@ import (
 	"math"
 	"math/rand"
 
-	"github.com/Fantom-foundation/Aida/stochastic/exponential"
 	"github.com/Fantom-foundation/Aida/stochastic/statistics"
 )
 
@@ -35,8 +34,8 @@ type RandomAccess struct {
 	// cardinality of set
 	numElem int64
 
-	// lambda parameter of exponential distribution
-	lambda float64
+	// distribution of random indexes
+	dist Distribution
 
 	// queue for indexes (always fixed to length qStatslen)
 	// Note that elements in queue are stored in the range from 0 to numElem-1
@@ -50,8 +49,13 @@ type RandomAccess struct {
 	rg *rand.Rand
 }
 
-// NewAccess creates a new access index.
+// NewAccess creates a new access index with an exponential distribution of random indexes.
 func NewRandomAccess(rg *rand.Rand, numElem int64, lambda float64, qpdf []float64) *RandomAccess {
+	return NewDistributedRandomAccess(rg, numElem, Exponential{lambda}, qpdf)
+}
+
+// NewDistributedRandomAccess creates a new access index with the given distribution of random indexes.
+func NewDistributedRandomAccess(rg *rand.Rand, numElem int64, dist Distribution, qpdf []float64) *RandomAccess {
 	if numElem < minRandomAccessSize {
 		return nil
 	}
@@ -68,7 +72,7 @@ func NewRandomAccess(rg *rand.Rand, numElem int64, lambda float64, qpdf []float6
 
 	return &RandomAccess{
 		numElem: numElem,
-		lambda:  lambda,
+		dist:    dist,
 		queue:   queue,
 		qpdf:    copyQpdf,
 		rg:      rg,
@@ -101,7 +105,7 @@ func (a *RandomAccess) NextIndex(class int) int64 {
 	case statistics.RandomValueID:
 		// use randomised value that is not contained in the queue
 		for {
-			v := exponential.DiscreteSample(a.rg, a.lambda, a.numElem)
+			v := a.dist.Sample(a.rg, a.numElem)
 			if !a.findQElem(v) {
 				a.placeQ(v)
 				return v + 1
@@ -145,7 +149,7 @@ func (a *RandomAccess) DeleteIndex(v int64) error {
 	// in range, but there might elements in the queue
 	// that exceed the new range limit. They need to
 	// be replaced.
-	j := exponential.DiscreteSample(a.rg, a.lambda, a.numElem)
+	j := a.dist.Sample(a.rg, a.numElem)
 	for i := 0; i < statistics.QueueLen; i++ {
 		if a.queue[i] >= a.numElem {
 			a.queue[i] = j
@@ -155,6 +159,14 @@ func (a *RandomAccess) DeleteIndex(v int64) error {
 	return nil
 }
 
+// SetDistribution replaces the access distribution while keeping the index
+// set and the queue of recently accessed indexes.
+func (a *RandomAccess) SetDistribution(dist Distribution, qpdf []float64) {
+	a.dist = dist
+	a.qpdf = make([]float64, statistics.QueueLen)
+	copy(a.qpdf, qpdf)
+}
+
 // findQElem finds an element in the queue.
 func (a *RandomAccess) findQElem(elem int64) bool {
 	for i := 0; i < statistics.QueueLen; i++ {
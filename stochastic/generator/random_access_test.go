@@ -280,3 +280,32 @@ func TestRandomAccessQueue(t *testing.T) {
 		t.Fatalf("wrong randomized value")
 	}
 }
+
+// TestRandomAccessSetDistribution tests replacing the access distribution.
+func TestRandomAccessSetDistribution(t *testing.T) {
+	// create random generator with fixed seed value
+	rg := rand.New(rand.NewSource(999))
+
+	qpdf := make([]float64, statistics.QueueLen)
+	ra := NewRandomAccess(rg, 1000, 5.0, qpdf)
+	queue := append([]int64{}, ra.queue...)
+
+	qpdf[1] = 1.0
+	ra.SetDistribution(Uniform{}, qpdf[:2])
+	if _, ok := ra.dist.(Uniform); !ok || ra.numElem != 1000 {
+		t.Fatalf("unexpected parameters after replacing distribution")
+	}
+	if len(ra.qpdf) != statistics.QueueLen || ra.qpdf[1] != 1.0 {
+		t.Fatalf("unexpected queue distribution %v", ra.qpdf)
+	}
+	for i := range queue {
+		if ra.queue[i] != queue[i] {
+			t.Fatalf("queue must be kept when replacing distribution")
+		}
+	}
+	// the distribution must be copied
+	qpdf[1] = 0.0
+	if ra.qpdf[1] != 1.0 {
+		t.Fatalf("queue distribution is not copied")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/holiman/uint256"
+)
+
+// magnitudeSampler samples values from a distribution of bit lengths. Values
+// are uniformly distributed within a bit length.
+type magnitudeSampler struct {
+	cdf []float64 // cumulative probabilities of the bit lengths
+}
+
+// newMagnitudeSampler creates a sampler for the probabilities of bit lengths
+// up to maxBits. It returns nil if no distribution is given.
+func newMagnitudeSampler(dist []float64, maxBits int) (*magnitudeSampler, error) {
+	if len(dist) == 0 {
+		return nil, nil
+	}
+	if len(dist) > maxBits+1 {
+		return nil, fmt.Errorf("distribution exceeds %v bits", maxBits)
+	}
+	cdf := make([]float64, len(dist))
+	sum := 0.0
+	for i, p := range dist {
+		if p < 0 {
+			return nil, fmt.Errorf("negative probability for bit length %v", i)
+		}
+		sum += p
+		cdf[i] = sum
+	}
+	if sum <= 0 {
+		return nil, fmt.Errorf("distribution has no probability mass")
+	}
+	return &magnitudeSampler{cdf: cdf}, nil
+}
+
+// sampleBitLen samples a bit length.
+func (s *magnitudeSampler) sampleBitLen(rg *rand.Rand) int {
+	r := rg.Float64() * s.cdf[len(s.cdf)-1]
+	for i, p := range s.cdf {
+		if r < p {
+			return i
+		}
+	}
+	return len(s.cdf) - 1
+}
+
+// sample samples a 256-bit value.
+func (s *magnitudeSampler) sample(rg *rand.Rand) *uint256.Int {
+	bitLen := s.sampleBitLen(rg)
+	v := new(uint256.Int)
+	if bitLen == 0 {
+		return v
+	}
+	v[0], v[1], v[2], v[3] = rg.Uint64(), rg.Uint64(), rg.Uint64(), rg.Uint64()
+	v.Rsh(v, uint(256-bitLen))
+	top := new(uint256.Int).Lsh(uint256.NewInt(1), uint(bitLen-1))
+	return v.Or(v, top)
+}
+
+// sampleUint64 samples a value of at most 64 bits.
+func (s *magnitudeSampler) sampleUint64(rg *rand.Rand) uint64 {
+	bitLen := min(s.sampleBitLen(rg), 64)
+	if bitLen == 0 {
+		return 0
+	}
+	return rg.Uint64()>>(64-bitLen) | 1<<(bitLen-1)
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// TestMagnitudeSamplerBitLength checks that sampled values have the sampled bit length.
+func TestMagnitudeSamplerBitLength(t *testing.T) {
+	rg := rand.New(rand.NewSource(1))
+	for _, bitLen := range []int{0, 1, 3, 64, 200, 256} {
+		dist := make([]float64, 257)
+		dist[bitLen] = 1
+		s, err := newMagnitudeSampler(dist, 256)
+		if err != nil {
+			t.Fatalf("cannot create sampler; %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if got := s.sample(rg).BitLen(); got != bitLen {
+				t.Fatalf("unexpected bit length %v, want %v", got, bitLen)
+			}
+			if want := min(bitLen, 64); uint256.NewInt(s.sampleUint64(rg)).BitLen() != want {
+				t.Fatalf("unexpected bit length of 64-bit value, want %v", want)
+			}
+		}
+	}
+}
+
+// TestMagnitudeSamplerDistribution checks that bit lengths follow the distribution.
+func TestMagnitudeSamplerDistribution(t *testing.T) {
+	rg := rand.New(rand.NewSource(1))
+	s, err := newMagnitudeSampler([]float64{0.25, 0, 0.75}, 8)
+	if err != nil {
+		t.Fatalf("cannot create sampler; %v", err)
+	}
+	counts := make([]int, 3)
+	n := 100000
+	for i := 0; i < n; i++ {
+		counts[s.sampleBitLen(rg)]++
+	}
+	if counts[1] != 0 {
+		t.Errorf("bit length without probability was sampled")
+	}
+	if p := float64(counts[2]) / float64(n); p < 0.74 || p > 0.76 {
+		t.Errorf("unexpected probability %v of bit length 2", p)
+	}
+}
+
+// TestNewMagnitudeSampler checks the construction of samplers.
+func TestNewMagnitudeSampler(t *testing.T) {
+	if s, err := newMagnitudeSampler(nil, 64); s != nil || err != nil {
+		t.Errorf("missing distribution must not create a sampler")
+	}
+	if _, err := newMagnitudeSampler(make([]float64, 66), 64); err == nil {
+		t.Errorf("too long distribution must be rejected")
+	}
+	if _, err := newMagnitudeSampler([]float64{0, 0}, 64); err == nil {
+		t.Errorf("distribution without mass must be rejected")
+	}
+	if _, err := newMagnitudeSampler([]float64{-1, 2}, 64); err == nil {
+		t.Errorf("negative probabilities must be rejected")
+	}
+}
+
+// TestEventRegistryMagnitudes checks that balances, nonces and code sizes are
+// counted and estimated.
+func TestEventRegistryMagnitudes(t *testing.T) {
+	r := NewEventRegistry()
+	r.RegisterBalance(uint256.NewInt(1000))
+	r.RegisterBalance(new(uint256.Int).Lsh(uint256.NewInt(1), 100))
+	r.RegisterNonce(5)
+	r.RegisterCodeSize(MaxCodeSize)
+
+	events := r.NewEventRegistryJSON()
+	if events.Balances.Freq[10] != 1 || events.Balances.Freq[101] != 1 {
+		t.Errorf("unexpected balance frequencies %v", events.Balances.Freq)
+	}
+	if events.Nonces.Freq[3] != 1 {
+		t.Errorf("unexpected nonce frequencies %v", events.Nonces.Freq)
+	}
+	if events.CodeSizes.Freq[15] != 1 {
+		t.Errorf("unexpected code-size frequencies %v", events.CodeSizes.Freq)
+	}
+	if dist := events.Balances.Distribution(); dist[10] != 0.5 || dist[101] != 0.5 {
+		t.Errorf("unexpected balance distribution %v", dist)
+	}
+}
+
+// TestSampleBalanceDeltaIsClamped checks that sampled deltas never exceed the balance.
+func TestSampleBalanceDeltaIsClamped(t *testing.T) {
+	dist := make([]float64, 257)
+	dist[200] = 1
+	s, err := newMagnitudeSampler(dist, 256)
+	if err != nil {
+		t.Fatalf("cannot create sampler; %v", err)
+	}
+	ss := &stochasticState{rg: rand.New(rand.NewSource(1)), balances: s}
+
+	balance := uint256.NewInt(1000)
+	for i := 0; i < 100; i++ {
+		if got := ss.sampleBalanceDelta(balance); got == nil || !got.Eq(balance) {
+			t.Fatalf("unexpected delta %v, want %v", got, balance)
+		}
+	}
+	if got := ss.sampleBalanceDelta(uint256.NewInt(0)); got != nil {
+		t.Errorf("unexpected delta %v of zero balance", got)
+	}
+}
+
+// TestSampleCodeSizeIsPositive checks that sampled code has at least one byte.
+func TestSampleCodeSizeIsPositive(t *testing.T) {
+	s, err := newMagnitudeSampler([]float64{1}, 0)
+	if err != nil {
+		t.Fatalf("cannot create sampler; %v", err)
+	}
+	ss := &stochasticState{rg: rand.New(rand.NewSource(1)), codeSizes: s}
+	for i := 0; i < 100; i++ {
+		if got := ss.sampleCodeSize(); got != 1 {
+			t.Fatalf("unexpected code size %v", got)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// MaxMarkovOrder is the highest supported order of the Markovian process.
+const MaxMarkovOrder = 4
+
+// markovContext holds the preceding states/operations of a Markovian process
+// of higher order (oldest first). Unused entries are set to -1.
+type markovContext [MaxMarkovOrder]int
+
+// newMarkovContext creates an empty context.
+func newMarkovContext() markovContext {
+	var c markovContext
+	for i := range c {
+		c[i] = -1
+	}
+	return c
+}
+
+// push appends a state to a context of the given order dropping the oldest state.
+func (c markovContext) push(x int, order int) markovContext {
+	copy(c[:order-1], c[1:order])
+	c[order-1] = x
+	return c
+}
+
+// ContextJSON counts the operations following a context of preceding
+// operations in an event file.
+type ContextJSON struct {
+	Context []string `json:"context"` // preceding operations (oldest first)
+	Next    []string `json:"next"`    // operations following the context
+	Freq    []uint64 `json:"freq"`    // frequencies of the following operations
+}
+
+// ContextModelJSON contains the transition probabilities after a context of
+// preceding states in a simulation file. States are indices of operations.
+type ContextModelJSON struct {
+	Context       []int     `json:"context"`       // preceding states (oldest first)
+	Next          []int     `json:"next"`          // states following the context
+	Probabilities []float64 `json:"probabilities"` // transition probabilities of the following states
+}
+
+// estimateContexts converts the context frequencies of an event file to
+// transition probabilities between states.
+func estimateContexts(d *EventRegistryJSON) []ContextModelJSON {
+	states := map[string]int{}
+	for i, label := range d.Operations {
+		states[label] = i
+	}
+	toStates := func(labels []string) ([]int, bool) {
+		res := make([]int, len(labels))
+		for i, label := range labels {
+			state, found := states[label]
+			if !found {
+				return nil, false
+			}
+			res[i] = state
+		}
+		return res, true
+	}
+
+	contexts := []ContextModelJSON{}
+	for _, c := range d.Contexts {
+		context, ok1 := toStates(c.Context)
+		next, ok2 := toStates(c.Next)
+		if !ok1 || !ok2 || len(c.Freq) != len(next) {
+			continue
+		}
+		total := uint64(0)
+		for _, freq := range c.Freq {
+			total += freq
+		}
+		if total == 0 {
+			continue
+		}
+		probabilities := make([]float64, len(c.Freq))
+		for i, freq := range c.Freq {
+			probabilities[i] = float64(freq) / float64(total)
+		}
+		contexts = append(contexts, ContextModelJSON{
+			Context:       context,
+			Next:          next,
+			Probabilities: probabilities,
+		})
+	}
+	return contexts
+}
+
+// contextTransitions are the transitions following a context.
+type contextTransitions struct {
+	next []int     // following states
+	cdf  []float64 // cumulative transition probabilities
+}
+
+// markovModel samples the next state of the Markovian process. For models of
+// higher order, the next state is sampled from the transitions observed after
+// the preceding states. If a context has not been observed, the model falls
+// back to the first-order stochastic matrix.
+type markovModel struct {
+	A          [][]float64                          // first-order stochastic matrix
+	order      int                                  // order of the Markovian process
+	contexts   map[markovContext]contextTransitions // transitions of observed contexts
+	history    markovContext                        // preceding states
+	historyLen int                                  // number of preceding states
+}
+
+// newMarkovModel creates a Markovian process for a simulation model.
+func newMarkovModel(e *EstimationModelJSON) (*markovModel, error) {
+	order := max(e.Order, 1)
+	if order > MaxMarkovOrder {
+		return nil, fmt.Errorf("markov order %v exceeds maximum order %v", order, MaxMarkovOrder)
+	}
+	m := &markovModel{
+		A:        e.StochasticMatrix,
+		order:    order,
+		contexts: map[markovContext]contextTransitions{},
+		history:  newMarkovContext(),
+	}
+	numStates := len(e.Operations)
+	valid := func(state int) bool { return state >= 0 && state < numStates }
+	for i, c := range e.Contexts {
+		if len(c.Context) != order || len(c.Next) != len(c.Probabilities) {
+			return nil, fmt.Errorf("context %v does not match markov order %v", i, order)
+		}
+		key := newMarkovContext()
+		for j, state := range c.Context {
+			if !valid(state) {
+				return nil, fmt.Errorf("context %v has invalid state %v", i, state)
+			}
+			key[j] = state
+		}
+		t := contextTransitions{next: make([]int, len(c.Next)), cdf: make([]float64, len(c.Next))}
+		sum := 0.0
+		for j, state := range c.Next {
+			if !valid(state) {
+				return nil, fmt.Errorf("context %v has invalid next state %v", i, state)
+			}
+			sum += c.Probabilities[j]
+			t.next[j] = state
+			t.cdf[j] = sum
+		}
+		m.contexts[key] = t
+	}
+	return m, nil
+}
+
+// next produces the next state of the Markovian process after the given state.
+func (m *markovModel) next(rg *rand.Rand, state int) int {
+	if m.order > 1 {
+		m.history = m.history.push(state, m.order)
+		if m.historyLen < m.order {
+			m.historyLen++
+		}
+		if t, found := m.contexts[m.history]; found && m.historyLen == m.order {
+			r := rg.Float64()
+			for i, p := range t.cdf {
+				if r <= p {
+					return t.next[i]
+				}
+			}
+			return t.next[len(t.next)-1]
+		}
+	}
+	return nextState(rg, m.A, state)
+}
+
+// SampleStates samples a sequence of states of the Markovian process of a
+// simulation model starting with the BeginSyncPeriod state.
+func SampleStates(e *EstimationModelJSON, steps int, rg *rand.Rand) ([]int, error) {
+	m, err := newMarkovModel(e)
+	if err != nil {
+		return nil, err
+	}
+	_, _, state := getStochasticMatrix(e)
+	states := make([]int, 0, steps)
+	for i := 0; i < steps; i++ {
+		states = append(states, state)
+		if state = m.next(rg, state); state == -1 {
+			return nil, fmt.Errorf("stochastic matrix is broken in step %v", i)
+		}
+	}
+	return states, nil
+}
+
+// CountNGrams computes the relative frequencies of the n-grams of operations
+// in a sequence of states.
+func CountNGrams(operations []string, states []int, n int) map[string]float64 {
+	freq := map[string]float64{}
+	total := 0
+	for i := 0; i+n <= len(states); i++ {
+		labels := make([]string, n)
+		for j := 0; j < n; j++ {
+			labels[j] = operations[states[i+j]]
+		}
+		freq[strings.Join(labels, " ")]++
+		total++
+	}
+	for gram := range freq {
+		freq[gram] /= float64(total)
+	}
+	return freq
+}
+
+// RecordedNGrams computes the relative frequencies of the recorded n-grams of
+// operations from the contexts of an event file. The event file must be
+// recorded with a markov order of at least n-1.
+func RecordedNGrams(d *EventRegistryJSON, n int) (map[string]float64, error) {
+	if n < 1 || n > d.Order+1 || len(d.Contexts) == 0 {
+		return nil, fmt.Errorf("%v-grams require events recorded with a markov order of at least %v", n, n-1)
+	}
+	freq := map[string]float64{}
+	total := uint64(0)
+	for i, c := range d.Contexts {
+		if len(c.Context) != d.Order || len(c.Next) != len(c.Freq) {
+			return nil, fmt.Errorf("context %v does not match markov order %v", i, d.Order)
+		}
+		prefix := c.Context[len(c.Context)-(n-1):]
+		for i, next := range c.Next {
+			labels := append(append([]string{}, prefix...), next)
+			freq[strings.Join(labels, " ")] += float64(c.Freq[i])
+			total += c.Freq[i]
+		}
+	}
+	for gram := range freq {
+		freq[gram] /= float64(total)
+	}
+	return freq, nil
+}
+
+// NGramDistance computes the total variation distance between two n-gram
+// distributions, i.e., a value between zero (equal) and one (disjoint).
+func NGramDistance(p, q map[string]float64) float64 {
+	grams := map[string]struct{}{}
+	for gram := range p {
+		grams[gram] = struct{}{}
+	}
+	for gram := range q {
+		grams[gram] = struct{}{}
+	}
+	keys := make([]string, 0, len(grams))
+	for gram := range grams {
+		keys = append(keys, gram)
+	}
+	// sum in a fixed order for reproducible results
+	sort.Strings(keys)
+	d := 0.0
+	for _, gram := range keys {
+		d += math.Abs(p[gram] - q[gram])
+	}
+	return d / 2
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// registerSequence registers a sequence of operations without arguments.
+func registerSequence(r *EventRegistry, ops []int, repeat int) {
+	for i := 0; i < repeat; i++ {
+		for _, op := range ops {
+			r.RegisterOp(op)
+		}
+	}
+}
+
+// TestEventRegistryContexts checks that contexts of preceding operations are counted.
+func TestEventRegistryContexts(t *testing.T) {
+	r := NewEventRegistry()
+	if err := r.SetMarkovOrder(2); err != nil {
+		t.Fatal(err)
+	}
+	registerSequence(&r, []int{BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID}, 2)
+
+	got := r.NewEventRegistryJSON()
+	if got.Order != 2 {
+		t.Fatalf("unexpected order %v", got.Order)
+	}
+	want := []ContextJSON{
+		{Context: []string{"BB", "EB"}, Next: []string{"ES"}, Freq: []uint64{2}},
+		{Context: []string{"BS", "BB"}, Next: []string{"EB"}, Freq: []uint64{2}},
+		{Context: []string{"EB", "ES"}, Next: []string{"BS"}, Freq: []uint64{1}},
+		{Context: []string{"ES", "BS"}, Next: []string{"BB"}, Freq: []uint64{1}},
+	}
+	if !reflect.DeepEqual(got.Contexts, want) {
+		t.Errorf("unexpected contexts\ngot:  %v\nwant: %v", got.Contexts, want)
+	}
+}
+
+// TestEventRegistryNoContexts checks that a first-order registry does not record contexts.
+func TestEventRegistryNoContexts(t *testing.T) {
+	r := NewEventRegistry()
+	registerSequence(&r, []int{BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID}, 2)
+	if contexts := r.NewEventRegistryJSON().Contexts; contexts != nil {
+		t.Errorf("unexpected contexts %v", contexts)
+	}
+	if err := r.SetMarkovOrder(MaxMarkovOrder + 1); err == nil {
+		t.Errorf("invalid markov order must be rejected")
+	}
+}
+
+// TestMarkovModelHigherOrder checks that a higher-order model reproduces a
+// pattern that cannot be expressed by a first-order model.
+func TestMarkovModelHigherOrder(t *testing.T) {
+	// after BS BB, the sequence alternates between one and two transactions
+	pattern := []int{
+		BeginSyncPeriodID, BeginBlockID,
+		BeginTransactionID, EndTransactionID, EndBlockID,
+		BeginBlockID,
+		BeginTransactionID, EndTransactionID, BeginTransactionID, EndTransactionID, EndBlockID,
+		EndSyncPeriodID,
+	}
+	r := NewEventRegistry()
+	if err := r.SetMarkovOrder(4); err != nil {
+		t.Fatal(err)
+	}
+	registerSequence(&r, pattern, 100)
+	events := r.NewEventRegistryJSON()
+	e := EstimationModelJSON{
+		FileId:           "simulation",
+		Operations:       events.Operations,
+		StochasticMatrix: events.StochasticMatrix,
+		Order:            events.Order,
+		Contexts:         estimateContexts(&events),
+	}
+
+	steps := 100 * len(pattern)
+	states, err := SampleStates(&e, steps, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatalf("cannot sample states; %v", err)
+	}
+	for i, state := range states {
+		if want := OpMnemo(pattern[i%len(pattern)]); e.Operations[state] != want {
+			t.Fatalf("unexpected operation %v at step %v, want %v", e.Operations[state], i, want)
+		}
+	}
+
+	// the higher-order model reproduces the recorded 5-grams exactly
+	recorded, err := RecordedNGrams(&events, 5)
+	if err != nil {
+		t.Fatalf("cannot compute recorded n-grams; %v", err)
+	}
+	if d := NGramDistance(recorded, CountNGrams(e.Operations, states, 5)); d > 0.01 {
+		t.Errorf("unexpected n-gram distance %v of higher-order model", d)
+	}
+
+	// the first-order reduction diverges from the recorded 5-grams
+	e.Order, e.Contexts = 1, nil
+	if states, err = SampleStates(&e, steps, rand.New(rand.NewSource(42))); err != nil {
+		t.Fatalf("cannot sample states; %v", err)
+	}
+	if d := NGramDistance(recorded, CountNGrams(e.Operations, states, 5)); d < 0.1 {
+		t.Errorf("unexpected n-gram distance %v of first-order model", d)
+	}
+}
+
+// TestNewMarkovModelInvalid checks that broken contexts are rejected.
+func TestNewMarkovModelInvalid(t *testing.T) {
+	e := EstimationModelJSON{
+		Operations: []string{"BS", "ES"},
+		Order:      2,
+		Contexts:   []ContextModelJSON{{Context: []int{0, 5}, Next: []int{1}, Probabilities: []float64{1}}},
+	}
+	if _, err := newMarkovModel(&e); err == nil {
+		t.Errorf("invalid state must be rejected")
+	}
+	e.Contexts = []ContextModelJSON{{Context: []int{0}, Next: []int{1}, Probabilities: []float64{1}}}
+	if _, err := newMarkovModel(&e); err == nil {
+		t.Errorf("context of wrong order must be rejected")
+	}
+	e.Order = MaxMarkovOrder + 1
+	if _, err := newMarkovModel(&e); err == nil {
+		t.Errorf("order exceeding the maximum must be rejected")
+	}
+}
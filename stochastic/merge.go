@@ -0,0 +1,197 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+)
+
+// MergeEvents merges event files recorded for separate block ranges into a
+// single event file. The transition and context frequencies are summed after
+// scaling them by the weights of the event files (nil for equal weights). The
+// access and snapshot statistics are mixed in proportion to the weighted
+// number of transitions. Phases are concatenated in the given order if all
+// event files have phases of the same length; otherwise they are omitted.
+func MergeEvents(events []*EventRegistryJSON, weights []float64) (*EventRegistryJSON, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no event files to merge")
+	}
+	if weights == nil {
+		weights = make([]float64, len(events))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != len(events) {
+		return nil, fmt.Errorf("number of weights (%v) does not match number of event files (%v)", len(weights), len(events))
+	}
+	for i, w := range weights {
+		if w <= 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, fmt.Errorf("invalid weight %v of event file %v", w, i)
+		}
+	}
+
+	// check that the event files can be merged
+	order := max(events[0].Order, 1)
+	for i, e := range events {
+		if len(e.TransitionFreq) != len(e.Operations) {
+			return nil, fmt.Errorf("event file %v has no transition frequencies; it must be recorded again", i)
+		}
+		for _, row := range e.TransitionFreq {
+			if len(row) != len(e.Operations) {
+				return nil, fmt.Errorf("event file %v has malformed transition frequencies", i)
+			}
+		}
+		if max(e.Order, 1) != order {
+			return nil, fmt.Errorf("markov order of event file %v (%v) differs from %v", i, max(e.Order, 1), order)
+		}
+	}
+
+	// collect observed operations of all event files in the order of the registry
+	argOps := make([][]int, len(events))
+	index := map[int]int{}
+	for i, e := range events {
+		argOps[i] = make([]int, len(e.Operations))
+		for j, label := range e.Operations {
+			argOps[i][j] = EncodeArgOp(DecodeOpcode(label))
+			index[argOps[i][j]] = 0
+		}
+	}
+	observed := make([]int, 0, len(index))
+	for argOp := range index {
+		observed = append(observed, argOp)
+	}
+	sort.Ints(observed)
+	label := make([]string, len(observed))
+	for i, argOp := range observed {
+		index[argOp] = i
+		label[i] = EncodeOpcode(DecodeArgOp(argOp))
+	}
+
+	// sum weighted transition frequencies
+	freq := make([][]uint64, len(observed))
+	for i := range freq {
+		freq[i] = make([]uint64, len(observed))
+	}
+	mixture := make([]float64, len(events))
+	for k, e := range events {
+		total := uint64(0)
+		for i, row := range e.TransitionFreq {
+			for j, f := range row {
+				freq[index[argOps[k][i]]][index[argOps[k][j]]] += scale(f, weights[k])
+				total += f
+			}
+		}
+		mixture[k] = weights[k] * float64(total)
+	}
+
+	// merge statistics
+	contracts := make([]statistics.AccessJSON, len(events))
+	keys := make([]statistics.AccessJSON, len(events))
+	values := make([]statistics.AccessJSON, len(events))
+	snapshots := make([][][2]float64, len(events))
+	balances := make([]statistics.MagnitudeJSON, len(events))
+	nonces := make([]statistics.MagnitudeJSON, len(events))
+	codeSizes := make([]statistics.MagnitudeJSON, len(events))
+	for k, e := range events {
+		contracts[k] = e.Contracts
+		keys[k] = e.Keys
+		values[k] = e.Values
+		snapshots[k] = e.SnapshotEcdf
+		balances[k] = e.Balances
+		nonces[k] = e.Nonces
+		codeSizes[k] = e.CodeSizes
+	}
+
+	merged := &EventRegistryJSON{
+		FileId:           "events",
+		Operations:       label,
+		StochasticMatrix: newStochasticMatrix(freq),
+		TransitionFreq:   freq,
+		Contracts:        statistics.MergeAccessJSON(contracts, mixture),
+		Keys:             statistics.MergeAccessJSON(keys, mixture),
+		Values:           statistics.MergeAccessJSON(values, mixture),
+		SnapshotEcdf:     statistics.MergeECdf(snapshots, mixture),
+		Balances:         statistics.MergeMagnitudeJSON(balances, weights),
+		Nonces:           statistics.MergeMagnitudeJSON(nonces, weights),
+		CodeSizes:        statistics.MergeMagnitudeJSON(codeSizes, weights),
+		Order:            order,
+	}
+	contexts, err := mergeContexts(events, weights, order)
+	if err != nil {
+		return nil, err
+	}
+	merged.Contexts = contexts
+	merged.PhaseLength, merged.Phases = mergePhases(events)
+	return merged, nil
+}
+
+// mergeContexts sums the weighted frequencies of operations following contexts.
+func mergeContexts(events []*EventRegistryJSON, weights []float64, order int) ([]ContextJSON, error) {
+	if order < 2 {
+		return nil, nil
+	}
+	contextFreq := map[markovContext]map[int]uint64{}
+	for k, e := range events {
+		for i, c := range e.Contexts {
+			if len(c.Context) != order || len(c.Next) != len(c.Freq) {
+				return nil, fmt.Errorf("context %v of event file %v does not match markov order %v", i, k, order)
+			}
+			key := newMarkovContext()
+			for j, label := range c.Context {
+				key[j] = EncodeArgOp(DecodeOpcode(label))
+			}
+			next, found := contextFreq[key]
+			if !found {
+				next = map[int]uint64{}
+				contextFreq[key] = next
+			}
+			for j, label := range c.Next {
+				next[EncodeArgOp(DecodeOpcode(label))] += scale(c.Freq[j], weights[k])
+			}
+		}
+	}
+	return newContextsJSON(contextFreq, order), nil
+}
+
+// mergePhases concatenates the phases of event files if all event files have
+// phases of the same length.
+func mergePhases(events []*EventRegistryJSON) (uint64, []EventRegistryJSON) {
+	length := events[0].PhaseLength
+	phases := []EventRegistryJSON{}
+	for _, e := range events {
+		if len(e.Phases) == 0 || e.PhaseLength != length {
+			return 0, nil
+		}
+		phases = append(phases, e.Phases...)
+	}
+	return length, phases
+}
+
+// scale scales a frequency by a weight. Observed transitions are kept even for
+// small weights, otherwise operations observed only in an event file with a
+// small weight would have no outgoing transitions.
+func scale(freq uint64, weight float64) uint64 {
+	if freq == 0 {
+		return 0
+	}
+	return max(uint64(math.Round(weight*float64(freq))), 1)
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// recordEvents records a sequence of operations without arguments.
+func recordEvents(t *testing.T, order int, ops ...int) *EventRegistryJSON {
+	r := NewEventRegistry()
+	if err := r.SetMarkovOrder(order); err != nil {
+		t.Fatalf("cannot set markov order; %v", err)
+	}
+	for _, op := range ops {
+		r.RegisterOp(op)
+	}
+	events := r.NewEventRegistryJSON()
+	return &events
+}
+
+// TestMergeEvents checks that weighted transition frequencies are summed.
+func TestMergeEvents(t *testing.T) {
+	a := recordEvents(t, 1, BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+	b := recordEvents(t, 1, BeginSyncPeriodID, BeginBlockID, EndBlockID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+
+	merged, err := MergeEvents([]*EventRegistryJSON{a, b}, nil)
+	if err != nil {
+		t.Fatalf("cannot merge events; %v", err)
+	}
+	if len(merged.Operations) != 4 {
+		t.Fatalf("unexpected operations %v", merged.Operations)
+	}
+	if got := transition(t, merged, EndBlockID, EndSyncPeriodID); got != 2.0/3.0 {
+		t.Errorf("unexpected transition probability %v", got)
+	}
+	if got := transition(t, merged, BeginSyncPeriodID, BeginBlockID); got != 1 {
+		t.Errorf("unexpected transition probability %v", got)
+	}
+
+	merged, err = MergeEvents([]*EventRegistryJSON{a, b}, []float64{2, 1})
+	if err != nil {
+		t.Fatalf("cannot merge events; %v", err)
+	}
+	if got := transition(t, merged, EndBlockID, EndSyncPeriodID); got != 0.75 {
+		t.Errorf("unexpected transition probability %v with weights", got)
+	}
+}
+
+// TestMergeEventsContexts checks that context frequencies are summed.
+func TestMergeEventsContexts(t *testing.T) {
+	a := recordEvents(t, 2, BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+	b := recordEvents(t, 2, BeginSyncPeriodID, BeginBlockID, EndBlockID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+	merged, err := MergeEvents([]*EventRegistryJSON{a, b}, nil)
+	if err != nil {
+		t.Fatalf("cannot merge events; %v", err)
+	}
+	freq := map[string]uint64{}
+	for _, c := range merged.Contexts {
+		for i, next := range c.Next {
+			freq[c.Context[0]+c.Context[1]+next] += c.Freq[i]
+		}
+	}
+	bs, bb, eb, es := OpMnemo(BeginSyncPeriodID), OpMnemo(BeginBlockID), OpMnemo(EndBlockID), OpMnemo(EndSyncPeriodID)
+	if freq[bs+bb+eb] != 2 || freq[bb+eb+es] != 2 || freq[bb+eb+bb] != 1 || freq[eb+bb+eb] != 1 {
+		t.Errorf("unexpected context frequencies %v", freq)
+	}
+}
+
+// TestMergeEventsInvalid checks that incompatible event files are rejected.
+func TestMergeEventsInvalid(t *testing.T) {
+	a := recordEvents(t, 1, BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+	b := recordEvents(t, 2, BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+	if _, err := MergeEvents([]*EventRegistryJSON{a, b}, nil); err == nil {
+		t.Errorf("different markov orders must be rejected")
+	}
+	if _, err := MergeEvents([]*EventRegistryJSON{a, a}, []float64{1}); err == nil {
+		t.Errorf("wrong number of weights must be rejected")
+	}
+	if _, err := MergeEvents([]*EventRegistryJSON{a, a}, []float64{1, -1}); err == nil {
+		t.Errorf("negative weights must be rejected")
+	}
+	if _, err := MergeEvents([]*EventRegistryJSON{a, a}, []float64{1, 0}); err == nil {
+		t.Errorf("zero weights must be rejected")
+	}
+	old := *a
+	old.TransitionFreq = nil
+	if _, err := MergeEvents([]*EventRegistryJSON{a, &old}, nil); err == nil {
+		t.Errorf("event files without transition frequencies must be rejected")
+	}
+}
+
+// TestMergeEventsSmallWeights checks that operations observed only in an event
+// file with a small weight keep their transitions.
+func TestMergeEventsSmallWeights(t *testing.T) {
+	a := recordEvents(t, 1, BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID, BeginSyncPeriodID)
+	b := recordEvents(t, 1, BeginSyncPeriodID, BeginBlockID, BeginTransactionID, EndTransactionID, EndBlockID, EndSyncPeriodID, BeginSyncPeriodID)
+
+	merged, err := MergeEvents([]*EventRegistryJSON{a, b}, []float64{1, 0.001})
+	if err != nil {
+		t.Fatalf("cannot merge events; %v", err)
+	}
+	if got := transition(t, merged, BeginTransactionID, EndTransactionID); got != 1 {
+		t.Errorf("unexpected transition probability %v", got)
+	}
+	if _, err = json.Marshal(merged); err != nil {
+		t.Errorf("cannot encode merged events; %v", err)
+	}
+}
+
+// TestMergeEventsPhases checks that phases of the same length are concatenated.
+func TestMergeEventsPhases(t *testing.T) {
+	a := recordEvents(t, 1, BeginSyncPeriodID, BeginBlockID, EndBlockID, EndSyncPeriodID)
+	b := *a
+	a.PhaseLength, a.Phases = 10, []EventRegistryJSON{*a}
+	b.PhaseLength, b.Phases = 10, []EventRegistryJSON{b, b}
+	merged, err := MergeEvents([]*EventRegistryJSON{a, &b}, nil)
+	if err != nil {
+		t.Fatalf("cannot merge events; %v", err)
+	}
+	if merged.PhaseLength != 10 || len(merged.Phases) != 3 {
+		t.Errorf("unexpected phases of length %v: %v", merged.PhaseLength, len(merged.Phases))
+	}
+	b.PhaseLength = 20
+	if merged, err = MergeEvents([]*EventRegistryJSON{a, &b}, nil); err != nil || merged.Phases != nil {
+		t.Errorf("phases of different lengths must be omitted")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// Operation is a StateDB operation executed by the stochastic replay
+// together with the concrete arguments it was executed with.
+type Operation struct {
+	Op     int            // operation ID
+	Addr   common.Address // contract address
+	Key    common.Hash    // storage key
+	Value  common.Hash    // storage value
+	Num    uint64         // nonce, snapshot id, or block/tx/sync-period number
+	Amount *uint256.Int   // balance delta of AddBalance/SubBalance
+	Code   []byte         // contract code of SetCode
+}
+
+// String returns a human-readable representation of an operation.
+func (o Operation) String() string {
+	switch o.Op {
+	case AddBalanceID, SubBalanceID:
+		return fmt.Sprintf("%v(%v, %v)", opText[o.Op], o.Addr.Hex(), o.Amount)
+	case SetNonceID:
+		return fmt.Sprintf("%v(%v, %v)", opText[o.Op], o.Addr.Hex(), o.Num)
+	case BeginBlockID, BeginSyncPeriodID, BeginTransactionID, RevertToSnapshotID, SnapshotID:
+		return fmt.Sprintf("%v(%v)", opText[o.Op], o.Num)
+	case SetCodeID:
+		return fmt.Sprintf("%v(%v, %d bytes)", opText[o.Op], o.Addr.Hex(), len(o.Code))
+	case GetCommittedStateID, GetStateID, GetTransientStateID:
+		return fmt.Sprintf("%v(%v, %v)", opText[o.Op], o.Addr.Hex(), o.Key.Hex())
+	case SetStateID, SetTransientStateID:
+		return fmt.Sprintf("%v(%v, %v, %v)", opText[o.Op], o.Addr.Hex(), o.Key.Hex(), o.Value.Hex())
+	case EndBlockID, EndSyncPeriodID, EndTransactionID:
+		return opText[o.Op]
+	default:
+		return fmt.Sprintf("%v(%v)", opText[o.Op], o.Addr.Hex())
+	}
+}
+
+// OperationLog writes executed operations to a file as a gob stream.
+type OperationLog struct {
+	file   *os.File
+	writer *bufio.Writer
+	enc    *gob.Encoder
+	err    error // first error encountered while writing
+}
+
+// NewOperationLog creates an operation log in the given file.
+func NewOperationLog(filename string) (*OperationLog, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create operation log %v; %v", filename, err)
+	}
+	writer := bufio.NewWriter(file)
+	return &OperationLog{
+		file:   file,
+		writer: writer,
+		enc:    gob.NewEncoder(writer),
+	}, nil
+}
+
+// Append writes an operation to the log. Write errors are retained and
+// reported by Close so that the replay is not interrupted.
+func (l *OperationLog) Append(op Operation) {
+	if l.err != nil {
+		return
+	}
+	l.err = l.enc.Encode(&op)
+}
+
+// Close flushes and closes the operation log.
+func (l *OperationLog) Close() error {
+	err := l.err
+	if ferr := l.writer.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReadOperations reads all operations of an operation log.
+func ReadOperations(filename string) ([]Operation, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open operation log %v; %v", filename, err)
+	}
+	defer file.Close()
+
+	var ops []Operation
+	dec := gob.NewDecoder(bufio.NewReader(file))
+	for {
+		var op Operation
+		err := dec.Decode(&op)
+		if errors.Is(err, io.EOF) {
+			return ops, nil
+		}
+		// a log of a crashed replay may end with a partially written operation
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return ops, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode operation %v of %v; %v", len(ops), filename, err)
+		}
+		if op.Op < 0 || op.Op >= NumOps {
+			return nil, fmt.Errorf("invalid operation id %v at position %v of %v", op.Op, len(ops), filename)
+		}
+		ops = append(ops, op)
+	}
+}
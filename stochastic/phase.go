@@ -0,0 +1,128 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+)
+
+// PhaseRecorder splits recorded events into phases, i.e., windows of
+// consecutive blocks, for a time-varying simulation model. Each phase is
+// counted by its own event registry that observes a complete sequence of
+// operations starting with a sync-period. Phases without blocks are omitted.
+type PhaseRecorder struct {
+	first    uint64              // first block of the first phase
+	length   uint64              // number of blocks per phase
+	order    int                 // markov order of the event registries
+	phase    uint64              // index of the current phase
+	blocks   int                 // number of blocks in the current phase
+	registry *EventRegistry      // event registry of the current phase
+	pending  []int               // sync-period operations preceding the next block
+	phases   []EventRegistryJSON // events of the closed phases
+}
+
+// NewPhaseRecorder creates a phase recorder for phases of the given length
+// starting with the first block.
+func NewPhaseRecorder(first uint64, length uint64, order int) (*PhaseRecorder, error) {
+	if length == 0 {
+		return nil, fmt.Errorf("phase length must be positive")
+	}
+	p := &PhaseRecorder{first: first, length: length, order: order}
+	if err := p.open(0); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Registry returns the event registry of the current phase.
+func (p *PhaseRecorder) Registry() *EventRegistry {
+	return p.registry
+}
+
+// RegisterOp registers an operation with no simulation arguments in the
+// current phase. Sync-period operations are deferred until the next block
+// because a phase opens and closes its own sync-period.
+func (p *PhaseRecorder) RegisterOp(op int) {
+	if op == BeginSyncPeriodID || op == EndSyncPeriodID {
+		p.pending = append(p.pending, op)
+		return
+	}
+	p.flush()
+	p.registry.RegisterOp(op)
+}
+
+// BeginBlock registers the beginning of a block. If the block belongs to a
+// new phase, the current phase is closed and the new phase is opened.
+func (p *PhaseRecorder) BeginBlock(block uint64) error {
+	if block < p.first {
+		return fmt.Errorf("block %v precedes first block %v", block, p.first)
+	}
+	if phase := (block - p.first) / p.length; phase != p.phase {
+		p.close()
+		if err := p.open(phase); err != nil {
+			return err
+		}
+	}
+	if p.blocks > 0 {
+		p.flush()
+	}
+	p.pending = p.pending[:0]
+	p.registry.RegisterOp(BeginBlockID)
+	p.blocks++
+	return nil
+}
+
+// Finish closes the current phase and returns the events of all phases.
+func (p *PhaseRecorder) Finish() []EventRegistryJSON {
+	p.close()
+	p.blocks = 0
+	return p.phases
+}
+
+// open creates the event registry of a new phase.
+func (p *PhaseRecorder) open(phase uint64) error {
+	r := NewEventRegistry()
+	if err := r.SetMarkovOrder(p.order); err != nil {
+		return err
+	}
+	r.RegisterOp(BeginSyncPeriodID)
+	p.phase = phase
+	p.blocks = 0
+	p.registry = &r
+	return nil
+}
+
+// close ends the sync-period of the current phase and keeps its events. The
+// end of the sync-period is followed by the beginning of a new one so that
+// all rows of the stochastic matrix of the phase are defined.
+func (p *PhaseRecorder) close() {
+	p.pending = p.pending[:0]
+	if p.blocks == 0 {
+		return
+	}
+	p.registry.RegisterOp(EndSyncPeriodID)
+	p.registry.RegisterOp(BeginSyncPeriodID)
+	p.phases = append(p.phases, p.registry.NewEventRegistryJSON())
+}
+
+// flush registers the deferred sync-period operations.
+func (p *PhaseRecorder) flush() {
+	for _, op := range p.pending {
+		p.registry.RegisterOp(op)
+	}
+	p.pending = p.pending[:0]
+}
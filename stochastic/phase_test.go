@@ -0,0 +1,99 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// transition returns the transition probability between two operations
+// without arguments in recorded events.
+func transition(t *testing.T, d *EventRegistryJSON, from int, to int) float64 {
+	i := find(d.Operations, OpMnemo(from))
+	j := find(d.Operations, OpMnemo(to))
+	if i == -1 || j == -1 {
+		t.Fatalf("operations %v and %v are not recorded", OpMnemo(from), OpMnemo(to))
+	}
+	return d.StochasticMatrix[i][j]
+}
+
+// TestPhaseRecorder checks that blocks are split into phases, each beginning
+// and ending with a sync-period, and that windows without blocks are omitted.
+func TestPhaseRecorder(t *testing.T) {
+	p, err := NewPhaseRecorder(10, 4, 1)
+	if err != nil {
+		t.Fatalf("cannot create phase recorder; %v", err)
+	}
+	p.RegisterOp(BeginSyncPeriodID)
+	for _, block := range []uint64{10, 11, 12, 13, 20, 21} {
+		// sync-periods of two blocks
+		if block%2 == 0 && block != 10 {
+			p.RegisterOp(EndSyncPeriodID)
+			p.RegisterOp(BeginSyncPeriodID)
+		}
+		if err := p.BeginBlock(block); err != nil {
+			t.Fatalf("cannot begin block %v; %v", block, err)
+		}
+		p.RegisterOp(EndBlockID)
+	}
+	p.RegisterOp(EndSyncPeriodID)
+	phases := p.Finish()
+
+	if len(phases) != 2 {
+		t.Fatalf("unexpected number of phases %v", len(phases))
+	}
+	// first phase: BS BB EB BB EB ES BS BB EB BB EB ES BS
+	if got := transition(t, &phases[0], EndBlockID, EndSyncPeriodID); got != 0.5 {
+		t.Errorf("unexpected transition probability %v from end of block to end of sync-period", got)
+	}
+	// second phase: BS BB EB BB EB ES BS (sync-period at begin of phase dropped)
+	if got := transition(t, &phases[1], EndBlockID, BeginBlockID); got != 0.5 {
+		t.Errorf("unexpected transition probability %v from end of block to begin of block", got)
+	}
+	for i := range phases {
+		if got := transition(t, &phases[i], BeginSyncPeriodID, BeginBlockID); got != 1 {
+			t.Errorf("sync-period of phase %v must be followed by a block", i)
+		}
+		if got := transition(t, &phases[i], EndSyncPeriodID, BeginSyncPeriodID); got != 1 {
+			t.Errorf("end of sync-period of phase %v must be followed by a sync-period", i)
+		}
+		if _, err := json.Marshal(phases[i]); err != nil {
+			t.Errorf("cannot convert phase %v to JSON; %v", i, err)
+		}
+	}
+}
+
+// TestPhaseRecorderInvalid checks invalid phase lengths and blocks.
+func TestPhaseRecorderInvalid(t *testing.T) {
+	if _, err := NewPhaseRecorder(10, 0, 1); err == nil {
+		t.Errorf("phase length of zero must be rejected")
+	}
+	if _, err := NewPhaseRecorder(10, 4, MaxMarkovOrder+1); err == nil {
+		t.Errorf("invalid markov order must be rejected")
+	}
+	p, err := NewPhaseRecorder(10, 4, 1)
+	if err != nil {
+		t.Fatalf("cannot create phase recorder; %v", err)
+	}
+	if err := p.BeginBlock(9); err == nil {
+		t.Errorf("block preceding the first block must be rejected")
+	}
+	if phases := p.Finish(); len(phases) != 0 {
+		t.Errorf("phases without blocks must be omitted")
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic/generator"
+	"github.com/Fantom-foundation/Aida/stochastic/stationary"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// readerQueryLength is the number of read-only operations issued on a single archive state.
+const readerQueryLength = 100
+
+// readOps are the read-only operations issued by reader streams.
+var readOps = []int{
+	EmptyID,
+	ExistID,
+	GetBalanceID,
+	GetCodeHashID,
+	GetCodeID,
+	GetCodeSizeID,
+	GetCommittedStateID,
+	GetNonceID,
+	GetStateID,
+	GetStorageRootID,
+}
+
+// readerStream issues read-only operations against archive states of past
+// blocks concurrently to the main stream of the stochastic replay.
+type readerStream struct {
+	id           int                    // number of the stream
+	db           state.StateDB          // StateDB providing the archive states
+	maxAge       uint64                 // maximal age of queried blocks (0 for unlimited)
+	contracts    generator.Distribution // access distribution of contracts
+	numContracts int64                  // number of contracts
+	keys         generator.Distribution // access distribution of storage keys
+	numKeys      int64                  // number of storage keys
+	opCdf        []float64              // cumulative distribution of read-only operations
+	rg           *rand.Rand             // random generator for sampling
+	numOps       atomic.Uint64          // number of executed operations
+	numErrors    atomic.Uint64          // number of failed queries
+	err          error                  // error of the first failed query
+	errMutex     sync.Mutex
+}
+
+// readerStreams runs a set of reader streams in the background.
+type readerStreams struct {
+	streams  []*readerStream
+	start    time.Time
+	finished utils.Event
+	done     sync.WaitGroup
+}
+
+// newReaderStreams creates reader streams issuing read-only operations
+// following the access distributions of a simulation model.
+func newReaderStreams(cfg *utils.Config, e *EstimationModelJSON, db state.StateDB) (*readerStreams, error) {
+	if !cfg.ArchiveMode {
+		return nil, fmt.Errorf("reader streams require an archive (missing --%s flag)", utils.ArchiveModeFlag.Name)
+	}
+	opCdf := readOpCdf(e)
+	maxAge := uint64(0)
+	if cfg.ArchiveMaxQueryAge > 0 {
+		maxAge = uint64(cfg.ArchiveMaxQueryAge)
+	}
+	r := &readerStreams{finished: utils.MakeEvent()}
+	for i := 0; i < cfg.ReaderStreams; i++ {
+		contractDist, keyDist, _, err := newAccessDistributions(e)
+		if err != nil {
+			return nil, err
+		}
+		r.streams = append(r.streams, &readerStream{
+			id:           i,
+			db:           db,
+			maxAge:       maxAge,
+			contracts:    contractDist,
+			numContracts: e.Contracts.NumKeys,
+			keys:         keyDist,
+			numKeys:      e.Keys.NumKeys,
+			opCdf:        opCdf,
+			rg:           rand.New(rand.NewSource(cfg.RandomSeed + int64(i) + 1)),
+		})
+	}
+	return r, nil
+}
+
+// readOpCdf computes the cumulative distribution of the read-only operations
+// from the stationary distribution of the stochastic matrix. If the stationary
+// distribution cannot be computed, the read-only operations are uniformly distributed.
+func readOpCdf(e *EstimationModelJSON) []float64 {
+	weights := make([]float64, len(readOps))
+	total := 0.0
+	if dist, err := stationary.ComputeDistribution(e.StochasticMatrix); err == nil {
+		for i, opc := range e.Operations {
+			op, _, _, _ := DecodeOpcode(opc)
+			if idx := find(readOps, op); idx != -1 && i < len(dist) {
+				weights[idx] += dist[i]
+				total += dist[i]
+			}
+		}
+	}
+	if total <= 0 || math.IsNaN(total) {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = float64(len(weights))
+	}
+	cdf := make([]float64, len(weights))
+	sum := 0.0
+	for i, w := range weights {
+		sum += w / total
+		cdf[i] = sum
+	}
+	return cdf
+}
+
+// sampleReadOp samples a read-only operation from its cumulative distribution.
+func sampleReadOp(rg *rand.Rand, cdf []float64) int {
+	r := rg.Float64()
+	for i, p := range cdf {
+		if r < p {
+			return readOps[i]
+		}
+	}
+	return readOps[len(readOps)-1]
+}
+
+// run starts the reader streams.
+func (r *readerStreams) run() {
+	r.start = time.Now()
+	r.done.Add(len(r.streams))
+	for _, s := range r.streams {
+		go s.run(r.finished, &r.done)
+	}
+}
+
+// stop terminates the reader streams and waits for their completion.
+func (r *readerStreams) stop() {
+	r.finished.Signal()
+	r.done.Wait()
+}
+
+// numOps returns the total number of operations executed by the reader streams.
+func (r *readerStreams) numOps() uint64 {
+	total := uint64(0)
+	for _, s := range r.streams {
+		total += s.numOps.Load()
+	}
+	return total
+}
+
+// report prints the throughput of the reader streams and returns the errors
+// of their failed queries.
+func (r *readerStreams) report(log logger.Logger) error {
+	sec := time.Since(r.start).Seconds()
+	var err error
+	for _, s := range r.streams {
+		ops := s.numOps.Load()
+		log.Noticef("Reader stream %v: %v operations, %.2f ops/s, %v failed queries", s.id, ops, float64(ops)/sec, s.numErrors.Load())
+		if s.err == nil {
+			continue
+		}
+		if err == nil {
+			err = fmt.Errorf("error: reader streams failed.")
+		}
+		err = fmt.Errorf("%v\n\tReader stream %v: %v", err, s.id, s.err)
+	}
+	return err
+}
+
+// run issues queries on archive states until the streams are finished.
+func (s *readerStream) run(finished utils.Event, done *sync.WaitGroup) {
+	defer done.Done()
+	for !finished.HasHappened() {
+		block, ok, err := s.sampleBlock()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		if !ok {
+			// wait for the archive to catch up
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-finished.Wait():
+				return
+			}
+			continue
+		}
+		if err := s.query(block); err != nil {
+			s.fail(fmt.Errorf("block %v: %v", block, err))
+		}
+	}
+}
+
+// sampleBlock samples a block covered by the archive. If the archive is
+// empty, no block is returned.
+func (s *readerStream) sampleBlock() (uint64, bool, error) {
+	height, empty, err := s.db.GetArchiveBlockHeight()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to obtain archive block height; %v", err)
+	}
+	if empty {
+		return 0, false, nil
+	}
+	first := uint64(0)
+	if s.maxAge > 0 && height > s.maxAge {
+		first = height - s.maxAge
+	}
+	return first + uint64(s.rg.Int63n(int64(height-first+1))), true, nil
+}
+
+// query issues read-only operations on the archive state of a block. The
+// operations are cross-checked if the archive state is shadowed.
+func (s *readerStream) query(block uint64) error {
+	archive, err := s.db.GetArchiveState(block)
+	if err != nil {
+		return fmt.Errorf("failed to obtain archive state; %v", err)
+	}
+	defer archive.Release()
+	if err := archive.BeginTransaction(0); err != nil {
+		return fmt.Errorf("cannot begin transaction; %v", err)
+	}
+	for i := 0; i < readerQueryLength; i++ {
+		s.execute(archive, sampleReadOp(s.rg, s.opCdf))
+	}
+	s.numOps.Add(readerQueryLength)
+	if err := archive.EndTransaction(); err != nil {
+		return fmt.Errorf("cannot end transaction; %v", err)
+	}
+	// a shadowed archive state reports diverging results
+	if shadow, ok := archive.(interface{ Error() error }); ok {
+		if err := shadow.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute runs a read-only operation on an archive state.
+func (s *readerStream) execute(archive state.NonCommittableStateDB, op int) {
+	addr := toAddress(sampleIndex(s.rg, s.contracts, s.numContracts))
+	switch op {
+	case EmptyID:
+		archive.Empty(addr)
+	case ExistID:
+		archive.Exist(addr)
+	case GetBalanceID:
+		archive.GetBalance(addr)
+	case GetCodeHashID:
+		archive.GetCodeHash(addr)
+	case GetCodeID:
+		archive.GetCode(addr)
+	case GetCodeSizeID:
+		archive.GetCodeSize(addr)
+	case GetCommittedStateID:
+		archive.GetCommittedState(addr, toHash(sampleIndex(s.rg, s.keys, s.numKeys)))
+	case GetNonceID:
+		archive.GetNonce(addr)
+	case GetStateID:
+		archive.GetState(addr, toHash(sampleIndex(s.rg, s.keys, s.numKeys)))
+	case GetStorageRootID:
+		archive.GetStorageRoot(addr)
+	}
+}
+
+// sampleIndex samples the index of a primed contract or key. The zero index
+// is returned if there are no elements.
+func sampleIndex(rg *rand.Rand, dist generator.Distribution, n int64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	return dist.Sample(rg, n) + 1
+}
+
+// fail records a failed query. Only the error of the first failed query is kept.
+func (s *readerStream) fail(err error) {
+	s.numErrors.Add(1)
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
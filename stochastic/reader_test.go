@@ -0,0 +1,72 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+)
+
+// TestReadOpCdf checks the distribution of read-only operations derived from
+// the stationary distribution of a stochastic matrix.
+func TestReadOpCdf(t *testing.T) {
+	getBalance := EncodeOpcode(GetBalanceID, statistics.RandomValueID, statistics.NoArgID, statistics.NoArgID)
+	getState := EncodeOpcode(GetStateID, statistics.RandomValueID, statistics.RandomValueID, statistics.NoArgID)
+	setState := EncodeOpcode(SetStateID, statistics.RandomValueID, statistics.RandomValueID, statistics.RandomValueID)
+	e := EstimationModelJSON{
+		Operations: []string{getBalance, getState, setState},
+		StochasticMatrix: [][]float64{
+			{0.0, 0.5, 0.5},
+			{0.5, 0.0, 0.5},
+			{0.5, 0.5, 0.0},
+		},
+	}
+	cdf := readOpCdf(&e)
+	if len(cdf) != len(readOps) {
+		t.Fatalf("unexpected length of distribution %v", len(cdf))
+	}
+	if math.Abs(cdf[len(cdf)-1]-1.0) > 1e-9 {
+		t.Fatalf("distribution does not sum up to one: %v", cdf)
+	}
+	counts := map[int]int{}
+	rg := rand.New(rand.NewSource(999))
+	for i := 0; i < 10000; i++ {
+		counts[sampleReadOp(rg, cdf)]++
+	}
+	if len(counts) != 2 || counts[GetBalanceID] < 4500 || counts[GetStateID] < 4500 {
+		t.Fatalf("unexpected operation counts %v", counts)
+	}
+}
+
+// TestReadOpCdfUniform checks that read-only operations are uniformly
+// distributed if the model has none.
+func TestReadOpCdfUniform(t *testing.T) {
+	setState := EncodeOpcode(SetStateID, statistics.RandomValueID, statistics.RandomValueID, statistics.RandomValueID)
+	e := EstimationModelJSON{
+		Operations:       []string{setState},
+		StochasticMatrix: [][]float64{{1.0}},
+	}
+	cdf := readOpCdf(&e)
+	for i, p := range cdf {
+		if expected := float64(i+1) / float64(len(readOps)); math.Abs(p-expected) > 1e-9 {
+			t.Fatalf("unexpected probability %v of operation %v; expected %v", p, i, expected)
+		}
+	}
+}
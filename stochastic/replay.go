@@ -19,6 +19,7 @@ package stochastic
 import (
 	"encoding/binary"
 	"fmt"
+	"math/bits"
 	"math/rand"
 	"time"
 
@@ -58,6 +59,10 @@ type stochasticState struct {
 	snapshot       []int                     // stack of active snapshots
 	selfDestructed []int64                   // list of self destructed accounts
 	traceDebug     bool                      // trace-debug flag
+	opLog          *OperationLog             // log of executed operations (nil if disabled)
+	balances       *magnitudeSampler         // distribution of balance deltas (nil for balance range)
+	nonces         *magnitudeSampler         // distribution of nonces (nil for nonce range)
+	codeSizes      *magnitudeSampler         // distribution of code sizes (nil for uniform sizes)
 	rg             *rand.Rand                // random generator for sampling
 	log            logger.Logger
 }
@@ -73,37 +78,108 @@ func find[T comparable](a []T, x T) int {
 }
 
 // createState creates a stochastic state and primes the StateDB
-func createState(cfg *utils.Config, e *EstimationModelJSON, db state.StateDB, rg *rand.Rand, log logger.Logger) *stochasticState {
+func createState(cfg *utils.Config, e *EstimationModelJSON, db state.StateDB, rg *rand.Rand, opLog *OperationLog, log logger.Logger) (*stochasticState, error) {
 	// produce random access generators for contract addresses,
 	// storage-keys, and storage addresses.
 	// (NB: Contracts need an indirect access wrapper because
 	// contract addresses can be deleted by suicide.)
-	contracts := generator.NewIndirectAccess(generator.NewRandomAccess(
+	contractDist, keyDist, valueDist, err := newAccessDistributions(e)
+	if err != nil {
+		return nil, err
+	}
+	contracts := generator.NewIndirectAccess(generator.NewDistributedRandomAccess(
 		rg,
 		e.Contracts.NumKeys,
-		e.Contracts.Lambda,
+		contractDist,
 		e.Contracts.QueueDistribution,
 	))
-	keys := generator.NewRandomAccess(
+	keys := generator.NewDistributedRandomAccess(
 		rg,
 		e.Keys.NumKeys,
-		e.Keys.Lambda,
+		keyDist,
 		e.Keys.QueueDistribution,
 	)
-	values := generator.NewRandomAccess(
+	values := generator.NewDistributedRandomAccess(
 		rg,
 		e.Values.NumKeys,
-		e.Values.Lambda,
+		valueDist,
 		e.Values.QueueDistribution,
 	)
 
 	// setup state
 	ss := NewStochasticState(rg, db, contracts, keys, values, e.SnapshotLambda, log)
+	ss.opLog = opLog
+
+	// setup value distributions
+	if err := ss.setValueDistributions(e); err != nil {
+		return nil, err
+	}
 
 	// create accounts in StateDB
 	ss.prime()
 
-	return &ss
+	return &ss, nil
+}
+
+// newAccessDistributions creates the distributions of random accesses of
+// contracts, keys, and values of a simulation model.
+func newAccessDistributions(e *EstimationModelJSON) (generator.Distribution, generator.Distribution, generator.Distribution, error) {
+	contracts, err := e.Contracts.NewDistribution()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid contract distribution; %v", err)
+	}
+	keys, err := e.Keys.NewDistribution()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid key distribution; %v", err)
+	}
+	values, err := e.Values.NewDistribution()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid value distribution; %v", err)
+	}
+	return contracts, keys, values, nil
+}
+
+// setValueDistributions sets the distributions of balance deltas, nonces, and
+// code sizes of a simulation model.
+func (ss *stochasticState) setValueDistributions(e *EstimationModelJSON) error {
+	var err error
+	if ss.balances, err = newMagnitudeSampler(e.Balances, 256); err != nil {
+		return fmt.Errorf("invalid balance distribution; %v", err)
+	}
+	if ss.nonces, err = newMagnitudeSampler(e.Nonces, 64); err != nil {
+		return fmt.Errorf("invalid nonce distribution; %v", err)
+	}
+	if ss.codeSizes, err = newMagnitudeSampler(e.CodeSizes, bits.Len(MaxCodeSize)); err != nil {
+		return fmt.Errorf("invalid code-size distribution; %v", err)
+	}
+	return nil
+}
+
+// enterPhase switches the simulation to the model of the next phase after the
+// end of a block. The access distributions are replaced while the accessed
+// contracts, keys, and values are kept. It returns the Markovian process of
+// the phase with its operations and the end-block state.
+func (ss *stochasticState) enterPhase(e *EstimationModelJSON) (*markovModel, []string, int, error) {
+	state := find(e.Operations, OpMnemo(EndBlockID))
+	if state == -1 {
+		return nil, nil, 0, fmt.Errorf("EndBlock cannot be observed in stochastic matrix of phase")
+	}
+	model, err := newMarkovModel(e)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if err := ss.setValueDistributions(e); err != nil {
+		return nil, nil, 0, err
+	}
+	contractDist, keyDist, valueDist, err := newAccessDistributions(e)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ss.contracts.SetDistribution(contractDist, e.Contracts.QueueDistribution)
+	ss.keys.SetDistribution(keyDist, e.Keys.QueueDistribution)
+	ss.values.SetDistribution(valueDist, e.Values.QueueDistribution)
+	ss.snapshotLambda = e.SnapshotLambda
+	return model, e.Operations, state, nil
 }
 
 // getStochasticMatrix returns the stochastic matrix with its operations and the initial state
@@ -143,11 +219,62 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 	rg := rand.New(rand.NewSource(cfg.RandomSeed))
 	log.Noticef("using random seed %d", cfg.RandomSeed)
 
+	// a time-varying model switches to the model of the next phase
+	// after a fixed number of blocks
+	phases := []EstimationModelJSON{*e}
+	if len(e.Phases) > 0 {
+		phases = e.Phases
+	}
+	phaseLength := cfg.PhaseLength
+	if phaseLength == 0 {
+		phaseLength = e.PhaseLength
+	}
+	if len(phases) > 1 {
+		if phaseLength == 0 {
+			return fmt.Errorf("phase length of time-varying model is missing")
+		}
+		log.Noticef("%d phases of %d blocks", len(phases), phaseLength)
+	}
+	phase := 0
+
+	// create the Markovian process sampling the operations
+	model, err := newMarkovModel(&phases[phase])
+	if err != nil {
+		return err
+	}
+	if model.order > 1 {
+		log.Noticef("markov order %d with %d contexts", model.order, len(model.contexts))
+	}
+
+	// create reader streams querying archive states of past blocks
+	var readers *readerStreams
+	if cfg.ReaderStreams > 0 {
+		if readers, err = newReaderStreams(cfg, &phases[phase], db); err != nil {
+			return err
+		}
+		log.Noticef("%d reader streams", cfg.ReaderStreams)
+	}
+
+	// open the operation log if requested
+	var opLog *OperationLog
+	if cfg.OperationLog != "" {
+		if opLog, err = NewOperationLog(cfg.OperationLog); err != nil {
+			return err
+		}
+		log.Noticef("logging operations to %v", cfg.OperationLog)
+	}
+
 	// create a stochastic state
-	ss := createState(cfg, e, db, rg, log)
+	ss, err := createState(cfg, &phases[phase], db, rg, opLog, log)
+	if err != nil {
+		if opLog != nil {
+			opLog.Close()
+		}
+		return err
+	}
 
 	// get stochastic matrix
-	operations, A, state := getStochasticMatrix(e)
+	operations, _, state := getStochasticMatrix(&phases[phase])
 
 	// progress message setup
 	var (
@@ -166,6 +293,11 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 		ss.enableDebug()
 	}
 
+	// start reader streams after priming
+	if readers != nil {
+		readers.run()
+	}
+
 	block := 0
 	// inclusive range
 	log.Noticef("Simulation block range: first %v, last %v", ss.blockNum, ss.blockNum+uint64(nBlocks-1))
@@ -187,6 +319,15 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 			if block >= nBlocks {
 				break
 			}
+			// switch to the model of the next phase
+			if phase+1 < len(phases) && uint64(block)%phaseLength == 0 {
+				phase++
+				log.Noticef("Phase %v starts at block %v", phase, ss.blockNum)
+				if model, operations, state, err = ss.enterPhase(&phases[phase]); err != nil {
+					runErr = fmt.Errorf("cannot enter phase %v; %v", phase, err)
+					break
+				}
+			}
 			// if current block is greater or equal to debug block, enable debug.
 			if cfg.Debug && !ss.traceDebug && ss.blockNum >= cfg.DebugFrom {
 				ss.enableDebug()
@@ -197,6 +338,9 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 		sec = time.Since(start).Seconds()
 		if sec-lastSec >= 15 {
 			log.Debugf("Elapsed time: %.0f s, at block %v", sec, block)
+			if readers != nil {
+				log.Debugf("Reader streams executed %v operations", readers.numOps())
+			}
 			lastSec = sec
 		}
 
@@ -214,7 +358,19 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 		}
 
 		// transit to next state in Markovian process
-		state = nextState(rg, A, state)
+		state = model.next(rg, state)
+	}
+
+	// stop reader streams
+	if readers != nil {
+		readers.stop()
+	}
+
+	// close the operation log
+	if opLog != nil {
+		if err := opLog.Close(); err != nil {
+			log.Errorf("failed writing operation log; %v", err)
+		}
 	}
 
 	// print progress summary
@@ -232,6 +388,18 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 	for op := 0; op < NumOps; op++ {
 		log.Noticef("\t%v: %v", opText[op], opFrequency[op])
 	}
+
+	// print throughput of streams
+	if readers != nil {
+		log.Noticef("Main stream: %v operations, %.2f ops/s", numOps, float64(numOps)/sec)
+		if err := readers.report(log); err != nil {
+			if runErr == nil {
+				runErr = err
+			} else {
+				runErr = fmt.Errorf("%v\n%v", runErr, err)
+			}
+		}
+	}
 	return runErr
 }
 
@@ -264,21 +432,83 @@ func (ss *stochasticState) prime() {
 	db.BeginSyncPeriod(0)
 	db.BeginBlock(0)
 	db.BeginTransaction(0)
+	ss.logOperation(Operation{Op: BeginSyncPeriodID})
+	ss.logOperation(Operation{Op: BeginBlockID})
+	ss.logOperation(Operation{Op: BeginTransactionID})
 
 	// initialise accounts in memory with balances greater than zero
 	for i := int64(0); i <= numInitialAccounts; i++ {
 		addr := toAddress(i)
+		value := ss.sampleBalance()
 		db.CreateAccount(addr)
-		db.AddBalance(addr, uint256.NewInt(uint64(ss.rg.Int63n(BalanceRange))), 0)
+		db.AddBalance(addr, value, 0)
+		ss.logOperation(Operation{Op: CreateAccountID, Addr: addr})
+		ss.logOperation(Operation{Op: AddBalanceID, Addr: addr, Amount: value})
 		pt.PrintProgress()
 	}
 	ss.log.Notice("Finalizing...")
 	db.EndTransaction()
 	db.EndBlock()
 	db.EndSyncPeriod()
+	ss.logOperation(Operation{Op: EndTransactionID})
+	ss.logOperation(Operation{Op: EndBlockID})
+	ss.logOperation(Operation{Op: EndSyncPeriodID})
 	ss.log.Notice("End priming...")
 }
 
+// sampleBalance samples a balance delta from the estimated distribution or
+// from the balance range if the model has no balance distribution.
+func (ss *stochasticState) sampleBalance() *uint256.Int {
+	if ss.balances != nil {
+		return ss.balances.sample(ss.rg)
+	}
+	return uint256.NewInt(uint64(ss.rg.Int63n(BalanceRange)))
+}
+
+// sampleBalanceDelta samples a delta not exceeding the given balance, nil if the balance is zero.
+// Deltas sampled from the estimated distribution are clamped to the balance.
+func (ss *stochasticState) sampleBalanceDelta(balance *uint256.Int) *uint256.Int {
+	if ss.balances != nil {
+		if balance.IsZero() {
+			return nil
+		}
+		value := ss.balances.sample(ss.rg)
+		if value.Gt(balance) {
+			value.Set(balance)
+		}
+		return value
+	}
+	if b := balance.Uint64(); b > 0 {
+		return uint256.NewInt(uint64(ss.rg.Int63n(int64(b))))
+	}
+	return nil
+}
+
+// sampleNonce samples a nonce from the estimated distribution or from the
+// nonce range if the model has no nonce distribution.
+func (ss *stochasticState) sampleNonce() uint64 {
+	if ss.nonces != nil {
+		return ss.nonces.sampleUint64(ss.rg)
+	}
+	return uint64(ss.rg.Intn(NonceRange))
+}
+
+// sampleCodeSize samples a code size from the estimated distribution or
+// uniformly if the model has no code-size distribution. The code has at least one byte.
+func (ss *stochasticState) sampleCodeSize() int {
+	if ss.codeSizes != nil {
+		return int(max(min(ss.codeSizes.sampleUint64(ss.rg), MaxCodeSize), 1))
+	}
+	return ss.rg.Intn(MaxCodeSize-1) + 1
+}
+
+// logOperation appends an executed operation to the operation log if enabled.
+func (ss *stochasticState) logOperation(op Operation) {
+	if ss.opLog != nil {
+		ss.opLog.Append(op)
+	}
+}
+
 // EnableDebug set traceDebug flag to true, and enable debug message when executing an operation
 func (ss *stochasticState) enableDebug() {
 	ss.traceDebug = true
@@ -292,6 +522,7 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 		value common.Hash
 		db    = ss.db
 		rg    = ss.rg
+		skip  bool // operation was not executed and is not logged
 	)
 
 	// fetch indexes from index access generators
@@ -327,19 +558,24 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 		}
 	}
 
+	// the logged operation with its concrete arguments
+	entry := Operation{Op: op, Addr: addr, Key: key, Value: value}
+
 	switch op {
 	case AddBalanceID:
-		value := rg.Int63n(BalanceRange)
+		value := ss.sampleBalance()
 		if ss.traceDebug {
 			ss.log.Infof("value: %v", value)
 		}
-		db.AddBalance(addr, uint256.NewInt(uint64(value)), 0)
+		db.AddBalance(addr, value, 0)
+		entry.Amount = value
 
 	case BeginBlockID:
 		if ss.traceDebug {
 			ss.log.Infof(" id: %v", ss.blockNum)
 		}
 		db.BeginBlock(ss.blockNum)
+		entry.Num = ss.blockNum
 		ss.txNum = 0
 		ss.selfDestructed = []int64{}
 
@@ -348,12 +584,14 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 			ss.log.Infof(" id: %v", ss.syncPeriodNum)
 		}
 		db.BeginSyncPeriod(ss.syncPeriodNum)
+		entry.Num = ss.syncPeriodNum
 
 	case BeginTransactionID:
 		if ss.traceDebug {
 			ss.log.Infof(" id: %v", ss.txNum)
 		}
 		db.BeginTransaction(ss.txNum)
+		entry.Num = uint64(ss.txNum)
 		ss.snapshot = []int{}
 		ss.selfDestructed = []int64{}
 
@@ -407,6 +645,9 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 	case GetStorageRootID:
 		db.GetStorageRoot(addr)
 
+	case GetTransientStateID:
+		db.GetTransientState(addr, key)
+
 	case HasSelfDestructedID:
 		db.HasSelfDestructed(addr)
 
@@ -421,13 +662,16 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 				ss.log.Infof(" id: %v", snapshot)
 			}
 			db.RevertToSnapshot(snapshot)
+			entry.Num = uint64(snapshot)
 
 			// update active snapshots and perform a rollback in balance log
 			ss.snapshot = ss.snapshot[0:snapshotIdx]
+		} else {
+			skip = true
 		}
 
 	case SetCodeID:
-		sz := rg.Intn(MaxCodeSize-1) + 1
+		sz := ss.sampleCodeSize()
 		if ss.traceDebug {
 			ss.log.Infof(" code-size: %v", sz)
 		}
@@ -437,37 +681,44 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 			ss.log.Fatalf("error producing a random byte slice. Error: %v", err)
 		}
 		db.SetCode(addr, code)
+		entry.Code = code
 
 	case SetNonceID:
-		value := uint64(rg.Intn(NonceRange))
+		value := ss.sampleNonce()
 		db.SetNonce(addr, value)
+		entry.Num = value
 
 	case SetStateID:
 		db.SetState(addr, key, value)
 
+	case SetTransientStateID:
+		db.SetTransientState(addr, key, value)
+
 	case SnapshotID:
 		id := db.Snapshot()
 		if ss.traceDebug {
 			ss.log.Infof(" id: %v", id)
 		}
 		ss.snapshot = append(ss.snapshot, id)
+		entry.Num = uint64(id)
 
 	case SubBalanceID:
-		shadowDB := db.GetShadowDB()
-		var balance uint64
-		if shadowDB == nil {
-			balance = db.GetBalance(addr).Uint64()
+		var balance *uint256.Int
+		if shadowDB := db.GetShadowDB(); shadowDB == nil {
+			balance = db.GetBalance(addr)
 		} else {
-			balance = shadowDB.GetBalance(addr).Uint64()
+			balance = shadowDB.GetBalance(addr)
 		}
-		if balance > 0 {
-			// get a delta that does not exceed current balance
-			// in the current snapshot
-			value := uint64(rg.Int63n(int64(balance)))
+		// get a delta that does not exceed current balance
+		// in the current snapshot
+		if value := ss.sampleBalanceDelta(balance); value != nil {
 			if ss.traceDebug {
 				ss.log.Infof(" value: %v", value)
 			}
-			db.SubBalance(addr, uint256.NewInt(value), 0)
+			db.SubBalance(addr, value, 0)
+			entry.Amount = value
+		} else {
+			skip = true
 		}
 
 	case SelfDestructID:
@@ -485,6 +736,10 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 	default:
 		ss.log.Fatal("invalid operation")
 	}
+
+	if !skip {
+		ss.logOperation(entry)
+	}
 }
 
 // nextState produces the next state in the Markovian process.
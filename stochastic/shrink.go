@@ -0,0 +1,324 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/holiman/uint256"
+)
+
+// isStructural returns true for operations that delimit sync-periods, blocks,
+// and transactions. They are never removed individually by the shrinker so
+// that every reduced sequence remains well-formed.
+func isStructural(op int) bool {
+	switch op {
+	case BeginSyncPeriodID, EndSyncPeriodID, BeginBlockID, EndBlockID, BeginTransactionID, EndTransactionID:
+		return true
+	}
+	return false
+}
+
+// ReplayOperations executes a sequence of logged operations on a StateDB. It
+// stops at the first operation after which the StateDB reports an error and
+// returns this error. Sync-period, block, and transaction numbers are assigned
+// consecutively, snapshot ids are translated to the ids returned by the
+// StateDB, and reverts to snapshots that were removed from the sequence are
+// skipped. Balance deltas of SubBalance are capped by the current balance.
+func ReplayOperations(db state.StateDB, ops []Operation) error {
+	var (
+		syncPeriodNum uint64
+		blockNum      uint64
+		txNum         uint32
+		snapshots     = map[uint64]int{}
+	)
+	for i, o := range ops {
+		switch o.Op {
+		case AddBalanceID:
+			db.AddBalance(o.Addr, amountOf(o), 0)
+		case BeginBlockID:
+			db.BeginBlock(blockNum)
+			txNum = 0
+		case BeginSyncPeriodID:
+			db.BeginSyncPeriod(syncPeriodNum)
+		case BeginTransactionID:
+			db.BeginTransaction(txNum)
+			snapshots = map[uint64]int{}
+		case CreateAccountID:
+			db.CreateAccount(o.Addr)
+		case CreateContractID:
+			db.CreateContract(o.Addr)
+		case EmptyID:
+			db.Empty(o.Addr)
+		case EndBlockID:
+			db.EndBlock()
+			blockNum++
+		case EndSyncPeriodID:
+			db.EndSyncPeriod()
+			syncPeriodNum++
+		case EndTransactionID:
+			db.EndTransaction()
+			txNum++
+		case ExistID:
+			db.Exist(o.Addr)
+		case GetBalanceID:
+			db.GetBalance(o.Addr)
+		case GetCodeHashID:
+			db.GetCodeHash(o.Addr)
+		case GetCodeID:
+			db.GetCode(o.Addr)
+		case GetCodeSizeID:
+			db.GetCodeSize(o.Addr)
+		case GetCommittedStateID:
+			db.GetCommittedState(o.Addr, o.Key)
+		case GetNonceID:
+			db.GetNonce(o.Addr)
+		case GetStateID:
+			db.GetState(o.Addr, o.Key)
+		case GetStorageRootID:
+			db.GetStorageRoot(o.Addr)
+		case GetTransientStateID:
+			db.GetTransientState(o.Addr, o.Key)
+		case HasSelfDestructedID:
+			db.HasSelfDestructed(o.Addr)
+		case RevertToSnapshotID:
+			if id, found := snapshots[o.Num]; found {
+				db.RevertToSnapshot(id)
+			}
+		case SetCodeID:
+			db.SetCode(o.Addr, o.Code)
+		case SetNonceID:
+			db.SetNonce(o.Addr, o.Num)
+		case SetStateID:
+			db.SetState(o.Addr, o.Key, o.Value)
+		case SetTransientStateID:
+			db.SetTransientState(o.Addr, o.Key, o.Value)
+		case SnapshotID:
+			snapshots[o.Num] = db.Snapshot()
+		case SubBalanceID:
+			balanceDB := db
+			if shadowDB := db.GetShadowDB(); shadowDB != nil {
+				balanceDB = shadowDB
+			}
+			value := amountOf(o)
+			if balance := balanceDB.GetBalance(o.Addr); value.Gt(balance) {
+				value = balance.Clone()
+			}
+			db.SubBalance(o.Addr, value, 0)
+		case SelfDestructID:
+			db.SelfDestruct(o.Addr)
+		case SelfDestruct6780ID:
+			db.Selfdestruct6780(o.Addr)
+		default:
+			return fmt.Errorf("operation %v: unsupported operation %v", i, o.Op)
+		}
+		if err := db.Error(); err != nil {
+			return fmt.Errorf("operation %v (%v): %w", i, o, err)
+		}
+	}
+	return nil
+}
+
+// amountOf returns a copy of the balance delta of an operation.
+func amountOf(o Operation) *uint256.Int {
+	if o.Amount == nil {
+		return new(uint256.Int)
+	}
+	return o.Amount.Clone()
+}
+
+// opRange is a half-open range [from, to) of operations removed as one unit.
+type opRange struct {
+	from, to int
+}
+
+// transactionRanges returns the ranges of all complete transactions.
+func transactionRanges(ops []Operation) []opRange {
+	var (
+		ranges []opRange
+		begin  = -1
+	)
+	for i, o := range ops {
+		switch o.Op {
+		case BeginTransactionID:
+			begin = i
+		case EndTransactionID:
+			if begin >= 0 {
+				ranges = append(ranges, opRange{begin, i + 1})
+			}
+			begin = -1
+		}
+	}
+	return ranges
+}
+
+// operationRanges returns a range for each non-structural operation.
+func operationRanges(ops []Operation) []opRange {
+	var ranges []opRange
+	for i, o := range ops {
+		if !isStructural(o.Op) {
+			ranges = append(ranges, opRange{i, i + 1})
+		}
+	}
+	return ranges
+}
+
+// ShrinkOperations reduces a failing operation sequence to a smaller sequence
+// for which fails still returns true. It applies delta debugging (ddmin) first
+// to whole transactions and then to individual operations. Operations that
+// delimit sync-periods, blocks, and transactions are only removed together
+// with their transaction so that the result remains well-formed. The progress
+// function, if not nil, is called with the length of each smaller failing
+// sequence found.
+func ShrinkOperations(ops []Operation, fails func([]Operation) bool, progress func(int)) []Operation {
+	ops = ddmin(ops, transactionRanges(ops), fails, progress)
+	ops = ddmin(ops, operationRanges(ops), fails, progress)
+	return ops
+}
+
+// ddmin removes a 1-minimal subset of the given ranges from the operation
+// sequence such that the remaining sequence still fails.
+func ddmin(ops []Operation, ranges []opRange, fails func([]Operation) bool, progress func(int)) []Operation {
+	removed := make([]bool, len(ops))
+	n := 2
+	for len(ranges) > 0 {
+		chunk := (len(ranges) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(ranges); start += chunk {
+			end := min(start+chunk, len(ranges))
+			candidate := removeRanges(removed, ranges[start:end])
+			if seq := selectOperations(ops, candidate); fails(seq) {
+				removed = candidate
+				ranges = append(ranges[:start:start], ranges[end:]...)
+				n = max(n-1, 2)
+				reduced = true
+				if progress != nil {
+					progress(len(seq))
+				}
+				break
+			}
+		}
+		if !reduced {
+			if n >= len(ranges) {
+				break
+			}
+			n = min(2*n, len(ranges))
+		}
+	}
+	return selectOperations(ops, removed)
+}
+
+// removeRanges returns a copy of the removal mask with the given ranges removed.
+func removeRanges(removed []bool, ranges []opRange) []bool {
+	res := make([]bool, len(removed))
+	copy(res, removed)
+	for _, r := range ranges {
+		for i := r.from; i < r.to; i++ {
+			res[i] = true
+		}
+	}
+	return res
+}
+
+// selectOperations returns all operations that are not removed.
+func selectOperations(ops []Operation, removed []bool) []Operation {
+	res := make([]Operation, 0, len(ops))
+	for i, o := range ops {
+		if !removed[i] {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// operationTestTemplate is the template of a standalone Go test reproducing
+// a failing operation sequence.
+var operationTestTemplate = template.Must(template.New("test").Funcs(template.FuncMap{
+	"opName": func(op int) string { return opText[op] + "ID" },
+}).Parse(`// Code generated by aida-stochastic-sdb shrink. DO NOT EDIT.
+
+package stochastic_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+{{- if .HasAmount}}
+	"github.com/holiman/uint256"
+{{- end}}
+)
+
+// TestShrunkStochasticReplay replays a minimal operation sequence that caused
+// the StateDB to report an error in a stochastic replay. The test passes once
+// the error is fixed.
+func TestShrunkStochasticReplay(t *testing.T) {
+	cfg := &utils.Config{
+		ChainID:             {{.Cfg.ChainID}},
+		DbImpl:              {{printf "%q" .Cfg.DbImpl}},
+		DbVariant:           {{printf "%q" .Cfg.DbVariant}},
+		CarmenSchema:        {{.Cfg.CarmenSchema}},
+		ShadowDb:            {{.Cfg.ShadowDb}},
+		ShadowImpl:          {{printf "%q" .Cfg.ShadowImpl}},
+		ShadowVariant:       {{printf "%q" .Cfg.ShadowVariant}},
+		ValidateStateHashes: {{.Cfg.ValidateStateHashes}},
+		DbTmp:               t.TempDir(),
+		LogLevel:            "ERROR",
+	}
+	db, dbPath, err := utils.PrepareStateDB(cfg)
+	if err != nil {
+		t.Fatalf("cannot create StateDB; %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	ops := []stochastic.Operation{
+{{- range .Ops}}
+		{Op: stochastic.{{opName .Op}}
+			{{- if ne .Addr.Big.Sign 0}}, Addr: common.HexToAddress("{{.Addr.Hex}}"){{end}}
+			{{- if ne .Key.Big.Sign 0}}, Key: common.HexToHash("{{.Key.Hex}}"){{end}}
+			{{- if ne .Value.Big.Sign 0}}, Value: common.HexToHash("{{.Value.Hex}}"){{end}}
+			{{- if ne .Num 0}}, Num: {{.Num}}{{end}}
+			{{- if .Amount}}, Amount: uint256.MustFromDecimal("{{.Amount.Dec}}"){{end}}
+			{{- if .Code}}, Code: common.FromHex("{{printf "%x" .Code}}"){{end}}},
+{{- end}}
+	}
+	if err := stochastic.ReplayOperations(db, ops); err != nil {
+		t.Fatal(err)
+	}
+}
+`))
+
+// WriteOperationTest writes a standalone Go test replaying the operation
+// sequence on a StateDB configured like the given configuration.
+func WriteOperationTest(w io.Writer, ops []Operation, cfg *utils.Config) error {
+	hasAmount := false
+	for _, o := range ops {
+		hasAmount = hasAmount || o.Amount != nil
+	}
+	return operationTestTemplate.Execute(w, struct {
+		Cfg       *utils.Config
+		Ops       []Operation
+		HasAmount bool
+	}{cfg, ops, hasAmount})
+}
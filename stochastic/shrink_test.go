@@ -0,0 +1,189 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"go.uber.org/mock/gomock"
+)
+
+// makeTestOperations creates a sequence of n transactions in a single block,
+// each setting a storage slot of its own account.
+func makeTestOperations(n int) []Operation {
+	ops := []Operation{{Op: BeginSyncPeriodID}, {Op: BeginBlockID}}
+	for i := 0; i < n; i++ {
+		addr := toAddress(int64(i + 1))
+		ops = append(ops,
+			Operation{Op: BeginTransactionID, Num: uint64(i)},
+			Operation{Op: CreateAccountID, Addr: addr},
+			Operation{Op: AddBalanceID, Addr: addr, Amount: uint256.NewInt(10)},
+			Operation{Op: SetStateID, Addr: addr, Key: toHash(1), Value: toHash(int64(i))},
+			Operation{Op: EndTransactionID},
+		)
+	}
+	return append(ops, Operation{Op: EndBlockID}, Operation{Op: EndSyncPeriodID})
+}
+
+// TestShrinkOperations checks that the shrinker finds the minimal failing
+// sequence and keeps the sequence well-formed.
+func TestShrinkOperations(t *testing.T) {
+	ops := makeTestOperations(50)
+
+	// the sequence fails if account 7 is created and account 31 is written
+	contains := func(seq []Operation, op int, idx int64) bool {
+		for _, o := range seq {
+			if o.Op == op && o.Addr == toAddress(idx) {
+				return true
+			}
+		}
+		return false
+	}
+	fails := func(seq []Operation) bool {
+		return contains(seq, CreateAccountID, 7) && contains(seq, SetStateID, 31)
+	}
+
+	steps := 0
+	shrunk := ShrinkOperations(ops, fails, func(int) { steps++ })
+	if steps == 0 {
+		t.Errorf("progress was not reported")
+	}
+
+	want := []Operation{
+		{Op: BeginSyncPeriodID},
+		{Op: BeginBlockID},
+		{Op: BeginTransactionID, Num: 6},
+		{Op: CreateAccountID, Addr: toAddress(7)},
+		{Op: EndTransactionID},
+		{Op: BeginTransactionID, Num: 30},
+		{Op: SetStateID, Addr: toAddress(31), Key: toHash(1), Value: toHash(30)},
+		{Op: EndTransactionID},
+		{Op: EndBlockID},
+		{Op: EndSyncPeriodID},
+	}
+	if !reflect.DeepEqual(shrunk, want) {
+		t.Errorf("unexpected shrunk sequence\ngot:  %v\nwant: %v", shrunk, want)
+	}
+}
+
+// TestShrinkOperations_NoReduction checks that a sequence is kept if every
+// operation is needed for the failure.
+func TestShrinkOperations_NoReduction(t *testing.T) {
+	ops := makeTestOperations(3)
+	fails := func(seq []Operation) bool { return len(seq) == len(ops) }
+	if shrunk := ShrinkOperations(ops, fails, nil); !reflect.DeepEqual(shrunk, ops) {
+		t.Errorf("sequence must not be reduced, got %v", shrunk)
+	}
+}
+
+// TestOperationLog checks that logged operations are read back unchanged.
+func TestOperationLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ops.log")
+	opLog, err := NewOperationLog(filename)
+	if err != nil {
+		t.Fatalf("cannot create operation log; %v", err)
+	}
+	ops := makeTestOperations(5)
+	ops = append(ops, Operation{Op: SetCodeID, Addr: toAddress(3), Code: []byte{1, 2, 3}})
+	for _, op := range ops {
+		opLog.Append(op)
+	}
+	if err := opLog.Close(); err != nil {
+		t.Fatalf("cannot close operation log; %v", err)
+	}
+
+	got, err := ReadOperations(filename)
+	if err != nil {
+		t.Fatalf("cannot read operation log; %v", err)
+	}
+	if !reflect.DeepEqual(got, ops) {
+		t.Errorf("unexpected operations\ngot:  %v\nwant: %v", got, ops)
+	}
+}
+
+// TestWriteOperationTest checks that the generated test contains the
+// operations and the DB configuration.
+func TestWriteOperationTest(t *testing.T) {
+	ops := []Operation{
+		{Op: BeginSyncPeriodID},
+		{Op: SetStateID, Addr: common.HexToAddress("0x01"), Key: common.HexToHash("0x02"), Value: common.HexToHash("0x03")},
+		{Op: SetCodeID, Addr: common.HexToAddress("0x01"), Code: []byte{0xab, 0xcd}},
+		{Op: SubBalanceID, Addr: common.HexToAddress("0x01"), Amount: uint256.NewInt(42)},
+		{Op: SetNonceID, Addr: common.HexToAddress("0x01"), Num: 7},
+	}
+	cfg := &utils.Config{DbImpl: "carmen", DbVariant: "go-file", ShadowDb: true, ShadowImpl: "geth"}
+
+	var buf bytes.Buffer
+	if err := WriteOperationTest(&buf, ops, cfg); err != nil {
+		t.Fatalf("cannot write test; %v", err)
+	}
+	src := buf.String()
+	for _, want := range []string{
+		`DbImpl:              "carmen"`,
+		`ShadowImpl:          "geth"`,
+		`ShadowDb:            true`,
+		`{Op: stochastic.BeginSyncPeriodID},`,
+		`{Op: stochastic.SetStateID, Addr: common.HexToAddress("0x0000000000000000000000000000000000000001"), Key: common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000002"), Value: common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000003")},`,
+		`{Op: stochastic.SetCodeID, Addr: common.HexToAddress("0x0000000000000000000000000000000000000001"), Code: common.FromHex("abcd")},`,
+		`"github.com/holiman/uint256"`,
+		`{Op: stochastic.SubBalanceID, Addr: common.HexToAddress("0x0000000000000000000000000000000000000001"), Amount: uint256.MustFromDecimal("42")},`,
+		`{Op: stochastic.SetNonceID, Addr: common.HexToAddress("0x0000000000000000000000000000000000000001"), Num: 7},`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated test does not contain %q\n%v", want, src)
+		}
+	}
+}
+
+// TestReplayOperationsTransientStorage checks that logged transient storage
+// operations are replayed on the transient storage of the StateDB.
+func TestReplayOperationsTransientStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+
+	addr := common.HexToAddress("0x01")
+	key := common.HexToHash("0x02")
+	value := common.HexToHash("0x03")
+	gomock.InOrder(
+		db.EXPECT().SetTransientState(addr, key, value),
+		db.EXPECT().Error(),
+		db.EXPECT().GetTransientState(addr, key),
+		db.EXPECT().Error(),
+	)
+
+	ops := []Operation{
+		{Op: SetTransientStateID, Addr: addr, Key: key, Value: value},
+		{Op: GetTransientStateID, Addr: addr, Key: key},
+	}
+	if err := ReplayOperations(db, ops); err != nil {
+		t.Errorf("cannot replay transient storage operations; %v", err)
+	}
+	if got, want := ops[0].String(), "SetTransientState("+addr.Hex()+", "+key.Hex()+", "+value.Hex()+")"; got != want {
+		t.Errorf("unexpected text of operation, got %v, want %v", got, want)
+	}
+	if got, want := ops[1].String(), "GetTransientState("+addr.Hex()+", "+key.Hex()+")"; got != want {
+		t.Errorf("unexpected text of operation, got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package statistics
+
+import (
+	"math"
+)
+
+// LogLikelihood computes the log-likelihood per sample of a distribution for
+// a piecewise linear ECDF whose segments are treated as bins. The function
+// mass returns the probability of the distribution between two points.
+func LogLikelihood(ecdf [][2]float64, mass func(x0, x1 float64) float64) float64 {
+	ll := 0.0
+	for i := 1; i < len(ecdf); i++ {
+		p := ecdf[i][1] - ecdf[i-1][1]
+		if p <= 0 {
+			continue
+		}
+		q := mass(ecdf[i-1][0], ecdf[i][0])
+		if q <= 0 {
+			return math.Inf(-1)
+		}
+		ll += p * math.Log(q)
+	}
+	return ll
+}
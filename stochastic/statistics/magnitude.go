@@ -0,0 +1,69 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package statistics
+
+// Magnitude counts the bit lengths of observed values, i.e., an empirical
+// distribution of values on a logarithmic scale.
+type Magnitude struct {
+	freq []uint64 // frequency counts per bit length
+}
+
+// MagnitudeJSON is the JSON output for magnitude statistics.
+type MagnitudeJSON struct {
+	// frequency of values per bit length
+	Freq []uint64 `json:"freq"`
+}
+
+// NewMagnitude creates a new magnitude statistics for values with up to maxBits bits.
+func NewMagnitude(maxBits int) Magnitude {
+	return Magnitude{make([]uint64, maxBits+1)}
+}
+
+// Place counts a value with the given bit length. Bit lengths beyond the
+// maximum are counted as the maximum.
+func (m *Magnitude) Place(bitLen int) {
+	if bitLen < 0 {
+		bitLen = 0
+	} else if bitLen >= len(m.freq) {
+		bitLen = len(m.freq) - 1
+	}
+	m.freq[bitLen]++
+}
+
+// NewMagnitudeJSON produces JSON output for a magnitude statistics.
+func (m *Magnitude) NewMagnitudeJSON() MagnitudeJSON {
+	freq := make([]uint64, len(m.freq))
+	copy(freq, m.freq)
+	return MagnitudeJSON{Freq: freq}
+}
+
+// Distribution returns the probabilities of the bit lengths or nil if no
+// value was observed.
+func (m *MagnitudeJSON) Distribution() []float64 {
+	total := uint64(0)
+	for _, freq := range m.Freq {
+		total += freq
+	}
+	if total == 0 {
+		return nil
+	}
+	dist := make([]float64, len(m.Freq))
+	for i, freq := range m.Freq {
+		dist[i] = float64(freq) / float64(total)
+	}
+	return dist
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package statistics
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMagnitudePlace checks counting of bit lengths including clamping.
+func TestMagnitudePlace(t *testing.T) {
+	m := NewMagnitude(4)
+	m.Place(0)
+	m.Place(2)
+	m.Place(2)
+	m.Place(9)
+	m.Place(-1)
+	got := m.NewMagnitudeJSON()
+	if want := []uint64{2, 0, 2, 0, 1}; !reflect.DeepEqual(got.Freq, want) {
+		t.Fatalf("unexpected frequencies %v, want %v", got.Freq, want)
+	}
+	if want := []float64{0.4, 0, 0.4, 0, 0.2}; !reflect.DeepEqual(got.Distribution(), want) {
+		t.Fatalf("unexpected distribution %v, want %v", got.Distribution(), want)
+	}
+}
+
+// TestMagnitudeEmpty checks that an empty statistics has no distribution.
+func TestMagnitudeEmpty(t *testing.T) {
+	m := NewMagnitude(8)
+	js := m.NewMagnitudeJSON()
+	if dist := js.Distribution(); dist != nil {
+		t.Fatalf("empty statistics must not have a distribution, got %v", dist)
+	}
+}
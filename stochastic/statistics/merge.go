@@ -0,0 +1,149 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package statistics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/stochastic/empirical"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/simplify"
+)
+
+// MergeAccessJSON merges the access statistics of separate recordings with
+// the given weights.
+func MergeAccessJSON(stats []AccessJSON, weights []float64) AccessJSON {
+	counting := make([]CountingJSON, len(stats))
+	queuing := make([]QueuingJSON, len(stats))
+	for i := range stats {
+		counting[i] = stats[i].Counting
+		queuing[i] = stats[i].Queuing
+	}
+	return AccessJSON{
+		Counting: MergeCountingJSON(counting, weights),
+		Queuing:  MergeQueuingJSON(queuing, weights),
+	}
+}
+
+// MergeCountingJSON merges the counting statistics of separate recordings.
+// The ECDF is the mixture of the ECDFs with the given weights. Since the data
+// items of the recordings are not known, the number of keys is the maximum
+// number of keys assuming that the recordings access the same data items.
+func MergeCountingJSON(stats []CountingJSON, weights []float64) CountingJSON {
+	numKeys := int64(0)
+	ecdfs := make([][][2]float64, len(stats))
+	for i := range stats {
+		numKeys = max(numKeys, stats[i].NumKeys)
+		ecdfs[i] = stats[i].ECdf
+	}
+	return CountingJSON{
+		NumKeys: numKeys,
+		ECdf:    MergeECdf(ecdfs, weights),
+	}
+}
+
+// MergeQueuingJSON merges the queuing statistics of separate recordings to
+// the mixture of their distributions with the given weights. Recordings
+// without hits in the queue are ignored.
+func MergeQueuingJSON(stats []QueuingJSON, weights []float64) QueuingJSON {
+	dist := make([]float64, QueueLen)
+	total := 0.0
+	for i := range stats {
+		sum := 0.0
+		for _, p := range stats[i].Distribution {
+			sum += p
+		}
+		if sum == 0 || weights[i] == 0 {
+			continue
+		}
+		for j := 0; j < QueueLen && j < len(stats[i].Distribution); j++ {
+			dist[j] += weights[i] * stats[i].Distribution[j] / sum
+		}
+		total += weights[i]
+	}
+	if total > 0 {
+		for j := range dist {
+			dist[j] /= total
+		}
+	}
+	return QueuingJSON{Distribution: dist}
+}
+
+// MergeMagnitudeJSON merges magnitude statistics of separate recordings by
+// summing their frequencies scaled by the given weights.
+func MergeMagnitudeJSON(stats []MagnitudeJSON, weights []float64) MagnitudeJSON {
+	n := 0
+	for i := range stats {
+		n = max(n, len(stats[i].Freq))
+	}
+	freq := make([]uint64, n)
+	for i := range stats {
+		for j, f := range stats[i].Freq {
+			freq[j] += uint64(math.Round(weights[i] * float64(f)))
+		}
+	}
+	return MagnitudeJSON{Freq: freq}
+}
+
+// MergeECdf merges empirical cumulative distribution functions, which are
+// piecewise-linear functions on the unit interval, to their mixture with the
+// given weights. Empty functions are ignored. The mixture is simplified to
+// NumDistributionPoints points.
+func MergeECdf(ecdfs [][][2]float64, weights []float64) [][2]float64 {
+	// collect the points of all functions
+	xs := []float64{}
+	total := 0.0
+	for i := range ecdfs {
+		if len(ecdfs[i]) == 0 || weights[i] == 0 {
+			continue
+		}
+		for _, p := range ecdfs[i] {
+			xs = append(xs, p[0])
+		}
+		total += weights[i]
+	}
+	if total == 0 {
+		return [][2]float64{}
+	}
+	sort.Float64s(xs)
+
+	// evaluate the mixture at all points
+	ls := orb.LineString{}
+	for k, x := range xs {
+		if k > 0 && xs[k-1] == x {
+			continue
+		}
+		y := 0.0
+		for i := range ecdfs {
+			if len(ecdfs[i]) == 0 || weights[i] == 0 {
+				continue
+			}
+			y += weights[i] * empirical.Cdf(ecdfs[i], x)
+		}
+		ls = append(ls, orb.Point{x, y / total})
+	}
+
+	// reduce the mixture using the Visvalingam-Whyatt algorithm
+	simplifier := simplify.VisvalingamKeep(NumDistributionPoints)
+	simplified := simplifier.Simplify(ls).(orb.LineString)
+	eCdf := make([][2]float64, len(simplified))
+	for i := range simplified {
+		eCdf[i] = [2]float64(simplified[i])
+	}
+	return eCdf
+}
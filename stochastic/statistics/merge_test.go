@@ -0,0 +1,76 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package statistics
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMergeECdf checks the mixture of two ECDFs.
+func TestMergeECdf(t *testing.T) {
+	a := [][2]float64{{0, 0}, {0.5, 1}, {1, 1}}
+	b := [][2]float64{{0, 0}, {1, 1}}
+	merged := MergeECdf([][][2]float64{a, b, {}}, []float64{1, 3, 1})
+	want := map[float64]float64{0: 0, 0.5: 0.25*1 + 0.75*0.5, 1: 1}
+	if len(merged) != len(want) {
+		t.Fatalf("unexpected number of points %v", merged)
+	}
+	for _, p := range merged {
+		if y, ok := want[p[0]]; !ok || math.Abs(p[1]-y) > 1e-9 {
+			t.Errorf("unexpected point %v", p)
+		}
+	}
+	if merged := MergeECdf([][][2]float64{{}}, []float64{1}); len(merged) != 0 {
+		t.Errorf("merging empty ECDFs must be empty")
+	}
+}
+
+// TestMergeQueuingJSON checks the mixture of queuing distributions.
+func TestMergeQueuingJSON(t *testing.T) {
+	a := QueuingJSON{Distribution: make([]float64, QueueLen)}
+	b := QueuingJSON{Distribution: make([]float64, QueueLen)}
+	empty := QueuingJSON{Distribution: make([]float64, QueueLen)}
+	a.Distribution[1] = 1
+	b.Distribution[2] = 1
+	merged := MergeQueuingJSON([]QueuingJSON{a, b, empty}, []float64{1, 3, 5})
+	if merged.Distribution[1] != 0.25 || merged.Distribution[2] != 0.75 {
+		t.Errorf("unexpected distribution %v", merged.Distribution)
+	}
+}
+
+// TestMergeCountingJSON checks that the maximum number of keys is kept.
+func TestMergeCountingJSON(t *testing.T) {
+	a := CountingJSON{NumKeys: 10, ECdf: [][2]float64{{0, 0}, {1, 1}}}
+	b := CountingJSON{NumKeys: 20, ECdf: [][2]float64{{0, 0}, {1, 1}}}
+	if merged := MergeCountingJSON([]CountingJSON{a, b}, []float64{1, 1}); merged.NumKeys != 20 {
+		t.Errorf("unexpected number of keys %v", merged.NumKeys)
+	}
+}
+
+// TestMergeMagnitudeJSON checks the weighted sum of magnitude frequencies.
+func TestMergeMagnitudeJSON(t *testing.T) {
+	a := MagnitudeJSON{Freq: []uint64{1, 2}}
+	b := MagnitudeJSON{Freq: []uint64{3, 4, 5}}
+	merged := MergeMagnitudeJSON([]MagnitudeJSON{a, b}, []float64{2, 1})
+	want := []uint64{5, 8, 5}
+	for i := range want {
+		if merged.Freq[i] != want[i] {
+			t.Fatalf("unexpected frequencies %v, want %v", merged.Freq, want)
+		}
+	}
+}
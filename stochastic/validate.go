@@ -0,0 +1,281 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic/stationary"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	substatecontext "github.com/Fantom-foundation/Aida/txcontext/substate"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/substate"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// ValidationResult is the divergence of a statistic of a simulation from
+// the recorded statistic.
+type ValidationResult struct {
+	Statistic  string  // name of the compared statistic
+	Test       string  // applied test
+	Divergence float64 // divergence between simulation and recording
+	Threshold  float64 // maximum divergence accepted by the test
+	Detail     string  // additional test details
+}
+
+// Diverges returns true if the divergence exceeds the threshold of the test.
+func (r ValidationResult) Diverges() bool {
+	return r.Divergence > r.Threshold
+}
+
+// ValidationThresholds are the maximum divergences accepted by each test. The
+// tests report divergences on different scales: Cohen's w is unbounded, while
+// the total variation and Kolmogorov-Smirnov distances are within [0,1].
+type ValidationThresholds struct {
+	CohensW        float64 // chi-square test of operation frequencies
+	TotalVariation float64 // stationary distributions
+	KsDistance     float64 // access-class distributions
+}
+
+// SimulateEvents runs a simulation model for a number of blocks on an
+// in-memory StateDB and counts the generated events with an event proxy.
+// Priming operations are not counted.
+func SimulateEvents(e *EstimationModelJSON, nBlocks int, cfg *utils.Config, log logger.Logger) (*EventRegistry, error) {
+	BalanceRange = cfg.BalanceRange
+	NonceRange = cfg.NonceRange
+	rg := rand.New(rand.NewSource(cfg.RandomSeed))
+
+	model, err := newMarkovModel(e)
+	if err != nil {
+		return nil, err
+	}
+
+	// prime an in-memory StateDB and count events afterwards
+	db := state.MakeInMemoryStateDB(substatecontext.NewWorldState(substate.WorldState{}), 0)
+	ss, err := createState(cfg, e, db, rg, nil, log)
+	if err != nil {
+		return nil, err
+	}
+	registry := NewEventRegistry()
+	ss.db = NewEventProxy(db, &registry)
+
+	operations, _, st := getStochasticMatrix(e)
+	for block := 0; block < nBlocks; {
+		op, addrCl, keyCl, valueCl := DecodeOpcode(operations[st])
+		ss.execute(op, addrCl, keyCl, valueCl)
+		if op == EndBlockID {
+			block++
+		}
+		next := model.next(rg, st)
+		if next == -1 {
+			return nil, fmt.Errorf("stochastic matrix is broken after operation %v", operations[st])
+		}
+		st = next
+	}
+	return &registry, nil
+}
+
+// ValidateSimulation compares the events of a simulation with the recorded
+// events. Operation frequencies are compared with the frequencies expected
+// from the stationary distribution of the recording by a chi-square test
+// (reporting Cohen's w as divergence), stationary distributions by their total
+// variation distance, and access-class distributions by Kolmogorov-Smirnov
+// statistics. Each result carries the threshold of its test.
+func ValidateSimulation(recorded *EventRegistryJSON, simulated *EventRegistry, thresholds ValidationThresholds) ([]ValidationResult, error) {
+	results := []ValidationResult{}
+
+	// stationary distribution of the recording
+	recordedDist, err := stationary.ComputeDistribution(recorded.StochasticMatrix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute stationary distribution of recording; %v", err)
+	}
+
+	// operation frequencies
+	res, err := chiSquareOperations(recorded.Operations, recordedDist, simulated)
+	if err != nil {
+		return nil, err
+	}
+	res.Threshold = thresholds.CohensW
+	results = append(results, res)
+
+	// stationary distributions
+	simulatedJSON := simulated.NewEventRegistryJSON()
+	simulatedDist, err := stationary.ComputeDistribution(simulatedJSON.StochasticMatrix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute stationary distribution of simulation; %v", err)
+	}
+	results = append(results, ValidationResult{
+		Statistic:  "stationary distribution",
+		Test:       "TV",
+		Divergence: totalVariation(toDistribution(recorded.Operations, recordedDist), toDistribution(simulatedJSON.Operations, simulatedDist)),
+		Threshold:  thresholds.TotalVariation,
+	})
+
+	// access-class distributions
+	for _, access := range []struct {
+		name                string
+		recorded, simulated statistics.AccessJSON
+	}{
+		{"contract", recorded.Contracts, simulatedJSON.Contracts},
+		{"key", recorded.Keys, simulatedJSON.Keys},
+		{"value", recorded.Values, simulatedJSON.Values},
+	} {
+		results = append(results,
+			ValidationResult{
+				Statistic:  access.name + " counting",
+				Test:       "KS",
+				Divergence: ksECdf(access.recorded.Counting.ECdf, access.simulated.Counting.ECdf),
+				Threshold:  thresholds.KsDistance,
+			},
+			ValidationResult{
+				Statistic:  access.name + " queuing",
+				Test:       "KS",
+				Divergence: ksDistribution(access.recorded.Queuing.Distribution, access.simulated.Queuing.Distribution),
+				Threshold:  thresholds.KsDistance,
+			},
+		)
+	}
+	return results, nil
+}
+
+// chiSquareOperations tests the simulated operation frequencies against the
+// frequencies expected from the stationary distribution of the recording.
+func chiSquareOperations(labels []string, dist []float64, simulated *EventRegistry) (ValidationResult, error) {
+	expected := make([]float64, numArgOps)
+	for i, label := range labels {
+		op, addr, key, value := DecodeOpcode(label)
+		expected[EncodeArgOp(op, addr, key, value)] = dist[i]
+	}
+	total := uint64(0)
+	for _, freq := range simulated.argOpFreq {
+		total += freq
+	}
+	if total == 0 {
+		return ValidationResult{}, fmt.Errorf("simulation produced no operations")
+	}
+
+	chi2 := 0.0
+	df := -1
+	unexpected := uint64(0)
+	for argop, freq := range simulated.argOpFreq {
+		if expected[argop] == 0 {
+			unexpected += freq
+			continue
+		}
+		e := expected[argop] * float64(total)
+		chi2 += (float64(freq) - e) * (float64(freq) - e) / e
+		df++
+	}
+	pValue := 1.0
+	if df > 0 {
+		pValue = 1 - distuv.ChiSquared{K: float64(df)}.CDF(chi2)
+	}
+	detail := fmt.Sprintf("chi2=%.1f df=%v p=%.3g", chi2, df, pValue)
+	w := math.Sqrt(chi2 / float64(total))
+	if unexpected > 0 {
+		// operations that were never recorded make the simulation diverge
+		detail = fmt.Sprintf("%v, %v unrecorded operations", detail, unexpected)
+		w = math.Inf(1)
+	}
+	return ValidationResult{
+		Statistic:  "operation frequencies",
+		Test:       "chi2 (Cohen's w)",
+		Divergence: w,
+		Detail:     detail,
+	}, nil
+}
+
+// toDistribution maps the operation labels to their probabilities.
+func toDistribution(labels []string, dist []float64) map[string]float64 {
+	res := make(map[string]float64, len(labels))
+	for i, label := range labels {
+		res[label] = dist[i]
+	}
+	return res
+}
+
+// totalVariation computes the total variation distance of two discrete distributions.
+func totalVariation(p, q map[string]float64) float64 {
+	labels := map[string]struct{}{}
+	for label := range p {
+		labels[label] = struct{}{}
+	}
+	for label := range q {
+		labels[label] = struct{}{}
+	}
+	keys := make([]string, 0, len(labels))
+	for label := range labels {
+		keys = append(keys, label)
+	}
+	sort.Strings(keys)
+	d := 0.0
+	for _, label := range keys {
+		d += math.Abs(p[label] - q[label])
+	}
+	return d / 2
+}
+
+// ksECdf computes the Kolmogorov-Smirnov statistic of two piecewise-linear
+// empirical cumulative distribution functions given by their points.
+func ksECdf(f, g [][2]float64) float64 {
+	d := 0.0
+	for _, p := range f {
+		d = math.Max(d, math.Abs(p[1]-interpolate(g, p[0])))
+	}
+	for _, p := range g {
+		d = math.Max(d, math.Abs(p[1]-interpolate(f, p[0])))
+	}
+	return d
+}
+
+// interpolate evaluates a piecewise-linear function given by its points at x.
+// An empty function is treated as the distribution with all mass at zero.
+func interpolate(f [][2]float64, x float64) float64 {
+	if len(f) == 0 {
+		return 1
+	}
+	i := sort.Search(len(f), func(i int) bool { return f[i][0] >= x })
+	switch {
+	case i == len(f):
+		return f[len(f)-1][1]
+	case f[i][0] == x || i == 0:
+		return f[i][1]
+	}
+	x0, y0, x1, y1 := f[i-1][0], f[i-1][1], f[i][0], f[i][1]
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
+
+// ksDistribution computes the Kolmogorov-Smirnov statistic of two discrete
+// distributions over the same ordered domain.
+func ksDistribution(p, q []float64) float64 {
+	d, cp, cq := 0.0, 0.0, 0.0
+	for i := 0; i < max(len(p), len(q)); i++ {
+		if i < len(p) {
+			cp += p[i]
+		}
+		if i < len(q) {
+			cq += q[i]
+		}
+		d = math.Max(d, math.Abs(cp-cq))
+	}
+	return d
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math"
+	"testing"
+)
+
+// TestKsECdf checks the Kolmogorov-Smirnov statistic of piecewise-linear ECDFs.
+func TestKsECdf(t *testing.T) {
+	uniform := [][2]float64{{0, 0}, {1, 1}}
+	if d := ksECdf(uniform, uniform); d != 0 {
+		t.Errorf("identical ECDFs must have distance zero, got %v", d)
+	}
+	skewed := [][2]float64{{0, 0}, {0.5, 0.9}, {1, 1}}
+	if d := ksECdf(uniform, skewed); math.Abs(d-0.4) > 1e-9 {
+		t.Errorf("unexpected distance %v, want 0.4", d)
+	}
+	if d := ksECdf(skewed, uniform); math.Abs(d-0.4) > 1e-9 {
+		t.Errorf("distance must be symmetric, got %v", d)
+	}
+}
+
+// TestKsDistribution checks the Kolmogorov-Smirnov statistic of discrete distributions.
+func TestKsDistribution(t *testing.T) {
+	p := []float64{0.5, 0.5, 0}
+	q := []float64{0.2, 0.3, 0.5}
+	if d := ksDistribution(p, q); math.Abs(d-0.5) > 1e-9 {
+		t.Errorf("unexpected distance %v, want 0.5", d)
+	}
+	if d := ksDistribution(p, p); d != 0 {
+		t.Errorf("identical distributions must have distance zero, got %v", d)
+	}
+}
+
+// TestChiSquareOperations checks the comparison of operation frequencies.
+func TestChiSquareOperations(t *testing.T) {
+	r := NewEventRegistry()
+	for i := 0; i < 100; i++ {
+		r.RegisterOp(BeginBlockID)
+		r.RegisterOp(EndBlockID)
+	}
+	labels := []string{"BB", "EB"}
+
+	res, err := chiSquareOperations(labels, []float64{0.5, 0.5}, &r)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if res.Divergence != 0 {
+		t.Errorf("matching frequencies must not diverge, got %v (%v)", res.Divergence, res.Detail)
+	}
+
+	res, err = chiSquareOperations(labels, []float64{0.9, 0.1}, &r)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	// w = sqrt(((100-180)^2/180 + (100-20)^2/20) / 200)
+	if want := math.Sqrt((6400.0/180 + 6400.0/20) / 200); math.Abs(res.Divergence-want) > 1e-9 {
+		t.Errorf("unexpected divergence %v, want %v", res.Divergence, want)
+	}
+
+	res, err = chiSquareOperations([]string{"BB"}, []float64{1}, &r)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if !math.IsInf(res.Divergence, 1) {
+		t.Errorf("unrecorded operations must diverge, got %v", res.Divergence)
+	}
+}
+
+// TestValidationResultDiverges checks that divergences are compared with the threshold of their test.
+func TestValidationResultDiverges(t *testing.T) {
+	// Cohen's w may exceed the bounded distances by far
+	if !(ValidationResult{Divergence: math.Inf(1), Threshold: 0.3}).Diverges() {
+		t.Errorf("unrecorded operations must diverge")
+	}
+	if (ValidationResult{Divergence: 0.2, Threshold: 0.3}).Diverges() {
+		t.Errorf("divergence below its threshold must be accepted")
+	}
+	if !(ValidationResult{Divergence: 0.2, Threshold: 0.1}).Diverges() {
+		t.Errorf("divergence above its threshold must be rejected")
+	}
+}
@@ -18,50 +18,49 @@ package visualizer
 
 import (
 	"bytes"
+	"fmt"
+	"html"
+	"io"
 	"strings"
 
 	"github.com/goccy/go-graphviz"
 	"github.com/goccy/go-graphviz/cgraph"
 )
 
-// preGraphHtml is the preamble for an HTML page rending a dot graph.
+// preGraphHtml is the preamble for an HTML page embedding an SVG graph.
 const preGraphHtml = `
 <!DOCTYPE html>
 <html>
 <head>
     <meta charset="UTF-8">
     <title>TITLE</title>
-
-    <script>
-        const dot = ` + "`"
-
-// postGraphHtml is the postamble for an HTML page rending a dot graph.
-const postGraphHtml = "`" + `;
-    </script>
 </head>
 
 <body>
     <h1>TITLE</h1>
-    <div id="graph"></div>
-    <script type="module">
-        import { Graphviz } from "https://cdn.jsdelivr.net/npm/@hpcc-js/wasm/dist/index.js";
-        if (Graphviz) {
-            const graphviz = await Graphviz.load();
-            const svg = graphviz.layout(dot, "svg", "dot");
-	    document.getElementById("graph").innerHTML = svg;
-        } 
-    </script>
+    <div id="graph">
+`
+
+// postGraphHtml is the postamble for an HTML page embedding an SVG graph.
+const postGraphHtml = `
+    </div>
 </body>
 </html>
 `
 
-// renderDotGraph renders a dotgraph as a HTML document.
-func renderDotGraph(title string, g *graphviz.Graphviz, graph *cgraph.Graph) (string, error) {
-	preamble := strings.Replace(preGraphHtml, "TITLE", title, -1)
-	postamble := strings.Replace(postGraphHtml, "TITLE", title, -1)
+// renderSvgGraph lays out a dot graph with graphviz and renders it as an HTML
+// document embedding the graph as SVG, so that no scripts are needed to view it.
+func renderSvgGraph(w io.Writer, title string, g *graphviz.Graphviz, graph *cgraph.Graph) error {
 	var buf bytes.Buffer
-	if err := g.Render(graph, "dot", &buf); err != nil {
-		return "", err
+	if err := g.Render(graph, graphviz.SVG, &buf); err != nil {
+		return err
+	}
+	// strip the XML prolog preceding the SVG element
+	svg := buf.String()
+	if i := strings.Index(svg, "<svg"); i > 0 {
+		svg = svg[i:]
 	}
-	return preamble + buf.String() + postamble, nil
+	title = html.EscapeString(title)
+	_, err := fmt.Fprint(w, strings.Replace(preGraphHtml, "TITLE", title, -1)+svg+postGraphHtml)
+	return err
 }
@@ -18,7 +18,10 @@ package visualizer
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/Fantom-foundation/Aida/stochastic"
 	"github.com/go-echarts/go-echarts/v2/charts"
@@ -38,34 +41,69 @@ const txoperationRef = "tx-operation-stats"
 const simplifiedMarkovRef = "simplified-markov-stats"
 const markovRef = "markov-stats"
 
-// MainHtml is the index page.
-const MainHtml = `
+// remoteAssetsHost is the host from which the echarts assets of an exported report are fetched.
+const remoteAssetsHost = "https://go-echarts.github.io/go-echarts-assets/assets/"
+
+// reportAssetsDir is the directory of the echarts assets relative to the pages of an exported report.
+const reportAssetsDir = "assets/"
+
+// reportAssets are the echarts assets referenced by the charts of the pages.
+var reportAssets = []string{"echarts.min.js", "themes/" + types.ThemeChalk + ".js"}
+
+// assetsHost is the host of the echarts assets referenced by the rendered charts.
+// If empty, the charts reference the remote host of go-echarts.
+var assetsHost string
+
+// page is a rendered page of the visualizer.
+type page struct {
+	ref    string                  // HTML reference of the page
+	title  string                  // title of the page in the index
+	render func(w io.Writer) error // renders the page
+}
+
+// pages are the rendered pages in the order of the index.
+var pages = []page{
+	{countingRef, "Counting Statistics", renderCounting},
+	{queuingRef, "Queuing Statistics", renderQueuing},
+	{snapshotRef, "Snapshot Statistics", renderSnapshotStats},
+	{txoperationRef, "Transactional Operation Statistics", renderTransactionalOperationStats},
+	{operationRef, "Operation Statistics", renderOperationStats},
+	{simplifiedMarkovRef, "Simplified Markov Chain", renderSimplifiedMarkovChain},
+	{markovRef, "Markov Chain", renderMarkovChain},
+}
+
+// preMainHtml is the preamble of the index page.
+const preMainHtml = `
 <!DOCTYPE html>
 <html lang="en">
   <head>
     <meta charset="utf-8">
     <title>Aida: Stochastic Estimator</title>
-    <link rel="stylesheet" href="style.css">
-    <script src="script.js"></script>
   </head>
   <body>
     <h1>Aida: Stochastic Estimator</h1>
     <ul>
-    <li> <h3> <a href="/` + countingRef + `"> Counting Statistics </a> </h3> </li>
-    <li> <h3> <a href="/` + queuingRef + `"> Queuing Statistics </a> </h3> </li>
-    <li> <h3> <a href="/` + snapshotRef + `"> Snapshot Statistics </a> </h3> </li>
-    <li> <h3> <a href="/` + txoperationRef + `"> Transactional Operation Statistics  </a> </h3> </li>
-    <li> <h3> <a href="/` + operationRef + `"> Operation Statistics  </a> </h3> </li>
-    <li> <h3> <a href="/` + simplifiedMarkovRef + `"> Simplified Markov Chain </a> </h3> </li>
-    <li> <h3> <a href="/` + markovRef + `"> Markov Chain </a> </h3> </li>
-    </ul>
+`
+
+// postMainHtml is the postamble of the index page.
+const postMainHtml = `    </ul>
 </body>
 </html>
 `
 
-// renderMain renders the main menu.
-func renderMain(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, MainHtml)
+// renderMain renders the index page whose entries link the pages with the
+// given function.
+func renderMain(w io.Writer, link func(ref string) string) error {
+	if _, err := fmt.Fprint(w, preMainHtml); err != nil {
+		return err
+	}
+	for _, p := range pages {
+		if _, err := fmt.Fprintf(w, "    <li> <h3> <a href=\"%v\"> %v </a> </h3> </li>\n", link(p.ref), p.title); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprint(w, postMainHtml)
+	return err
 }
 
 // convertCountingData converts CDF points to chart points.
@@ -81,7 +119,8 @@ func convertCountingData(data [][2]float64) []opts.LineData {
 func newCountingChart(title string, subtitle string, lambda float64, ecdf [][2]float64, cdf [][2]float64) *charts.Line {
 	chart := charts.NewLine()
 	chart.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
-		Theme: types.ThemeChalk,
+		Theme:      types.ThemeChalk,
+		AssetsHost: assetsHost,
 	}),
 		charts.WithToolboxOpts(opts.Toolbox{
 			Show: true,
@@ -106,7 +145,7 @@ func newCountingChart(title string, subtitle string, lambda float64, ecdf [][2]f
 }
 
 // renderCounting renders counting statistics.
-func renderCounting(w http.ResponseWriter, r *http.Request) {
+func renderCounting(w io.Writer) error {
 	events := GetEventsData()
 	contracts := newCountingChart("Counting Statistics", "for Contract-Addresses",
 		events.Contracts.Lambda,
@@ -123,15 +162,17 @@ func renderCounting(w http.ResponseWriter, r *http.Request) {
 
 	// TODO: Set HTML title via GlobalOption
 	page := components.NewPage()
+	page.AssetsHost = assetsHost
 	page.AddCharts(contracts, keys, values)
-	page.Render(w)
+	return page.Render(w)
 }
 
 // renderSnapshotStast renders a line chart for a snapshot statistics
-func renderSnapshotStats(w http.ResponseWriter, r *http.Request) {
+func renderSnapshotStats(w io.Writer) error {
 	chart := charts.NewLine()
 	chart.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
-		Theme: types.ThemeChalk,
+		Theme:      types.ThemeChalk,
+		AssetsHost: assetsHost,
 	}),
 		charts.WithToolboxOpts(opts.Toolbox{
 			Show: true,
@@ -153,7 +194,7 @@ func renderSnapshotStats(w http.ResponseWriter, r *http.Request) {
 	events := GetEventsData()
 	sLambda := fmt.Sprintf("%v", events.Snapshot.Lambda)
 	chart.AddSeries("eCDF", convertCountingData(events.Snapshot.ECdf)).AddSeries("CDF, λ="+sLambda, convertCountingData(events.Snapshot.Cdf))
-	chart.Render(w)
+	return chart.Render(w)
 }
 
 // convertQueuingData rendering plot data for the queuing statistics.
@@ -166,12 +207,13 @@ func convertQueuingData(data []float64) []opts.ScatterData {
 }
 
 // renderQueuing renders a queuing statistics.
-func renderQueuing(w http.ResponseWriter, r *http.Request) {
+func renderQueuing(w io.Writer) error {
 	events := GetEventsData()
 	scatter := charts.NewScatter()
 	scatter.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
-		Theme:     types.ThemeChalk,
-		PageTitle: "Queuing Probabilities",
+		Theme:      types.ThemeChalk,
+		AssetsHost: assetsHost,
+		PageTitle:  "Queuing Probabilities",
 	}),
 		charts.WithToolboxOpts(opts.Toolbox{
 			Show: true,
@@ -191,7 +233,7 @@ func renderQueuing(w http.ResponseWriter, r *http.Request) {
 			Subtitle: "for contract-addresses, storage-keys, and storage-values",
 		}))
 	scatter.AddSeries("Contract", convertQueuingData(events.Contracts.QPdf)).AddSeries("Keys", convertQueuingData(events.Keys.QPdf)).AddSeries("Values", convertQueuingData(events.Values.QPdf))
-	scatter.Render(w)
+	return scatter.Render(w)
 }
 
 // convertOperationData produces the data series for the sationary distribution.
@@ -213,13 +255,14 @@ func convertOperationLabel(data []OpData) []string {
 }
 
 // renderOperationStats renders the stationary distribution.
-func renderOperationStats(w http.ResponseWriter, r *http.Request) {
+func renderOperationStats(w io.Writer) error {
 	events := GetEventsData()
 	bar := charts.NewBar()
 	bar.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
-		Theme:     types.ThemeChalk,
-		PageTitle: "StateDB Operations",
-		Height:    "1300px",
+		Theme:      types.ThemeChalk,
+		AssetsHost: assetsHost,
+		PageTitle:  "StateDB Operations",
+		Height:     "1300px",
 	}),
 		charts.WithToolboxOpts(opts.Toolbox{
 			Show: true,
@@ -239,18 +282,19 @@ func renderOperationStats(w http.ResponseWriter, r *http.Request) {
 		}))
 	bar.SetXAxis(convertOperationLabel(events.Stationary)).AddSeries("Stationary Distribution", convertOperationData(events.Stationary))
 	bar.XYReversal()
-	bar.Render(w)
+	return bar.Render(w)
 }
 
 // renderTransactionalOperationStats renders the average number of operations per transaction.
-func renderTransactionalOperationStats(w http.ResponseWriter, r *http.Request) {
+func renderTransactionalOperationStats(w io.Writer) error {
 	events := GetEventsData()
 	title := fmt.Sprintf("Average %.1f Tx/Bl; %.1f Bl/Ep", events.TxPerBlock, events.BlocksPerSyncPeriod)
 	bar := charts.NewBar()
 	bar.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
-		Theme:     types.ThemeChalk,
-		PageTitle: title,
-		Height:    "1300px",
+		Theme:      types.ThemeChalk,
+		AssetsHost: assetsHost,
+		PageTitle:  title,
+		Height:     "1300px",
 	}),
 		charts.WithToolboxOpts(opts.Toolbox{
 			Show: true,
@@ -269,11 +313,11 @@ func renderTransactionalOperationStats(w http.ResponseWriter, r *http.Request) {
 			Title: title,
 		}))
 	bar.SetXAxis(convertOperationLabel(events.TxOperation)).AddSeries("Ops/Tx", convertOperationData(events.TxOperation))
-	bar.Render(w)
+	return bar.Render(w)
 }
 
 // renderSimplifiedMarkovChain renders a reduced markov chain whose nodes have no argument classes.
-func renderSimplifiedMarkovChain(w http.ResponseWriter, r *http.Request) {
+func renderSimplifiedMarkovChain(w io.Writer) error {
 	events := GetEventsData()
 	g := graphviz.New()
 	graph, _ := g.Graph()
@@ -308,12 +352,11 @@ func renderSimplifiedMarkovChain(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	txt, _ := renderDotGraph("StateDB Simplified Markov-Chain", g, graph)
-	fmt.Fprint(w, txt)
+	return renderSvgGraph(w, "StateDB Simplified Markov-Chain", g, graph)
 }
 
 // renderMarkovChain renders a markov chain.
-func renderMarkovChain(w http.ResponseWriter, r *http.Request) {
+func renderMarkovChain(w io.Writer) error {
 	events := GetEventsData()
 	g := graphviz.New()
 	graph, _ := g.Graph()
@@ -349,8 +392,7 @@ func renderMarkovChain(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	txt, _ := renderDotGraph("StateDB Markov-Chain", g, graph)
-	fmt.Fprint(w, txt)
+	return renderSvgGraph(w, "StateDB Markov-Chain", g, graph)
 }
 
 // FireUpWeb produces a data model for the recorded events and
@@ -362,13 +404,94 @@ func FireUpWeb(eventRegistry *stochastic.EventRegistryJSON, addr string) {
 	eventModel.PopulateEventData(eventRegistry)
 
 	// create web server
-	http.HandleFunc("/", renderMain)
-	http.HandleFunc("/"+countingRef, renderCounting)
-	http.HandleFunc("/"+queuingRef, renderQueuing)
-	http.HandleFunc("/"+snapshotRef, renderSnapshotStats)
-	http.HandleFunc("/"+operationRef, renderOperationStats)
-	http.HandleFunc("/"+txoperationRef, renderTransactionalOperationStats)
-	http.HandleFunc("/"+simplifiedMarkovRef, renderSimplifiedMarkovChain)
-	http.HandleFunc("/"+markovRef, renderMarkovChain)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		renderMain(w, func(ref string) string { return "/" + ref })
+	})
+	for _, p := range pages {
+		render := p.render
+		http.HandleFunc("/"+p.ref, func(w http.ResponseWriter, r *http.Request) {
+			if err := render(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		})
+	}
 	http.ListenAndServe(":"+addr, nil)
 }
+
+// ExportReport produces a data model for the recorded events and renders
+// its pages as static HTML files into a directory. The index of the report
+// is the file index.html. The echarts assets are copied into the report so
+// that it can be viewed offline.
+func ExportReport(eventRegistry *stochastic.EventRegistryJSON, dir string) error {
+	return exportReport(eventRegistry, dir, remoteAssetsHost)
+}
+
+// exportReport exports the report fetching the echarts assets from the given host.
+func exportReport(eventRegistry *stochastic.EventRegistryJSON, dir string, host string) error {
+
+	// create data model (as a singleton) for visualization
+	eventModel := GetEventsData()
+	eventModel.PopulateEventData(eventRegistry)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create report directory; %v", err)
+	}
+	for _, asset := range reportAssets {
+		if err := fetchAsset(host+asset, filepath.Join(dir, reportAssetsDir, asset)); err != nil {
+			return err
+		}
+	}
+
+	// charts of the report reference the local copy of the assets
+	assetsHost = reportAssetsDir
+	defer func() { assetsHost = "" }()
+
+	if err := writePage(filepath.Join(dir, "index.html"), func(w io.Writer) error {
+		return renderMain(w, func(ref string) string { return ref + ".html" })
+	}); err != nil {
+		return err
+	}
+	for _, p := range pages {
+		if err := writePage(filepath.Join(dir, p.ref+".html"), p.render); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePage renders a page into a file.
+func writePage(filename string, render func(w io.Writer) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("cannot create page %v; %v", filename, err)
+	}
+	if err := render(f); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot render page %v; %v", filename, err)
+	}
+	return f.Close()
+}
+
+// fetchAsset downloads an asset into a file.
+func fetchAsset(url string, filename string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("cannot fetch asset %v; %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot fetch asset %v; %v", url, resp.Status)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("cannot create asset directory; %v", err)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("cannot create asset %v; %v", filename, err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot write asset %v; %v", filename, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package visualizer
+
+import (
+	"io"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newReportTestRegistry creates the events of two sync-periods accessing a skewed set of accounts and slots.
+func newReportTestRegistry() *stochastic.EventRegistryJSON {
+	rg := rand.New(rand.NewSource(1))
+	r := stochastic.NewEventRegistry()
+	for period := 0; period < 2; period++ {
+		r.RegisterOp(stochastic.BeginSyncPeriodID)
+		for block := 0; block < 5; block++ {
+			r.RegisterOp(stochastic.BeginBlockID)
+			for tx := 0; tx < 10; tx++ {
+				r.RegisterOp(stochastic.BeginTransactionID)
+				for i := 0; i < 10; i++ {
+					addr := common.BigToAddress(big.NewInt(rg.Int63n(1 + rg.Int63n(100))))
+					key := common.BigToHash(big.NewInt(rg.Int63n(1 + rg.Int63n(100))))
+					value := common.BigToHash(big.NewInt(rg.Int63n(1 + rg.Int63n(100))))
+					r.RegisterAddressOp(stochastic.GetBalanceID, &addr)
+					r.RegisterKeyOp(stochastic.GetStateID, &addr, &key)
+					r.RegisterOp(stochastic.SnapshotID)
+					r.RegisterValueOp(stochastic.SetStateID, &addr, &key, &value)
+					r.RegisterSnapshotDelta(rg.Intn(2))
+					r.RegisterOp(stochastic.RevertToSnapshotID)
+				}
+				r.RegisterOp(stochastic.EndTransactionID)
+			}
+			r.RegisterOp(stochastic.EndBlockID)
+		}
+		r.RegisterOp(stochastic.EndSyncPeriodID)
+	}
+	events := r.NewEventRegistryJSON()
+	return &events
+}
+
+func TestExportReport_WritesAllPagesAndAssets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "// "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := exportReport(newReportTestRegistry(), dir, server.URL+"/"); err != nil {
+		t.Fatalf("cannot export report: %v", err)
+	}
+
+	files := []string{"index.html"}
+	for _, p := range pages {
+		files = append(files, p.ref+".html")
+	}
+	for _, asset := range reportAssets {
+		files = append(files, filepath.Join(reportAssetsDir, asset))
+	}
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("missing file %v in report: %v", file, err)
+		}
+	}
+
+	counting, err := os.ReadFile(filepath.Join(dir, countingRef+".html"))
+	if err != nil {
+		t.Fatalf("cannot read page: %v", err)
+	}
+	if !strings.Contains(string(counting), reportAssetsDir+"echarts.min.js") {
+		t.Errorf("page does not reference the local echarts assets")
+	}
+	if strings.Contains(string(counting), remoteAssetsHost) {
+		t.Errorf("page references the remote echarts assets")
+	}
+}
+
+func TestExportReport_FailsIfAssetsCannotBeFetched(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if err := exportReport(newReportTestRegistry(), t.TempDir(), server.URL+"/"); err == nil {
+		t.Errorf("export must fail if the assets are not available")
+	}
+}
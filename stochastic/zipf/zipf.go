@@ -0,0 +1,105 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package zipf
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+)
+
+// Package for a Zipf distribution of normalized ranks, i.e., the rank of an
+// item among n items is scaled to the unit interval. The distribution is the
+// continuous power law with exponent s of the ranks between 1 and n+1 that
+// approximates the discrete Zipf distribution for large n.
+
+const (
+	exponentEpsilon = 1e-9  // exponents closer to one are treated as one
+	maxExponent     = 10.0  // upper bound of the exponent search
+	searchTolerance = 1e-6  // tolerance of the exponent search
+	maxSearchSteps  = 10000 // maximum number of steps of the exponent search
+)
+
+// Cdf is the cumulative distribution function of the normalized ranks of n items.
+func Cdf(s float64, n int64, x float64) float64 {
+	a := 1 - s
+	m := float64(n)
+	if math.Abs(a) < exponentEpsilon {
+		return math.Log1p(x*m) / math.Log1p(m)
+	}
+	return (math.Pow(1+x*m, a) - 1) / (math.Pow(1+m, a) - 1)
+}
+
+// Quantile is the inverse cumulative distribution function.
+func Quantile(s float64, n int64, p float64) float64 {
+	a := 1 - s
+	m := float64(n)
+	if math.Abs(a) < exponentEpsilon {
+		return math.Expm1(p*math.Log1p(m)) / m
+	}
+	return (math.Pow(1+p*(math.Pow(1+m, a)-1), 1/a) - 1) / m
+}
+
+// DiscreteSample samples the distribution and discretizes the result for numbers in the range between 0 and n-1.
+func DiscreteSample(rg *rand.Rand, s float64, n int64) int64 {
+	v := int64(float64(n) * Quantile(s, n, rg.Float64()))
+	return min(max(v, 0), n-1)
+}
+
+// Mass is the probability of the normalized ranks between x0 and x1 of n
+// items. It avoids the cancellation of subtracting the CDF for large ranks.
+func Mass(s float64, n int64, x0 float64, x1 float64) float64 {
+	a := 1 - s
+	m := float64(n)
+	if math.Abs(a) < exponentEpsilon {
+		return (math.Log1p(x1*m) - math.Log1p(x0*m)) / math.Log1p(m)
+	}
+	return (math.Pow(1+x1*m, a) - math.Pow(1+x0*m, a)) / (math.Pow(1+m, a) - 1)
+}
+
+// ApproximateExponent determines the exponent of n items maximizing the
+// likelihood of the empirical cumulative distribution function by a
+// golden-section search.
+func ApproximateExponent(points [][2]float64, n int64) (float64, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("ApproximateExponent: invalid number of items %v", n)
+	}
+	ll := func(s float64) float64 {
+		return statistics.LogLikelihood(points, func(x0, x1 float64) float64 { return Mass(s, n, x0, x1) })
+	}
+	invPhi := (math.Sqrt(5) - 1) / 2
+	a, b := 0.0, maxExponent
+	c, d := b-invPhi*(b-a), a+invPhi*(b-a)
+	llc, lld := ll(c), ll(d)
+	for step := 0; step < maxSearchSteps; step++ {
+		if b-a < searchTolerance {
+			return (a + b) / 2, nil
+		}
+		if llc > lld {
+			b, d, lld = d, c, llc
+			c = b - invPhi*(b-a)
+			llc = ll(c)
+		} else {
+			a, c, llc = c, d, lld
+			d = a + invPhi*(b-a)
+			lld = ll(d)
+		}
+	}
+	return 0, fmt.Errorf("ApproximateExponent: failed to converge after %v steps", maxSearchSteps)
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package zipf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// piecewiseLinearCdf is a piecewise linear representation of the cumulative distribution function.
+func piecewiseLinearCdf(s float64, n int64, points int) [][2]float64 {
+	fn := [][2]float64{}
+	for i := 0; i <= points; i++ {
+		x := float64(i) / float64(points)
+		fn = append(fn, [2]float64{x, Cdf(s, n, x)})
+	}
+	return fn
+}
+
+// TestQuantile checks that the quantile function inverts the CDF.
+func TestQuantile(t *testing.T) {
+	for _, s := range []float64{0, 0.5, 1, 1.5, 3} {
+		for _, p := range []float64{0, 0.1, 0.5, 0.9, 1} {
+			if x := Quantile(s, 1000, p); math.Abs(Cdf(s, 1000, x)-p) > 1e-9 {
+				t.Errorf("quantile of %v for exponent %v is not inverse of CDF", p, s)
+			}
+		}
+		if Cdf(s, 1000, 0) != 0 || math.Abs(Cdf(s, 1000, 1)-1) > 1e-12 {
+			t.Errorf("CDF for exponent %v is not normalized", s)
+		}
+	}
+}
+
+// TestEstimation checks the correctness of approximating the exponent for a discrete CDF.
+func TestEstimation(t *testing.T) {
+	for _, s := range []float64{0.2, 0.8, 1.0, 1.3, 2.5} {
+		computed, err := ApproximateExponent(piecewiseLinearCdf(s, 100000, 1000), 100000)
+		if err != nil {
+			t.Fatalf("failed to approximate; %v", err)
+		}
+		if math.Abs(computed-s) > 1e-2 {
+			t.Errorf("failed to approximate; expected exponent %v, computed %v", s, computed)
+		}
+	}
+}
+
+// TestDiscreteSample checks the range of the samples and the frequency of the first rank.
+func TestDiscreteSample(t *testing.T) {
+	rg := rand.New(rand.NewSource(999))
+	n := int64(100)
+	s := 1.5
+	first := 0
+	steps := 100000
+	for i := 0; i < steps; i++ {
+		v := DiscreteSample(rg, s, n)
+		if v < 0 || v >= n {
+			t.Fatalf("sample %v out of range", v)
+		}
+		if v == 0 {
+			first++
+		}
+	}
+	expected := Cdf(s, n, 1/float64(n))
+	if p := float64(first) / float64(steps); math.Abs(p-expected) > 0.01 {
+		t.Errorf("unexpected frequency %v of first rank, expected %v", p, expected)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/Fantom-foundation/Aida/profile"
 	"github.com/dsnet/compress/bzip2"
@@ -30,6 +31,10 @@ import (
 
 const (
 	WriteBufferSize = 1048576 // Size of write buffer for writing trace file.
+
+	// spinThreshold is the remaining waiting time below which pacing busy-waits
+	// instead of sleeping, since sleeps are not precise for short delays.
+	spinThreshold = 100 * time.Microsecond
 )
 
 // Context is an environment/facade for recording and replaying trace files
@@ -41,18 +46,24 @@ type Context struct {
 // Record is the recording environment/facade
 type Record struct {
 	Context
-	Debug bool          // debug flag
-	file  *os.File      // trace file
-	bFile *bufio.Writer // buffer for trace file
-	ZFile *bzip2.Writer // compressed file
+	Debug  bool          // debug flag
+	Timing bool          // if true record the time between operations
+	lastOp time.Time     // time at which the previous operation was recorded
+	file   *os.File      // trace file
+	bFile  *bufio.Writer // buffer for trace file
+	ZFile  *bzip2.Writer // compressed file
 }
 
 // Replay is the replaying environment/facade
 type Replay struct {
 	Context
-	snapshot *SnapshotIndex // snapshot translation table for replay
-	Profile  bool           // if true collect stats
-	Stats    *profile.Stats // stats object
+	snapshot   *SnapshotIndex // snapshot translation table for replay
+	Profile    bool           // if true collect stats
+	Stats      *profile.Stats // stats object
+	pacing     bool           // if true reproduce the recorded time between operations
+	timeScale  float64        // scaling factor for recorded time between operations
+	paceStart  time.Time      // time at which the paced replay started
+	paceOffset time.Duration  // scaled time since paceStart at which the next operation is due
 }
 
 // NewReplay creates a new replay context.
@@ -69,6 +80,34 @@ func (ctx *Replay) EnableProfiling(csv string) {
 	ctx.Stats = profile.NewStats(csv)
 }
 
+// EnablePacing enables reproducing the recorded time between operations.
+// The recorded times are multiplied by the given scale, e.g. a scale of 0.5
+// replays the operations twice as fast as recorded.
+func (ctx *Replay) EnablePacing(scale float64) {
+	ctx.pacing = true
+	ctx.timeScale = scale
+}
+
+// Pace waits until an operation recorded the given time after its predecessor
+// is due. If the replay is lagging behind, no waiting takes place such that
+// the replay catches up with the recorded pacing.
+func (ctx *Replay) Pace(elapsed time.Duration) {
+	if !ctx.pacing {
+		return
+	}
+	if ctx.paceStart.IsZero() {
+		ctx.paceStart = time.Now()
+	}
+	ctx.paceOffset += time.Duration(float64(elapsed) * ctx.timeScale)
+	due := ctx.paceStart.Add(ctx.paceOffset)
+	if wait := time.Until(due); wait > spinThreshold {
+		time.Sleep(wait - spinThreshold)
+	}
+	for time.Now().Before(due) {
+		// busy-wait for the remaining time
+	}
+}
+
 // NewContext creates a new record context.
 func NewRecord(filename string, first uint64) (*Record, error) {
 	// open trace file, write buffer, and compressed stream
@@ -95,6 +134,20 @@ func NewRecord(filename string, first uint64) (*Record, error) {
 	}, nil
 }
 
+// SinceLastOp returns the time since the previous operation was recorded, or
+// zero for the first operation.
+func (ctx *Record) SinceLastOp() time.Duration {
+	if ctx.lastOp.IsZero() {
+		return 0
+	}
+	return time.Since(ctx.lastOp)
+}
+
+// MarkOpRecorded marks the time at which the latest operation was recorded.
+func (ctx *Record) MarkOpRecorded() {
+	ctx.lastOp = time.Now()
+}
+
 // Close the trace file in the record context.
 func (ctx *Record) Close() {
 	// closing compressed stream, flushing buffer, and closing trace file
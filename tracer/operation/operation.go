@@ -92,6 +92,9 @@ const (
 	PointCacheID
 	WitnessID
 
+	// relative time of operations for timing-faithful replays
+	TimestampID
+
 	// WARNING: New IDs should be added here. Any change in the order of the
 	// IDs above invalidates persisted data -- in particular storage traces.
 
@@ -169,6 +172,9 @@ var opDict = map[byte]OperationDictionary{
 	GetTransientStateLclsID: {label: "GetTransientStateLcls", readfunc: ReadGetTransientStateLcls},
 	SetTransientStateID:     {label: "SetTransientState", readfunc: ReadSetTransientState},
 	SetTransientStateLclsID: {label: "SetTransientStateLcls", readfunc: ReadSetTransientStateLcls},
+
+	// Timing
+	TimestampID: {label: "Timestamp", readfunc: ReadTimestamp},
 }
 
 // GetLabel retrieves a label of a state operation.
@@ -238,7 +244,8 @@ func Write(f io.Writer, op Operation) {
 // Execute an operation and profile it.
 func Execute(op Operation, db state.StateDB, ctx *context.Replay) {
 	elapsed := op.Execute(db, ctx)
-	if ctx.Profile {
+	// timestamps are not StateDB operations and hence not profiled
+	if ctx.Profile && op.GetId() != TimestampID {
 		ctx.Stats.Profile(op.GetId(), elapsed)
 	}
 }
@@ -252,10 +259,17 @@ func Debug(ctx *context.Context, op Operation) {
 
 // writeOperation writes operation to file.
 func WriteOp(ctx *context.Record, op Operation) {
+	if ctx.Timing {
+		Write(ctx.ZFile, NewTimestamp(ctx.SinceLastOp()))
+	}
 	Write(ctx.ZFile, op)
 	if ctx.Debug {
 		Debug(&ctx.Context, op)
 	}
+	if ctx.Timing {
+		// exclude the time for writing the trace from the recorded time
+		ctx.MarkOpRecorded()
+	}
 }
 
 // CreateIdLabelMap returns a map of opcode ID and opcode name
@@ -0,0 +1,71 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+package operation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/state"
+
+	"github.com/Fantom-foundation/Aida/tracer/context"
+)
+
+// Timestamp operation data structure. A timestamp precedes an operation
+// and contains the time since the previous operation was recorded.
+type Timestamp struct {
+	Elapsed uint64 // elapsed time in nanoseconds
+}
+
+// GetId returns the timestamp operation identifier.
+func (op *Timestamp) GetId() byte {
+	return TimestampID
+}
+
+// NewTimestamp creates a new timestamp operation.
+func NewTimestamp(elapsed time.Duration) *Timestamp {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return &Timestamp{Elapsed: uint64(elapsed)}
+}
+
+// ReadTimestamp reads a timestamp operation from file.
+func ReadTimestamp(f io.Reader) (Operation, error) {
+	data := new(Timestamp)
+	err := binary.Read(f, binary.LittleEndian, data)
+	return data, err
+}
+
+// Write the timestamp operation to file.
+func (op *Timestamp) Write(f io.Writer) error {
+	err := binary.Write(f, binary.LittleEndian, *op)
+	return err
+}
+
+// Execute the timestamp operation. It does not access the StateDB, but
+// waits until the next operation is due if pacing is enabled.
+func (op *Timestamp) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
+	ctx.Pace(time.Duration(op.Elapsed))
+	return 0
+}
+
+// Debug prints a debug message for the timestamp operation.
+func (op *Timestamp) Debug(ctx *context.Context) {
+	fmt.Print(time.Duration(op.Elapsed))
+}
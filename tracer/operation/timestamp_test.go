@@ -0,0 +1,83 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+package operation
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/tracer/context"
+)
+
+func initTimestamp(t *testing.T) (*context.Replay, *Timestamp, time.Duration) {
+	elapsed := time.Duration(rand.Int63n(int64(time.Millisecond)))
+
+	// create context context
+	ctx := context.NewReplay()
+
+	// create new operation
+	op := NewTimestamp(elapsed)
+	if op == nil {
+		t.Fatalf("failed to create operation")
+	}
+	// check id
+	if op.GetId() != TimestampID {
+		t.Fatalf("wrong ID returned")
+	}
+
+	return ctx, op, elapsed
+}
+
+// TestTimestampReadWrite writes a new Timestamp object into a buffer, reads from it,
+// and checks equality.
+func TestTimestampReadWrite(t *testing.T) {
+	_, op1, _ := initTimestamp(t)
+	testOperationReadWrite(t, op1, ReadTimestamp)
+}
+
+// TestTimestampDebug creates a new Timestamp object and checks its Debug message.
+func TestTimestampDebug(t *testing.T) {
+	ctx, op, value := initTimestamp(t)
+	testOperationDebug(t, ctx, op, fmt.Sprint(value))
+}
+
+// TestTimestampExecute checks that a timestamp does not access the StateDB.
+func TestTimestampExecute(t *testing.T) {
+	ctx, op, _ := initTimestamp(t)
+
+	// check execution
+	mock := NewMockStateDB()
+	op.Execute(mock, ctx)
+
+	// check whether methods were correctly called
+	expected := []Record{}
+	mock.compareRecordings(expected, t)
+}
+
+// TestTimestampExecuteWithPacing checks that a timestamp delays the replay if pacing is enabled.
+func TestTimestampExecuteWithPacing(t *testing.T) {
+	ctx := context.NewReplay()
+	ctx.EnablePacing(1)
+
+	start := time.Now()
+	NewTimestamp(0).Execute(NewMockStateDB(), ctx)
+	NewTimestamp(5*time.Millisecond).Execute(NewMockStateDB(), ctx)
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("replay was not paced, elapsed time %v", elapsed)
+	}
+}
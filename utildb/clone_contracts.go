@@ -0,0 +1,250 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package utildb
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/Fantom-foundation/Substate/substate"
+	substatetypes "github.com/Fantom-foundation/Substate/types"
+	"github.com/Fantom-foundation/Substate/updateset"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// contractCloner creates aida-db subset containing transactions touching given contracts and
+// transactions modifying accounts already present in the subset
+type contractCloner struct {
+	cfg             *utils.Config
+	log             logger.Logger
+	aidaDb, cloneDb db.BaseDB
+	contracts       map[substatetypes.Address]struct{}
+	prime           substate.WorldState                                       // state of touched accounts at block cfg.First-1
+	seen            map[substatetypes.Address]map[substatetypes.Hash]struct{} // accounts and storage slots already known
+	substates       uint64
+	deletions       uint64
+}
+
+// CloneContracts creates a standalone aida-db subset for given block range which contains
+// substates touching any of cfg.Contracts in their input or output allocs. Substates modifying
+// any account touched by an already cloned substate (e.g. the sender of a contract call) are
+// cloned as well, so the subset may be larger than the contract set. The state of all accounts
+// touched by cloned substates is written as an update-set at block cfg.First-1, so the subset
+// can be primed and executed by aida-vm-sdb.
+func CloneContracts(cfg *utils.Config, aidaDb, cloneDb db.BaseDB) error {
+	if cfg.First == 0 {
+		return errors.New("first block must be greater than 0; update-set for priming is stored at first block - 1")
+	}
+
+	contracts, err := parseContracts(cfg.Contracts)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	c := newContractCloner(cfg, aidaDb, cloneDb, contracts, logger.NewLogger(cfg.LogLevel, "AidaDb Clone"))
+	if err = c.clone(); err != nil {
+		return err
+	}
+
+	sourceMD := utils.NewAidaDbMetadata(aidaDb, cfg.LogLevel)
+	if err = utils.ProcessCloneLikeMetadata(cloneDb, utils.CloneType, cfg.LogLevel, cfg.First, cfg.Last, sourceMD.GetChainID()); err != nil {
+		return err
+	}
+
+	if cfg.CompactDb {
+		c.log.Noticef("Starting compaction")
+		if err = cloneDb.Compact(nil, nil); err != nil {
+			return err
+		}
+	}
+
+	c.log.Noticef("Cloning finished. Db saved to %v. Total elapsed time: %v", cfg.TargetDb, time.Since(start).Round(1*time.Second))
+	return nil
+}
+
+// parseContracts converts given hex addresses into a set
+func parseContracts(addresses []string) (map[substatetypes.Address]struct{}, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no contract addresses given")
+	}
+
+	contracts := make(map[substatetypes.Address]struct{}, len(addresses))
+	for _, a := range addresses {
+		if !common.IsHexAddress(a) {
+			return nil, fmt.Errorf("invalid contract address %q", a)
+		}
+		contracts[substatetypes.HexToAddress(a)] = struct{}{}
+	}
+	return contracts, nil
+}
+
+func newContractCloner(cfg *utils.Config, aidaDb, cloneDb db.BaseDB, contracts map[substatetypes.Address]struct{}, log logger.Logger) *contractCloner {
+	return &contractCloner{
+		cfg:       cfg,
+		log:       log,
+		aidaDb:    aidaDb,
+		cloneDb:   cloneDb,
+		contracts: contracts,
+		prime:     make(substate.WorldState),
+		seen:      make(map[substatetypes.Address]map[substatetypes.Hash]struct{}),
+	}
+}
+
+// clone copies substates and deletions touching the contracts and writes the priming update-set
+func (c *contractCloner) clone() error {
+	c.log.Noticef("Copying substates touching %v contracts from block %v to %v", len(c.contracts), c.cfg.First, c.cfg.Last)
+
+	sourceSdb := db.MakeDefaultSubstateDBFromBaseDB(c.aidaDb)
+	sourceDdb := db.MakeDefaultDestroyedAccountDBFromBaseDB(c.aidaDb)
+	targetSdb := db.MakeDefaultSubstateDBFromBaseDB(c.cloneDb)
+	targetDdb := db.MakeDefaultDestroyedAccountDBFromBaseDB(c.cloneDb)
+
+	iter := sourceSdb.NewSubstateIterator(int(c.cfg.First), c.cfg.Workers)
+	defer iter.Release()
+
+	for iter.Next() {
+		ss := iter.Value()
+		if ss.Block > c.cfg.Last {
+			break
+		}
+
+		destroyed, resurrected, err := sourceDdb.GetDestroyedAccounts(ss.Block, ss.Transaction)
+		if err != nil {
+			return fmt.Errorf("cannot get deleted accounts of block %v, tx %v; %v", ss.Block, ss.Transaction, err)
+		}
+		if !c.touchesContracts(ss) && !c.modifiesSubset(ss, destroyed, resurrected) {
+			continue
+		}
+
+		if err = targetSdb.PutSubstate(ss); err != nil {
+			return err
+		}
+		c.substates++
+
+		if len(destroyed) > 0 || len(resurrected) > 0 {
+			if err = targetDdb.SetDestroyedAccounts(ss.Block, ss.Transaction, destroyed, resurrected); err != nil {
+				return fmt.Errorf("cannot put deleted accounts of block %v, tx %v; %v", ss.Block, ss.Transaction, err)
+			}
+			c.deletions++
+		}
+
+		c.collectPrimeState(ss, destroyed, resurrected)
+	}
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("cannot iterate substates; %v", err)
+	}
+
+	if c.substates == 0 {
+		return fmt.Errorf("no transactions touching given contracts found between block %v and %v", c.cfg.First, c.cfg.Last)
+	}
+
+	targetUdb := db.MakeDefaultUpdateDBFromBaseDB(c.cloneDb)
+	err := targetUdb.PutUpdateSet(&updateset.UpdateSet{WorldState: c.prime, Block: c.cfg.First - 1}, []substatetypes.Address{})
+	if err != nil {
+		return fmt.Errorf("cannot put update-set; %v", err)
+	}
+
+	c.log.Noticef("Copied %v substates, %v deletion records and update-set of %v accounts at block %v", c.substates, c.deletions, len(c.prime), c.cfg.First-1)
+	return nil
+}
+
+// touchesContracts returns true if any of the contracts is present in input or output alloc of given substate
+func (c *contractCloner) touchesContracts(ss *substate.Substate) bool {
+	for addr := range ss.InputSubstate {
+		if _, ok := c.contracts[addr]; ok {
+			return true
+		}
+	}
+	for addr := range ss.OutputSubstate {
+		if _, ok := c.contracts[addr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// modifiesSubset returns true if given substate changes, deletes or resurrects any account
+// touched by a previously cloned substate
+func (c *contractCloner) modifiesSubset(ss *substate.Substate, destroyed, resurrected []substatetypes.Address) bool {
+	for addr := range ss.OutputSubstate.Diff(ss.InputSubstate) {
+		if _, ok := c.seen[addr]; ok {
+			return true
+		}
+	}
+	for _, addrs := range [][]substatetypes.Address{destroyed, resurrected} {
+		for _, addr := range addrs {
+			if _, ok := c.seen[addr]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectPrimeState adds accounts and storage slots of input alloc, which were not touched by any previously
+// cloned substate, into the priming world state. Every later change of a touched account is cloned, hence the
+// priming state matches the input alloc of each cloned substate. Accounts not yet touched may have been
+// changed by transactions which are not cloned, so their values are taken at their first use.
+func (c *contractCloner) collectPrimeState(ss *substate.Substate, destroyed, resurrected []substatetypes.Address) {
+	for addr, acc := range ss.InputSubstate {
+		slots, known := c.seen[addr]
+		if !known {
+			slots = make(map[substatetypes.Hash]struct{})
+			c.seen[addr] = slots
+			c.prime[addr] = substate.NewAccount(acc.Nonce, new(big.Int).Set(acc.Balance), acc.Code)
+		}
+
+		primeAcc, primed := c.prime[addr]
+		for key, value := range acc.Storage {
+			if _, ok := slots[key]; ok {
+				continue
+			}
+			slots[key] = struct{}{}
+			if primed {
+				primeAcc.Storage[key] = value
+			}
+		}
+	}
+
+	// accounts and slots written by the transaction are no longer at their state from block cfg.First-1
+	for addr, acc := range ss.OutputSubstate {
+		slots, known := c.seen[addr]
+		if !known {
+			slots = make(map[substatetypes.Hash]struct{})
+			c.seen[addr] = slots
+		}
+		for key := range acc.Storage {
+			slots[key] = struct{}{}
+		}
+	}
+
+	// deleted and resurrected accounts are empty afterwards, so their state must not be primed later
+	for _, addrs := range [][]substatetypes.Address{destroyed, resurrected} {
+		for _, addr := range addrs {
+			if _, known := c.seen[addr]; !known {
+				c.seen[addr] = make(map[substatetypes.Hash]struct{})
+			}
+		}
+	}
+}
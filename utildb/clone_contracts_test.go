@@ -0,0 +1,305 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package utildb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/executor/extension/primer"
+	"github.com/Fantom-foundation/Aida/executor/extension/statedb"
+	"github.com/Fantom-foundation/Aida/executor/extension/validator"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/Fantom-foundation/Substate/substate"
+	substatetypes "github.com/Fantom-foundation/Substate/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	cloneTestContract = substatetypes.Address{0xc}
+	cloneTestSender   = substatetypes.Address{0x5}
+	cloneTestOther    = substatetypes.Address{0xd}
+	cloneTestStranger = substatetypes.Address{0x6}
+	cloneTestUnused   = substatetypes.Address{0xe}
+)
+
+// makeContractsTestDb creates an AidaDb with three transactions per block. The first one is sent
+// by cloneTestSender to cloneTestContract, the second one by cloneTestSender to cloneTestOther
+// and the third one by cloneTestStranger to cloneTestUnused. Each increments the first storage
+// slot of the called contract.
+func makeContractsTestDb(t *testing.T) db.BaseDB {
+	aidaDb, err := db.NewDefaultBaseDB(t.TempDir() + "/aidaDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { aidaDb.Close() })
+
+	sdb := db.MakeDefaultSubstateDBFromBaseDB(aidaDb)
+	ddb := db.MakeDefaultDestroyedAccountDBFromBaseDB(aidaDb)
+	for block := uint64(1); block <= 5; block++ {
+		for tx, contract := range []substatetypes.Address{cloneTestContract, cloneTestOther, cloneTestUnused} {
+			sender, nonce := cloneTestSender, 2*block+uint64(tx)
+			if contract == cloneTestUnused {
+				sender, nonce = cloneTestStranger, block
+			}
+			input := substate.WorldState{
+				sender:   substate.NewAccount(nonce, big.NewInt(100), nil),
+				contract: substate.NewAccount(1, big.NewInt(0), []byte{0x1}),
+			}
+			input[contract].Storage[substatetypes.Hash{}] = substatetypes.Hash{byte(block)}
+			output := substate.WorldState{
+				sender:   substate.NewAccount(nonce+1, big.NewInt(100), nil),
+				contract: substate.NewAccount(1, big.NewInt(0), []byte{0x1}),
+			}
+			output[contract].Storage[substatetypes.Hash{}] = substatetypes.Hash{byte(block + 1)}
+
+			err = sdb.PutSubstate(&substate.Substate{
+				Block:          block,
+				Transaction:    tx,
+				Env:            &substate.Env{Number: block},
+				Message:        &substate.Message{From: sender, Nonce: nonce, Value: big.NewInt(0), To: &contract},
+				InputSubstate:  input,
+				OutputSubstate: output,
+				Result:         &substate.Result{},
+			})
+			assert.NoError(t, err)
+
+			if contract != cloneTestUnused {
+				err = ddb.SetDestroyedAccounts(block, tx, []substatetypes.Address{{byte(tx + 1)}}, []substatetypes.Address{})
+				assert.NoError(t, err)
+			}
+		}
+	}
+	return aidaDb
+}
+
+func TestCloneContracts_CopiesTransactionsTouchingContractsOrModifyingSubset(t *testing.T) {
+	aidaDb := makeContractsTestDb(t)
+	cloneDb, err := db.NewDefaultBaseDB(t.TempDir() + "/cloneDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cloneDb.Close()
+
+	cfg := &utils.Config{First: 2, Last: 4, Workers: 1}
+	contracts := map[substatetypes.Address]struct{}{cloneTestContract: {}}
+	c := newContractCloner(cfg, aidaDb, cloneDb, contracts, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, c.clone())
+
+	sdb := db.MakeDefaultSubstateDBFromBaseDB(cloneDb)
+	ddb := db.MakeDefaultDestroyedAccountDBFromBaseDB(cloneDb)
+	for block := uint64(1); block <= 5; block++ {
+		inRange := block >= 2 && block <= 4
+
+		// the second transaction modifies the sender of the first one
+		for tx := 0; tx < 2; tx++ {
+			has, err := sdb.HasSubstate(block, tx)
+			assert.NoError(t, err)
+			assert.Equal(t, inRange, has, "substate of block %v, tx %v", block, tx)
+
+			destroyed, _, err := ddb.GetDestroyedAccounts(block, tx)
+			assert.NoError(t, err)
+			assert.Equal(t, inRange, len(destroyed) == 1, "deleted accounts of block %v, tx %v", block, tx)
+		}
+
+		has, err := sdb.HasSubstate(block, 2)
+		assert.NoError(t, err)
+		assert.False(t, has, "substate of unrelated transaction at block %v", block)
+	}
+}
+
+func TestCloneContracts_UpdateSetContainsStateBeforeFirstBlock(t *testing.T) {
+	aidaDb := makeContractsTestDb(t)
+	cloneDb, err := db.NewDefaultBaseDB(t.TempDir() + "/cloneDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cloneDb.Close()
+
+	cfg := &utils.Config{First: 2, Last: 4, Workers: 1}
+	contracts := map[substatetypes.Address]struct{}{cloneTestContract: {}}
+	c := newContractCloner(cfg, aidaDb, cloneDb, contracts, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, c.clone())
+
+	udb := db.MakeDefaultUpdateDBFromBaseDB(cloneDb)
+	us, err := udb.GetUpdateSet(1)
+	assert.NoError(t, err)
+	if assert.NotNil(t, us) {
+		assert.Equal(t, 3, len(us.WorldState))
+		assert.Equal(t, substatetypes.Hash{2}, us.WorldState[cloneTestContract].Storage[substatetypes.Hash{}])
+		assert.Equal(t, []byte{0x1}, us.WorldState[cloneTestContract].Code)
+		assert.Equal(t, substatetypes.Hash{2}, us.WorldState[cloneTestOther].Storage[substatetypes.Hash{}])
+		assert.Equal(t, uint64(4), us.WorldState[cloneTestSender].Nonce)
+		assert.NotContains(t, us.WorldState, cloneTestStranger)
+		assert.NotContains(t, us.WorldState, cloneTestUnused)
+	}
+
+	has, err := udb.HasUpdateSet(2)
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+// makeTransfersTestDb creates an AidaDb with the same senders and recipients as makeContractsTestDb,
+// but with executable value transfers whose allocs follow the running state of all accounts.
+func makeTransfersTestDb(t *testing.T) db.BaseDB {
+	aidaDb, err := db.NewDefaultBaseDB(t.TempDir() + "/aidaDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { aidaDb.Close() })
+
+	ws := substate.WorldState{
+		cloneTestSender:   substate.NewAccount(0, big.NewInt(1000), nil),
+		cloneTestStranger: substate.NewAccount(0, big.NewInt(1000), nil),
+		cloneTestContract: substate.NewAccount(0, big.NewInt(1), nil),
+		cloneTestOther:    substate.NewAccount(0, big.NewInt(1), nil),
+		cloneTestUnused:   substate.NewAccount(0, big.NewInt(1), nil),
+	}
+
+	sdb := db.MakeDefaultSubstateDBFromBaseDB(aidaDb)
+	for block := uint64(1); block <= 5; block++ {
+		for tx, recipient := range []substatetypes.Address{cloneTestContract, cloneTestOther, cloneTestUnused} {
+			sender := cloneTestSender
+			if recipient == cloneTestUnused {
+				sender = cloneTestStranger
+			}
+			input := substate.WorldState{sender: ws[sender].Copy(), recipient: ws[recipient].Copy()}
+
+			// balances are replaced rather than modified, since copied accounts share them
+			ws[sender].Nonce++
+			ws[sender].Balance = new(big.Int).Sub(ws[sender].Balance, big.NewInt(1))
+			ws[recipient].Balance = new(big.Int).Add(ws[recipient].Balance, big.NewInt(1))
+			output := substate.WorldState{sender: ws[sender].Copy(), recipient: ws[recipient].Copy()}
+
+			err = sdb.PutSubstate(&substate.Substate{
+				Block:       block,
+				Transaction: tx,
+				Env: &substate.Env{
+					Number:     block,
+					GasLimit:   100_000_000,
+					Difficulty: big.NewInt(1),
+					Timestamp:  block,
+				},
+				Message: &substate.Message{
+					From:       sender,
+					To:         &recipient,
+					Nonce:      input[sender].Nonce,
+					CheckNonce: true,
+					Value:      big.NewInt(1),
+					Gas:        21_000,
+					GasPrice:   big.NewInt(0),
+					GasFeeCap:  big.NewInt(0),
+					GasTipCap:  big.NewInt(0),
+				},
+				InputSubstate:  input,
+				OutputSubstate: output,
+				Result:         &substate.Result{Status: 1, GasUsed: 21_000},
+			})
+			assert.NoError(t, err)
+		}
+	}
+	return aidaDb
+}
+
+// TestCloneContracts_SubsetCanBeExecuted primes a StateDb from the cloned subset and executes it
+// with the state and receipt validation used by aida-vm-sdb.
+func TestCloneContracts_SubsetCanBeExecuted(t *testing.T) {
+	aidaDb := makeTransfersTestDb(t)
+	cloneDb, err := db.NewDefaultBaseDB(t.TempDir() + "/cloneDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cloneDb.Close()
+
+	cfg := utils.NewTestConfig(t, utils.MainnetChainID, 2, 4, true, "")
+	cfg.SkipPriming = false
+	cfg.StateValidationMode = utils.SubsetCheck
+	contracts := map[substatetypes.Address]struct{}{cloneTestContract: {}}
+	c := newContractCloner(cfg, aidaDb, cloneDb, contracts, logger.NewLogger("ERROR", "test"))
+	if err = c.clone(); err != nil {
+		t.Fatalf("cannot clone contracts; %v", err)
+	}
+
+	stateDb, err := state.MakeGethStateDB(t.TempDir(), "", common.Hash{}, false, nil)
+	if err != nil {
+		t.Fatalf("cannot create state-db; %v", err)
+	}
+	defer stateDb.Close()
+
+	provider, err := executor.OpenSubstateProvider(cfg, nil, cloneDb)
+	if err != nil {
+		t.Fatalf("cannot open substate provider; %v", err)
+	}
+	defer provider.Close()
+
+	processor, err := executor.MakeLiveDbTxProcessor(cfg)
+	if err != nil {
+		t.Fatalf("cannot create processor; %v", err)
+	}
+
+	err = executor.NewExecutor(provider, cfg.LogLevel).Run(
+		executor.Params{
+			From:  int(cfg.First),
+			To:    int(cfg.Last) + 1,
+			State: stateDb,
+		},
+		processor,
+		[]executor.Extension[txcontext.TxContext]{
+			primer.MakeStateDbPrimer[txcontext.TxContext](cfg),
+			statedb.MakeStateDbPrepper(),
+			statedb.MakeBlockEventEmitter[txcontext.TxContext](),
+			statedb.MakeTransactionEventEmitter[txcontext.TxContext](),
+			validator.MakeLiveDbValidator(cfg, validator.ValidateTxTarget{WorldState: true, Receipt: true}),
+		},
+		cloneDb,
+	)
+	if err != nil {
+		t.Errorf("execution of cloned subset failed; %v", err)
+	}
+}
+
+func TestCloneContracts_FailsWithoutMatchingTransactions(t *testing.T) {
+	aidaDb := makeContractsTestDb(t)
+	cloneDb, err := db.NewDefaultBaseDB(t.TempDir() + "/cloneDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cloneDb.Close()
+
+	cfg := &utils.Config{First: 2, Last: 4, Workers: 1}
+	contracts := map[substatetypes.Address]struct{}{{0xee}: {}}
+	c := newContractCloner(cfg, aidaDb, cloneDb, contracts, logger.NewLogger("ERROR", "test"))
+	assert.Error(t, c.clone())
+}
+
+func TestCloneContracts_ParseContracts(t *testing.T) {
+	contracts, err := parseContracts([]string{"0x0c00000000000000000000000000000000000000", "0x0C00000000000000000000000000000000000000"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[substatetypes.Address]struct{}{cloneTestContract: {}}, contracts)
+
+	_, err = parseContracts([]string{"0x1234"})
+	assert.Error(t, err)
+
+	_, err = parseContracts(nil)
+	assert.Error(t, err)
+}
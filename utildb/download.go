@@ -0,0 +1,227 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package utildb
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// partialPatchSuffix marks patches whose download has not been completed yet.
+const partialPatchSuffix = ".part"
+
+// patchCacheDir returns the directory keeping downloaded patches.
+func patchCacheDir(cfg *utils.Config) string {
+	if cfg.PatchCache != "" {
+		return cfg.PatchCache
+	}
+	return cfg.DbTmp
+}
+
+// fetchPatch provides a patch archive in the cache directory and returns its path.
+// A cached archive is reused if it matches its checksum. Otherwise, the archive is
+// downloaded from the repository resuming a partial download of an earlier run.
+// The archive is only provided if it matches the checksum of the patches JSON.
+func fetchPatch(cacheDir string, repository string, patch utils.PatchJson, log logger.Logger) (string, error) {
+	if patch.TarHash == "" {
+		return "", fmt.Errorf("archive %v has no checksum", patch.FileName)
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating patch cache: %v", err)
+	}
+
+	// reuse cached archive
+	patchPath := filepath.Join(cacheDir, patch.FileName)
+	if _, err := os.Stat(patchPath); err == nil {
+		if err = verifyPatch(patchPath, patch); err == nil {
+			log.Noticef("Using cached %v", patch.FileName)
+			return patchPath, nil
+		}
+		log.Warningf("Discarding cached archive; %v", err)
+		if err = os.Remove(patchPath); err != nil {
+			return "", err
+		}
+	}
+
+	// download archive; if a resumed download does not match its checksum,
+	// the download is restarted from scratch.
+	partialPath := patchPath + partialPatchSuffix
+	patchUrl := repository + "/" + patch.FileName
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = downloadFile(partialPath, patchUrl); err != nil {
+			return "", fmt.Errorf("unable to download %s; %v", patchUrl, err)
+		}
+		if err = verifyPatch(partialPath, patch); err == nil {
+			break
+		}
+		log.Warningf("Discarding downloaded archive; %v", err)
+		if rmErr := os.Remove(partialPath); rmErr != nil {
+			return "", rmErr
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = os.Rename(partialPath, patchPath); err != nil {
+		return "", fmt.Errorf("unable to move %v into patch cache; %v", patch.FileName, err)
+	}
+	return patchPath, nil
+}
+
+// verifyPatch checks whether a patch archive matches its checksum.
+func verifyPatch(path string, patch utils.PatchJson) error {
+	md5, err := calculateMD5Sum(path)
+	if err != nil {
+		return fmt.Errorf("archive %v; unable to calculate md5sum; %v", patch.FileName, err)
+	}
+	if md5 != patch.TarHash {
+		return fmt.Errorf("archive %v doesn't have matching md5; archive %v, expected %v", patch.FileName, md5, patch.TarHash)
+	}
+	return nil
+}
+
+// downloadFile downloads file - used for downloading individual patches.
+// The content of an existing file is kept and the remaining content is appended.
+func downloadFile(filePath string, url string) error {
+	// Open the file or create it if it doesn't exist
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening file: %v", err)
+	}
+	defer file.Close()
+
+	for tries := maxNumberOfDownloadAttempts; tries > 0; tries-- {
+		// resume the download from the current size of the file
+		var fileInfo os.FileInfo
+		fileInfo, err = file.Stat()
+		if err != nil {
+			return fmt.Errorf("error getting file info: %v", err)
+		}
+		if err = downloadFileContents(url, fileInfo.Size(), file); err == nil {
+			return nil
+		}
+
+		// wait until next attempt
+		if tries > 1 {
+			time.Sleep(2 * time.Second)
+		}
+	}
+
+	return fmt.Errorf("failed after %v attempts; %v", maxNumberOfDownloadAttempts, err)
+}
+
+// downloadFileContents downloads file contents from given start and writes them
+// at the same position into the file. If the source cannot resume the download,
+// the file is overwritten from the beginning.
+func downloadFileContents(url string, startSize int64, file *os.File) error {
+	if path, ok := utils.LocalRepositoryPath(url); ok {
+		return copyFileContents(path, startSize, file)
+	}
+
+	// Set the "Range" header to resume the download from the current size
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	if startSize > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(startSize, 10)+"-")
+	}
+
+	// Make the request
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// server resumes the download
+	case http.StatusOK:
+		// server ignores the range and sends the whole file
+		startSize = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the file is already complete if its size is the size of the remote file
+		if size, ok := parseUnsatisfiedRange(resp.Header.Get("Content-Range")); ok && size == startSize {
+			return nil
+		}
+		if err = file.Truncate(0); err != nil {
+			return err
+		}
+		return fmt.Errorf("downloading %s, cannot resume at %v; restarting download", url, startSize)
+	default:
+		return fmt.Errorf("downloading %s, bad status: %s", url, resp.Status)
+	}
+
+	return writeFileContents(file, startSize, resp.Body)
+}
+
+// copyFileContents copies file contents of a local file from given start.
+func copyFileContents(path string, startSize int64, file *os.File) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %v", path, err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("error getting file info: %v", err)
+	}
+	if startSize > info.Size() {
+		startSize = 0
+	}
+	if _, err = src.Seek(startSize, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking %s: %v", path, err)
+	}
+	return writeFileContents(file, startSize, src)
+}
+
+// writeFileContents writes file contents at given start and drops any content beyond.
+func writeFileContents(file *os.File, startSize int64, contents io.Reader) error {
+	if err := file.Truncate(startSize); err != nil {
+		return fmt.Errorf("error truncating file: %v", err)
+	}
+	if _, err := file.Seek(startSize, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking file: %v", err)
+	}
+	if _, err := io.Copy(file, contents); err != nil {
+		return fmt.Errorf("error writing file: %v", err)
+	}
+	return nil
+}
+
+// parseUnsatisfiedRange returns the size of a remote file from the Content-Range
+// header of a response to an unsatisfiable range request (e.g. "bytes */1234").
+func parseUnsatisfiedRange(contentRange string) (int64, bool) {
+	sizeStr, found := strings.CutPrefix(contentRange, "bytes */")
+	if !found {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	return size, err == nil
+}
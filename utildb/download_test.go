@@ -0,0 +1,155 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package utildb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeTestPatch creates the content of a patch archive with its description.
+func makeTestPatch() ([]byte, utils.PatchJson) {
+	content := bytes.Repeat([]byte("aida-db patch content "), 1000)
+	hash := md5.Sum(content)
+	return content, utils.PatchJson{FileName: "1-2.tar.gz", TarHash: hex.EncodeToString(hash[:])}
+}
+
+// newRangeServer creates a file server for a patch which supports range requests.
+func newRangeServer(content []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "patch", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestFetchPatch_ResumesPartialDownload(t *testing.T) {
+	content, patch := makeTestPatch()
+	server := newRangeServer(content)
+	defer server.Close()
+
+	cache := t.TempDir()
+	partialPath := filepath.Join(cache, patch.FileName+partialPatchSuffix)
+	assert.NoError(t, os.WriteFile(partialPath, content[:1000], 0644))
+
+	path, err := fetchPatch(cache, server.URL, patch, logger.NewLogger("INFO", "test"))
+	assert.NoError(t, err)
+	got, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	_, err = os.Stat(partialPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFetchPatch_AcceptsCompletedPartialDownload(t *testing.T) {
+	content, patch := makeTestPatch()
+	server := newRangeServer(content)
+	defer server.Close()
+
+	cache := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cache, patch.FileName+partialPatchSuffix), content, 0644))
+
+	path, err := fetchPatch(cache, server.URL, patch, logger.NewLogger("INFO", "test"))
+	assert.NoError(t, err)
+	got, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestFetchPatch_RestartsIfRangeIsIgnored(t *testing.T) {
+	content, patch := makeTestPatch()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	cache := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cache, patch.FileName+partialPatchSuffix), content[:1000], 0644))
+
+	path, err := fetchPatch(cache, server.URL, patch, logger.NewLogger("INFO", "test"))
+	assert.NoError(t, err)
+	got, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestFetchPatch_RestartsCorruptedPartialDownload(t *testing.T) {
+	content, patch := makeTestPatch()
+	server := newRangeServer(content)
+	defer server.Close()
+
+	cache := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cache, patch.FileName+partialPatchSuffix), []byte("corrupted"), 0644))
+
+	path, err := fetchPatch(cache, server.URL, patch, logger.NewLogger("INFO", "test"))
+	assert.NoError(t, err)
+	got, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestFetchPatch_ReusesCachedPatch(t *testing.T) {
+	content, patch := makeTestPatch()
+	cache := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cache, patch.FileName), content, 0644))
+
+	// the repository is not accessed for a cached patch
+	path, err := fetchPatch(cache, filepath.Join(t.TempDir(), "missing"), patch, logger.NewLogger("INFO", "test"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(cache, patch.FileName), path)
+}
+
+func TestFetchPatch_CopiesFromLocalMirror(t *testing.T) {
+	content, patch := makeTestPatch()
+	mirror := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(mirror, patch.FileName), content, 0644))
+
+	cache := t.TempDir()
+	path, err := fetchPatch(cache, "file://"+mirror, patch, logger.NewLogger("INFO", "test"))
+	assert.NoError(t, err)
+	got, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestFetchPatch_RejectsMismatchingChecksum(t *testing.T) {
+	content, patch := makeTestPatch()
+	mirror := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(mirror, patch.FileName), content[1:], 0644))
+
+	cache := t.TempDir()
+	_, err := fetchPatch(cache, mirror, patch, logger.NewLogger("INFO", "test"))
+	assert.ErrorContains(t, err, "doesn't have matching md5")
+	_, err = os.Stat(filepath.Join(cache, patch.FileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFetchPatch_RequiresChecksum(t *testing.T) {
+	_, patch := makeTestPatch()
+	patch.TarHash = ""
+	_, err := fetchPatch(t.TempDir(), t.TempDir(), patch, logger.NewLogger("INFO", "test"))
+	assert.ErrorContains(t, err, "has no checksum")
+}
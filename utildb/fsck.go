@@ -0,0 +1,637 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package utildb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utildb/dbcomponent"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/Fantom-foundation/lachesis-base/kvdb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// maxFsckProblems limits the number of problems kept for a block range.
+const maxFsckProblems = 10
+
+// FsckRange is the result of checking a block range of a db component.
+type FsckRange struct {
+	Component   dbcomponent.DbComponent
+	First, Last uint64
+	Count       uint64   // number of records in the range
+	Hash        []byte   // md5 hash of the decoded records in the range
+	NumProblems uint64   // number of problems found in the range
+	Problems    []string // first problems found in the range
+	hasher      hash.Hash
+}
+
+// FsckReport is the result of an integrity check of an AidaDb.
+type FsckReport struct {
+	FirstBlock, LastBlock uint64       // block range of the check
+	Problems              []string     // problems which cannot be assigned to a block range
+	Ranges                []*FsckRange // results of the checked block ranges
+}
+
+// Damaged returns the damaged block ranges.
+func (r *FsckReport) Damaged() []*FsckRange {
+	var damaged []*FsckRange
+	for _, rng := range r.Ranges {
+		if rng.NumProblems > 0 {
+			damaged = append(damaged, rng)
+		}
+	}
+	return damaged
+}
+
+// Ok returns true if no problems were found.
+func (r *FsckReport) Ok() bool {
+	return len(r.Problems) == 0 && len(r.Damaged()) == 0
+}
+
+// add writes a decoded record into the hash of the range.
+func (r *FsckRange) add(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	r.hasher.Write(data)
+	return nil
+}
+
+// problem records a problem of the range.
+func (r *FsckRange) problem(format string, args ...any) {
+	r.NumProblems++
+	if len(r.Problems) < maxFsckProblems {
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// fsckRanges splits the block range of a db component into ranges of fixed size.
+type fsckRanges struct {
+	first  uint64
+	size   uint64
+	ranges []*FsckRange
+}
+
+// newFsckRanges creates the block ranges of a db component.
+func newFsckRanges(component dbcomponent.DbComponent, first, last, size uint64) *fsckRanges {
+	r := &fsckRanges{first: first, size: size}
+	for start := first; start <= last; start += size {
+		end := start + size - 1
+		if end > last || end < start {
+			end = last
+		}
+		r.ranges = append(r.ranges, &FsckRange{Component: component, First: start, Last: end, hasher: md5.New()})
+		if end == last {
+			break
+		}
+	}
+	return r
+}
+
+// get returns the range of a block, or nil if the block is not covered.
+func (r *fsckRanges) get(block uint64) *FsckRange {
+	if block < r.first {
+		return nil
+	}
+	idx := (block - r.first) / r.size
+	if idx >= uint64(len(r.ranges)) {
+		return nil
+	}
+	return r.ranges[idx]
+}
+
+// last returns the last block covered by the ranges.
+func (r *fsckRanges) last() uint64 {
+	return r.ranges[len(r.ranges)-1].Last
+}
+
+// Fsck checks the integrity of the db components of an AidaDb. The block range given by the
+// metadata is split into ranges of the given size. For each range, the records are decoded,
+// gaps are detected, and a hash over the decoded records is computed.
+func Fsck(base db.BaseDB, component dbcomponent.DbComponent, rangeSize uint64, log logger.Logger) (*FsckReport, error) {
+	if rangeSize == 0 {
+		return nil, errors.New("range size must be greater than zero")
+	}
+	report := &FsckReport{}
+
+	// check metadata and determine block range
+	first, last, problems := checkMetadata(base)
+	report.FirstBlock, report.LastBlock = first, last
+	report.Problems = append(report.Problems, problems...)
+	if last < first {
+		return report, nil
+	}
+	log.Noticef("Checking blocks %v-%v in ranges of %v blocks", first, last, rangeSize)
+
+	for _, c := range fsckComponents(component) {
+		start := time.Now()
+		log.Noticef("Checking %v...", c)
+		ranges := newFsckRanges(c, first, last, rangeSize)
+		problems, err := checkComponent(base, ranges, log)
+		if err != nil {
+			return nil, fmt.Errorf("cannot check %v; %v", c, err)
+		}
+		report.Problems = append(report.Problems, problems...)
+		for _, rng := range ranges.ranges {
+			rng.Hash = rng.hasher.Sum(nil)
+		}
+		report.Ranges = append(report.Ranges, ranges.ranges...)
+		log.Infof("Checking %v took %v", c, time.Since(start).Round(time.Second))
+	}
+	return report, nil
+}
+
+// fsckComponents returns the db components selected for checking.
+func fsckComponents(component dbcomponent.DbComponent) []dbcomponent.DbComponent {
+	if component == dbcomponent.All {
+		return []dbcomponent.DbComponent{dbcomponent.Substate, dbcomponent.Update, dbcomponent.Delete, dbcomponent.StateHash}
+	}
+	return []dbcomponent.DbComponent{component}
+}
+
+// checkComponent checks the records of a db component in its block ranges. It returns the
+// problems which cannot be assigned to a block range.
+func checkComponent(base db.BaseDB, ranges *fsckRanges, log logger.Logger) ([]string, error) {
+	switch ranges.ranges[0].Component {
+	case dbcomponent.Substate:
+		return checkSubstates(base, ranges)
+	case dbcomponent.Update:
+		return checkUpdateSets(base, ranges)
+	case dbcomponent.Delete:
+		return checkDeletions(base, ranges)
+	case dbcomponent.StateHash:
+		return checkStateHashes(base, ranges, log)
+	default:
+		return nil, fmt.Errorf("unsupported db component %v", ranges.ranges[0].Component)
+	}
+}
+
+// checkMetadata checks the consistency of the metadata with the substates and returns the
+// block range to be checked.
+func checkMetadata(base db.BaseDB) (uint64, uint64, []string) {
+	var problems []string
+	md := utils.NewAidaDbMetadata(base, "ERROR")
+	first, last := md.GetFirstBlock(), md.GetLastBlock()
+	firstEpoch, lastEpoch := md.GetFirstEpoch(), md.GetLastEpoch()
+
+	substateFirst, substateLast, found := utils.FindBlockRangeInSubstate(db.MakeDefaultSubstateDBFromBaseDB(base))
+	if !found {
+		problems = append(problems, "no substates found")
+	}
+	if last == 0 {
+		problems = append(problems, "metadata has no block range")
+		if !found {
+			return 1, 0, problems
+		}
+		return substateFirst, substateLast, problems
+	}
+	if first > last {
+		problems = append(problems, fmt.Sprintf("metadata first block %v is after last block %v", first, last))
+	}
+	if firstEpoch > lastEpoch {
+		problems = append(problems, fmt.Sprintf("metadata first epoch %v is after last epoch %v", firstEpoch, lastEpoch))
+	}
+	if found && (first != substateFirst || last != substateLast) {
+		problems = append(problems, fmt.Sprintf("metadata block range %v-%v does not match substate block range %v-%v", first, last, substateFirst, substateLast))
+	}
+	return first, last, problems
+}
+
+// checkSubstates decodes the substates of the block ranges.
+func checkSubstates(base db.BaseDB, ranges *fsckRanges) ([]string, error) {
+	var problems []string
+	sdb := db.MakeDefaultSubstateDBFromBaseDB(base)
+	iter := base.NewIterator([]byte(db.SubstateDBPrefix), db.BlockToBytes(ranges.first))
+	defer iter.Release()
+
+	for iter.Next() {
+		block, tx, err := db.DecodeSubstateDBKey(iter.Key())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid substate key %x; %v", iter.Key(), err))
+			continue
+		}
+		if block > ranges.last() {
+			break
+		}
+		rng := ranges.get(block)
+		if rng == nil {
+			continue
+		}
+		rng.Count++
+		ss, err := sdb.GetSubstate(block, tx)
+		if err != nil {
+			rng.problem("block %v, tx %v: cannot decode substate; %v", block, tx, err)
+			continue
+		}
+		if ss.Block != block || ss.Transaction != tx {
+			rng.problem("block %v, tx %v: substate is stored as block %v, tx %v", block, tx, ss.Block, ss.Transaction)
+		}
+		if err = rng.add(ss); err != nil {
+			return nil, err
+		}
+	}
+	return problems, iter.Error()
+}
+
+// checkUpdateSets decodes the update-sets of the block ranges and detects gaps
+// exceeding the update-set interval.
+func checkUpdateSets(base db.BaseDB, ranges *fsckRanges) ([]string, error) {
+	var problems []string
+	udb := db.MakeDefaultUpdateDBFromBaseDB(base)
+	interval := uint64(0)
+	if value, err := base.Get([]byte(db.UpdatesetIntervalKey)); err == nil && len(value) == 8 {
+		interval = binary.BigEndian.Uint64(value)
+	}
+	iter := base.NewIterator([]byte(db.UpdateDBPrefix), db.BlockToBytes(ranges.first))
+	defer iter.Release()
+
+	var previous uint64
+	hasPrevious := false
+	for iter.Next() {
+		block, err := db.DecodeUpdateSetKey(iter.Key())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid update-set key %x; %v", iter.Key(), err))
+			continue
+		}
+		if block > ranges.last() {
+			break
+		}
+		rng := ranges.get(block)
+		if rng == nil {
+			continue
+		}
+		rng.Count++
+		if interval > 0 && hasPrevious && block-previous > interval {
+			rng.problem("block %v: gap of %v blocks since previous update-set at block %v", block, block-previous, previous)
+		}
+		previous, hasPrevious = block, true
+		us, err := udb.GetUpdateSet(block)
+		if err != nil {
+			rng.problem("block %v: cannot decode update-set; %v", block, err)
+			continue
+		}
+		if err = rng.add(us); err != nil {
+			return nil, err
+		}
+	}
+	return problems, iter.Error()
+}
+
+// checkDeletions decodes the deleted accounts of the block ranges.
+func checkDeletions(base db.BaseDB, ranges *fsckRanges) ([]string, error) {
+	var problems []string
+	iter := base.NewIterator([]byte(db.DestroyedAccountPrefix), db.BlockToBytes(ranges.first))
+	defer iter.Release()
+
+	for iter.Next() {
+		block, tx, err := db.DecodeDestroyedAccountKey(iter.Key())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid deleted-account key %x; %v", iter.Key(), err))
+			continue
+		}
+		if block > ranges.last() {
+			break
+		}
+		rng := ranges.get(block)
+		if rng == nil {
+			continue
+		}
+		rng.Count++
+		list, err := db.DecodeAddressList(iter.Value())
+		if err != nil {
+			rng.problem("block %v, tx %v: cannot decode deleted accounts; %v", block, tx, err)
+			continue
+		}
+		combined := append(list.DestroyedAccounts, list.ResurrectedAccounts...)
+		sort.Slice(combined, func(i, j int) bool {
+			return bytes.Compare(combined[i].Bytes(), combined[j].Bytes()) < 0
+		})
+		for _, address := range combined {
+			if err = rng.add(address.String()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return problems, iter.Error()
+}
+
+// checkStateHashes checks that there is a valid state hash for every block of the block
+// ranges. An AidaDb without any state hashes is not considered damaged.
+func checkStateHashes(base db.BaseDB, ranges *fsckRanges, log logger.Logger) ([]string, error) {
+	missing := make([]uint64, len(ranges.ranges))
+	total := uint64(0)
+	for i, rng := range ranges.ranges {
+		for block := rng.First; block <= rng.Last && block >= rng.First; block++ {
+			value, err := base.Get(stateHashKey(block))
+			if err != nil {
+				if errors.Is(err, leveldb.ErrNotFound) {
+					missing[i]++
+					continue
+				}
+				return nil, err
+			}
+			rng.Count++
+			if len(value) != common.HashLength {
+				rng.problem("block %v: state hash has invalid length %v", block, len(value))
+				continue
+			}
+			if err = rng.add(common.BytesToHash(value)); err != nil {
+				return nil, err
+			}
+		}
+		total += rng.Count
+	}
+	if total == 0 {
+		log.Warning("AidaDb has no state hashes")
+		return nil, nil
+	}
+	for i, rng := range ranges.ranges {
+		if missing[i] > 0 {
+			rng.problem("%v blocks without state hash", missing[i])
+		}
+	}
+	return nil, nil
+}
+
+// stateHashKey returns the key of the state hash of a block.
+func stateHashKey(block uint64) []byte {
+	return []byte(utils.StateHashPrefix + "0x" + strconv.FormatUint(block, 16))
+}
+
+// RepairFsck re-fetches the damaged block ranges of a fsck report from the aida-db patches
+// and replaces their records in the AidaDb. Afterwards, the metadata is repaired if problems
+// were found. It returns the damaged ranges which are not covered by any patch; they need
+// to be re-generated.
+func RepairFsck(cfg *utils.Config, base db.BaseDB, report *FsckReport, log logger.Logger) ([]*FsckRange, error) {
+	var remaining []*FsckRange
+	if damaged := report.Damaged(); len(damaged) > 0 {
+		var err error
+		if remaining, err = repairRanges(cfg, base, damaged, log); err != nil {
+			return nil, err
+		}
+	}
+	if len(report.Problems) > 0 {
+		if err := repairMetadata(base, log); err != nil {
+			return nil, fmt.Errorf("cannot repair metadata; %v", err)
+		}
+	}
+	return remaining, nil
+}
+
+// repairMetadata sets the block range of the metadata to the block range of the substates.
+// Epochs which are inconsistent or belong to a replaced block range are unknown and reset
+// to zero. Without substates, the metadata cannot be repaired.
+func repairMetadata(base db.BaseDB, log logger.Logger) error {
+	first, last, found := utils.FindBlockRangeInSubstate(db.MakeDefaultSubstateDBFromBaseDB(base))
+	if !found {
+		log.Warning("Metadata cannot be repaired without substates")
+		return nil
+	}
+	md := utils.NewAidaDbMetadata(base, "ERROR")
+	resetEpochs := md.GetFirstEpoch() > md.GetLastEpoch()
+	if md.GetFirstBlock() != first || md.GetLastBlock() != last {
+		log.Noticef("Setting metadata block range to %v-%v", first, last)
+		if err := md.SetBlockRange(first, last); err != nil {
+			return err
+		}
+		resetEpochs = true
+	}
+	if !resetEpochs {
+		return nil
+	}
+	log.Notice("Resetting metadata epochs")
+	if err := md.SetFirstEpoch(0); err != nil {
+		return err
+	}
+	return md.SetLastEpoch(0)
+}
+
+// repairRanges re-fetches the damaged block ranges from the aida-db patches and returns
+// the damaged ranges which are not covered by any patch.
+func repairRanges(cfg *utils.Config, base db.BaseDB, damaged []*FsckRange, log logger.Logger) ([]*FsckRange, error) {
+	patches, err := utils.DownloadPatchesJson()
+	if err != nil {
+		return nil, fmt.Errorf("unable to download patches.json; %v", err)
+	}
+	sort.Sort(ByToBlock(patches))
+
+	covered := make([]bool, len(damaged))
+	for _, patch := range patches {
+		if patch.Nightly && cfg.UpdateType != "nightly" {
+			continue
+		}
+		var overlapping []int
+		for i, rng := range damaged {
+			if rng.First <= patch.ToBlock && rng.Last >= patch.FromBlock {
+				overlapping = append(overlapping, i)
+			}
+		}
+		if len(overlapping) == 0 {
+			continue
+		}
+
+		log.Noticef("Re-fetching %v", patch.FileName)
+		if err = repairFromPatch(cfg, base, patch, damaged, overlapping, log); err != nil {
+			return nil, err
+		}
+		for _, i := range overlapping {
+			if damaged[i].First >= patch.FromBlock && damaged[i].Last <= patch.ToBlock {
+				covered[i] = true
+			}
+		}
+	}
+
+	var remaining []*FsckRange
+	for i, rng := range damaged {
+		if !covered[i] {
+			remaining = append(remaining, rng)
+		}
+	}
+	return remaining, nil
+}
+
+// repairFromPatch replaces the records of damaged block ranges by the records of a patch.
+func repairFromPatch(cfg *utils.Config, base db.BaseDB, patch utils.PatchJson, damaged []*FsckRange, overlapping []int, log logger.Logger) error {
+	patchPath, err := fetchPatch(patchCacheDir(cfg), utils.AidaDbRepositoryUrl, patch, log)
+	if err != nil {
+		return err
+	}
+	if err = extractTarGz(patchPath, cfg.DbTmp); err != nil {
+		return err
+	}
+	extractedPatchPath := filepath.Join(cfg.DbTmp, strings.TrimSuffix(patch.FileName, ".tar.gz"))
+	defer os.RemoveAll(extractedPatchPath)
+	if cfg.PatchCache == "" {
+		defer os.Remove(patchPath)
+	}
+
+	patchDb, err := db.NewReadOnlyBaseDB(extractedPatchPath)
+	if err != nil {
+		return fmt.Errorf("cannot open patch %v; %v", patch.FileName, err)
+	}
+	defer MustCloseDB(patchDb)
+
+	needsCode := false
+	for _, i := range overlapping {
+		rng := damaged[i]
+		first, last := max(rng.First, patch.FromBlock), min(rng.Last, patch.ToBlock)
+		log.Infof("Replacing %v of blocks %v-%v", rng.Component, first, last)
+		if err = replaceRange(base, patchDb, rng.Component, first, last); err != nil {
+			return fmt.Errorf("cannot replace %v of blocks %v-%v; %v", rng.Component, first, last, err)
+		}
+		needsCode = needsCode || rng.Component == dbcomponent.Substate || rng.Component == dbcomponent.Update
+	}
+
+	// substates and update-sets refer to contract codes stored separately
+	if needsCode {
+		if err = copyPrefix(base, patchDb, db.CodeDBPrefix); err != nil {
+			return fmt.Errorf("cannot copy contract codes; %v", err)
+		}
+	}
+	return nil
+}
+
+// replaceRange replaces the records of a db component in a block range by the records of another db.
+func replaceRange(target db.BaseDB, source db.BaseDB, component dbcomponent.DbComponent, first, last uint64) error {
+	batch := target.NewBatch()
+
+	if component == dbcomponent.StateHash {
+		for block := first; block <= last && block >= first; block++ {
+			key := stateHashKey(block)
+			value, err := source.Get(key)
+			if errors.Is(err, leveldb.ErrNotFound) {
+				err = batch.Delete(key)
+			} else if err == nil {
+				err = batch.Put(key, value)
+			}
+			if err != nil {
+				return err
+			}
+			if err = flushBatch(batch, kvdb.IdealBatchSize); err != nil {
+				return err
+			}
+		}
+		return flushBatch(batch, 0)
+	}
+
+	var prefix string
+	var decodeBlock func(key []byte) (uint64, error)
+	switch component {
+	case dbcomponent.Substate:
+		prefix = db.SubstateDBPrefix
+		decodeBlock = func(key []byte) (uint64, error) {
+			block, _, err := db.DecodeSubstateDBKey(key)
+			return block, err
+		}
+	case dbcomponent.Update:
+		prefix = db.UpdateDBPrefix
+		decodeBlock = db.DecodeUpdateSetKey
+	case dbcomponent.Delete:
+		prefix = db.DestroyedAccountPrefix
+		decodeBlock = func(key []byte) (uint64, error) {
+			block, _, err := db.DecodeDestroyedAccountKey(key)
+			return block, err
+		}
+	default:
+		return fmt.Errorf("unsupported db component %v", component)
+	}
+
+	// delete damaged records and write records of source
+	for _, d := range []struct {
+		db     db.BaseDB
+		delete bool
+	}{{target, true}, {source, false}} {
+		iter := d.db.NewIterator([]byte(prefix), db.BlockToBytes(first))
+		for iter.Next() {
+			block, err := decodeBlock(iter.Key())
+			if err != nil {
+				continue
+			}
+			if block > last {
+				break
+			}
+			if d.delete {
+				err = batch.Delete(iter.Key())
+			} else {
+				err = batch.Put(iter.Key(), iter.Value())
+			}
+			if err == nil {
+				err = flushBatch(batch, kvdb.IdealBatchSize)
+			}
+			if err != nil {
+				iter.Release()
+				return err
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+		// deletions need to be written before the records of the source
+		if err := flushBatch(batch, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPrefix copies all records with a prefix from one db to another.
+func copyPrefix(target db.BaseDB, source db.BaseDB, prefix string) error {
+	batch := target.NewBatch()
+	iter := source.NewIterator([]byte(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := batch.Put(iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+		if err := flushBatch(batch, kvdb.IdealBatchSize); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return flushBatch(batch, 0)
+}
+
+// flushBatch writes a batch if its size reaches a limit.
+func flushBatch(batch db.Batch, limit int) error {
+	if batch.ValueSize() < limit {
+		return nil
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("batch-writer cannot write data; %v", err)
+	}
+	batch.Reset()
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package utildb
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utildb/dbcomponent"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/Substate/db"
+	"github.com/Fantom-foundation/Substate/substate"
+	substatetypes "github.com/Fantom-foundation/Substate/types"
+	"github.com/Fantom-foundation/Substate/updateset"
+	"github.com/stretchr/testify/assert"
+)
+
+// fsckTestBlocks is the number of blocks of the AidaDb created for fsck tests.
+const fsckTestBlocks = 20
+
+// makeFsckTestDb creates an AidaDb with a substate and a state hash for every block,
+// update-sets every five blocks, and deleted accounts every third block.
+func makeFsckTestDb(t *testing.T) db.BaseDB {
+	aidaDb, err := db.NewDefaultBaseDB(t.TempDir() + "/aidaDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { aidaDb.Close() })
+
+	sdb := db.MakeDefaultSubstateDBFromBaseDB(aidaDb)
+	udb := db.MakeDefaultUpdateDBFromBaseDB(aidaDb)
+	ddb := db.MakeDefaultDestroyedAccountDBFromBaseDB(aidaDb)
+	acc := substate.NewAccount(1, big.NewInt(1), []byte{1})
+	for i := uint64(0); i < fsckTestBlocks; i++ {
+		err = sdb.PutSubstate(&substate.Substate{
+			Block:          i,
+			Transaction:    0,
+			Env:            &substate.Env{Number: i},
+			Message:        &substate.Message{Value: big.NewInt(int64(i))},
+			InputSubstate:  substate.WorldState{substatetypes.Address{0x0}: acc},
+			OutputSubstate: substate.WorldState{substatetypes.Address{0x0}: acc},
+			Result:         &substate.Result{},
+		})
+		assert.NoError(t, err)
+		if i%5 == 0 {
+			err = udb.PutUpdateSet(&updateset.UpdateSet{
+				WorldState: substate.WorldState{substatetypes.Address{byte(i + 1)}: substate.NewAccount(i, big.NewInt(1), nil)},
+				Block:      i,
+			}, []substatetypes.Address{})
+			assert.NoError(t, err)
+		}
+		if i%3 == 0 {
+			err = ddb.SetDestroyedAccounts(i, 0, []substatetypes.Address{{byte(i + 1)}}, []substatetypes.Address{})
+			assert.NoError(t, err)
+		}
+		err = utils.SaveStateRoot(aidaDb, fmt.Sprintf("0x%x", i), strings.Repeat("1", 64))
+		assert.NoError(t, err)
+	}
+	md := utils.NewAidaDbMetadata(aidaDb, "ERROR")
+	assert.NoError(t, md.SetUpdatesetInterval(5))
+	assert.NoError(t, md.SetBlockRange(0, fsckTestBlocks-1))
+	return aidaDb
+}
+
+// findFsckRange finds the checked range of a component containing a block.
+func findFsckRange(t *testing.T, report *FsckReport, component dbcomponent.DbComponent, block uint64) *FsckRange {
+	for _, rng := range report.Ranges {
+		if rng.Component == component && rng.First <= block && block <= rng.Last {
+			return rng
+		}
+	}
+	t.Fatalf("no range of %v contains block %v", component, block)
+	return nil
+}
+
+func TestFsck_Healthy(t *testing.T) {
+	aidaDb := makeFsckTestDb(t)
+
+	report, err := Fsck(aidaDb, dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	assert.True(t, report.Ok(), "unexpected problems: %v, %v", report.Problems, report.Damaged())
+	assert.Equal(t, 8, len(report.Ranges))
+	assert.Equal(t, uint64(10), findFsckRange(t, report, dbcomponent.Substate, 0).Count)
+	assert.Equal(t, uint64(2), findFsckRange(t, report, dbcomponent.Update, 19).Count)
+	assert.Equal(t, uint64(4), findFsckRange(t, report, dbcomponent.Delete, 0).Count)
+	assert.Equal(t, uint64(10), findFsckRange(t, report, dbcomponent.StateHash, 19).Count)
+}
+
+func TestFsck_HashesAreReproducible(t *testing.T) {
+	first, err := Fsck(makeFsckTestDb(t), dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	second, err := Fsck(makeFsckTestDb(t), dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	for i := range first.Ranges {
+		assert.Equal(t, first.Ranges[i].Hash, second.Ranges[i].Hash)
+	}
+	assert.NotEqual(t, first.Ranges[0].Hash, first.Ranges[1].Hash)
+}
+
+func TestFsck_DetectsUndecodableSubstate(t *testing.T) {
+	aidaDb := makeFsckTestDb(t)
+	assert.NoError(t, aidaDb.Put(db.SubstateDBKey(12, 0), []byte{1, 2, 3}))
+
+	report, err := Fsck(aidaDb, dbcomponent.Substate, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	damaged := report.Damaged()
+	assert.Equal(t, 1, len(damaged))
+	assert.Equal(t, uint64(10), damaged[0].First)
+	assert.Equal(t, uint64(19), damaged[0].Last)
+}
+
+func TestFsck_DetectsUpdateSetGap(t *testing.T) {
+	aidaDb := makeFsckTestDb(t)
+	assert.NoError(t, db.MakeDefaultUpdateDBFromBaseDB(aidaDb).DeleteUpdateSet(10))
+
+	report, err := Fsck(aidaDb, dbcomponent.Update, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	damaged := report.Damaged()
+	assert.Equal(t, 1, len(damaged))
+	assert.Equal(t, uint64(10), damaged[0].First)
+}
+
+func TestFsck_DetectsMissingStateHash(t *testing.T) {
+	aidaDb := makeFsckTestDb(t)
+	assert.NoError(t, aidaDb.Delete(stateHashKey(3)))
+
+	report, err := Fsck(aidaDb, dbcomponent.StateHash, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	damaged := report.Damaged()
+	assert.Equal(t, 1, len(damaged))
+	assert.Equal(t, uint64(0), damaged[0].First)
+	assert.Equal(t, []string{"1 blocks without state hash"}, damaged[0].Problems)
+}
+
+func TestFsck_DetectsInconsistentMetadata(t *testing.T) {
+	aidaDb := makeFsckTestDb(t)
+	assert.NoError(t, utils.NewAidaDbMetadata(aidaDb, "ERROR").SetLastBlock(25))
+
+	report, err := Fsck(aidaDb, dbcomponent.Delete, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	assert.False(t, report.Ok())
+	assert.Equal(t, 1, len(report.Problems))
+	assert.Empty(t, report.Damaged())
+}
+
+func TestFsck_RepairMetadataRestoresSubstateBlockRange(t *testing.T) {
+	aidaDb := makeFsckTestDb(t)
+	md := utils.NewAidaDbMetadata(aidaDb, "ERROR")
+	assert.NoError(t, md.SetLastBlock(25))
+	assert.NoError(t, md.SetFirstEpoch(5))
+	assert.NoError(t, md.SetLastEpoch(3))
+
+	report, err := Fsck(aidaDb, dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(report.Problems))
+
+	assert.NoError(t, repairMetadata(aidaDb, logger.NewLogger("ERROR", "test")))
+	report, err = Fsck(aidaDb, dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	assert.True(t, report.Ok(), "unexpected problems: %v, %v", report.Problems, report.Damaged())
+	md = utils.NewAidaDbMetadata(aidaDb, "ERROR")
+	assert.Equal(t, uint64(fsckTestBlocks-1), md.GetLastBlock())
+	assert.Equal(t, uint64(0), md.GetFirstEpoch())
+	assert.Equal(t, uint64(0), md.GetLastEpoch())
+}
+
+func TestFsck_ReplaceRangeRepairsDamage(t *testing.T) {
+	source := makeFsckTestDb(t)
+	target := makeFsckTestDb(t)
+	assert.NoError(t, target.Put(db.SubstateDBKey(12, 0), []byte{1, 2, 3}))
+	assert.NoError(t, target.Delete(stateHashKey(3)))
+
+	assert.NoError(t, replaceRange(target, source, dbcomponent.Substate, 10, 19))
+	assert.NoError(t, replaceRange(target, source, dbcomponent.StateHash, 0, 9))
+
+	expected, err := Fsck(source, dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	report, err := Fsck(target, dbcomponent.All, 10, logger.NewLogger("ERROR", "test"))
+	assert.NoError(t, err)
+	assert.True(t, report.Ok(), "unexpected problems: %v, %v", report.Problems, report.Damaged())
+	for i := range expected.Ranges {
+		assert.Equal(t, expected.Ranges[i].Hash, report.Ranges[i].Hash)
+	}
+}
@@ -18,18 +18,15 @@ package utildb
 
 import (
 	"archive/tar"
-	"bufio"
 	"bytes"
 	"compress/gzip"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -298,7 +295,7 @@ func decompressPatch(cfg *utils.Config, patchChan chan utils.PatchJson, errChan
 					}
 					log.Debugf("Decompressing %v...", patch.FileName)
 
-					compressedPatchPath := filepath.Join(cfg.DbTmp, patch.FileName)
+					compressedPatchPath := filepath.Join(patchCacheDir(cfg), patch.FileName)
 					err := extractTarGz(compressedPatchPath, cfg.DbTmp)
 					if err != nil {
 						errDecompressChan <- err
@@ -306,14 +303,16 @@ func decompressPatch(cfg *utils.Config, patchChan chan utils.PatchJson, errChan
 					}
 
 					// extracted patch is folder without the .tar.gz extension
-					extractedPatchPath := strings.TrimSuffix(compressedPatchPath, ".tar.gz")
+					extractedPatchPath := filepath.Join(cfg.DbTmp, strings.TrimSuffix(patch.FileName, ".tar.gz"))
 
 					decompressedPatchChan <- extractedPatchPath
-					// remove compressed patch
-					err = os.RemoveAll(compressedPatchPath)
-					if err != nil {
-						errDecompressChan <- err
-						return
+					// remove compressed patch unless it is cached for later updates
+					if cfg.PatchCache == "" {
+						err = os.RemoveAll(compressedPatchPath)
+						if err != nil {
+							errDecompressChan <- err
+							return
+						}
 					}
 				}
 
@@ -325,7 +324,7 @@ func decompressPatch(cfg *utils.Config, patchChan chan utils.PatchJson, errChan
 
 }
 
-// downloadPatch downloads patches from server and sends them towards decompressor
+// downloadPatch provides verified patches in the patch cache and sends them towards decompressor
 func downloadPatch(cfg *utils.Config, patchesChan chan utils.PatchJson) (chan utils.PatchJson, chan error) {
 	log := logger.NewLogger(cfg.LogLevel, "Download patch")
 	downloadedPatchChan := make(chan utils.PatchJson, 1)
@@ -339,28 +338,12 @@ func downloadPatch(cfg *utils.Config, patchesChan chan utils.PatchJson) (chan ut
 			if !ok {
 				return
 			}
-			log.Debugf("Downloading %s...", patch.FileName)
-			patchUrl := utils.AidaDbRepositoryUrl + "/" + patch.FileName
-			compressedPatchPath := filepath.Join(cfg.DbTmp, patch.FileName)
-			err := downloadFile(compressedPatchPath, cfg.DbTmp, patchUrl)
-			if err != nil {
-				errChan <- fmt.Errorf("unable to download %s; %v", patchUrl, err)
-				return
-			}
-			log.Debugf("Finished downloading %s!", patch.FileName)
-
-			log.Debugf("Calculating %s md5...", patch.FileName)
-			md5, err := calculateMD5Sum(compressedPatchPath)
-			if err != nil {
-				errChan <- fmt.Errorf("archive %v; unable to calculate md5sum; %v", patch.FileName, err)
-				return
-			}
-
-			// Compare whether downloaded file matches expected md5
-			if strings.Compare(md5, patch.TarHash) != 0 {
-				errChan <- fmt.Errorf("archive %v doesn't have matching md5; archive %v, expected %v", patch.FileName, md5, patch.TarHash)
+			log.Debugf("Fetching %s...", patch.FileName)
+			if _, err := fetchPatch(patchCacheDir(cfg), utils.AidaDbRepositoryUrl, patch, log); err != nil {
+				errChan <- err
 				return
 			}
+			log.Debugf("Finished fetching %s!", patch.FileName)
 
 			downloadedPatchChan <- patch
 		}
@@ -505,96 +488,6 @@ func deleteOperaWorldStateFromUpdateSet(dbPath string) error {
 	return updateDb.Close()
 }
 
-// downloadFile downloads file - used for downloading individual patches.
-func downloadFile(filePath string, parentPath string, url string) error {
-	// Create parent directories if they don't exist
-	err := os.MkdirAll(parentPath, 0755)
-	if err != nil {
-		return fmt.Errorf("error creating parent directories: %v", err)
-	}
-
-	// Open the file in append mode or create it if it doesn't exist
-	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return fmt.Errorf("error opening file: %v", err)
-	}
-	defer file.Close()
-
-	// Get the current file size
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("error getting file info: %v", err)
-	}
-	currentSize := fileInfo.Size()
-
-	// Seek to the end of the file
-	_, err = file.Seek(currentSize, io.SeekStart)
-	if err != nil {
-		return fmt.Errorf("error seeking file: %v", err)
-	}
-
-	writer := bufio.NewWriter(file)
-
-	err = getFileContentsFromUrl(url, currentSize, writer)
-	if err != nil {
-		return err
-	}
-
-	err = writer.Flush()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// getFileContentsFromUrl retrieves file contents stream from file at given url address
-func getFileContentsFromUrl(url string, startSize int64, out *bufio.Writer) error {
-	var err error
-	var written int64
-
-	for tries := maxNumberOfDownloadAttempts; tries > 0; tries-- {
-		written, err = downloadFileContents(url, startSize, out)
-		if err == nil {
-			return nil
-		}
-
-		// wait until next attempt
-		time.Sleep(2 * time.Second)
-
-		startSize += written
-	}
-
-	return fmt.Errorf("failed after %v attempts; %s", maxNumberOfDownloadAttempts, err.Error())
-}
-
-// downloadFileContents downloads file contents from given start
-func downloadFileContents(url string, startSize int64, out *bufio.Writer) (int64, error) {
-	// Set the "Range" header to resume the download from the current size
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return 0, fmt.Errorf("error creating request: %v", err)
-	}
-	if startSize > 0 {
-		req.Header.Set("Range", "bytes="+strconv.FormatInt(startSize, 10)+"-")
-	}
-
-	// Make the request
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("error making request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check server response again
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
-		return 0, fmt.Errorf("downloading %s, bad status: %s", url, resp.Status)
-	}
-
-	// Writer the body to file
-	return io.Copy(out, resp.Body)
-}
-
 // extractTarGz extracts tar file contents into location of output folder
 func extractTarGz(tarGzFile, outputFolder string) error {
 	// Open the tar.gz file
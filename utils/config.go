@@ -178,22 +178,23 @@ type Config struct {
 	CarmenNodeCacheSize      int     // the size of the in-memory cache to be used by a Carmen LiveDB in byte (0 for default value)
 	ChainID                  ChainID // Blockchain ID (mainnet: 250/testnet: 4002)
 	chainCfg                 *params.ChainConfig
-	ChannelBufferSize        int    // set a buffer size for profiling channel
-	CompactDb                bool   // compact database after merging
-	ContinueOnFailure        bool   // continue validation when an error detected
-	ContractNumber           int64  // number of contracts to create
-	CustomDbName             string // name of state-db directory
-	DbComponent              string // options for util-db info are 'all', 'substate', 'delete', 'update', 'state-hash'
-	DbImpl                   string // storage implementation
-	DbLogging                string // set to true if all DB operations should be logged
-	DbTmp                    string // path to temporary database
-	DbVariant                string // database variant
-	Debug                    bool   // enable trace debug flag
-	DebugFrom                uint64 // the first block to print trace debug
-	DeleteSourceDbs          bool   // delete source databases
-	DeletionDb               string // directory of deleted account database
-	DiagnosticServer         int64  // if not zero, the port used for hosting a HTTP server for performance diagnostics
-	ErrorLogging             string // if defined, error logging to file is enabled
+	ChannelBufferSize        int      // set a buffer size for profiling channel
+	CompactDb                bool     // compact database after merging
+	ContinueOnFailure        bool     // continue validation when an error detected
+	ContractNumber           int64    // number of contracts to create
+	Contracts                []string // addresses of contracts whose transactions are cloned
+	CustomDbName             string   // name of state-db directory
+	DbComponent              string   // options for util-db info are 'all', 'substate', 'delete', 'update', 'state-hash'
+	DbImpl                   string   // storage implementation
+	DbLogging                string   // set to true if all DB operations should be logged
+	DbTmp                    string   // path to temporary database
+	DbVariant                string   // database variant
+	Debug                    bool     // enable trace debug flag
+	DebugFrom                uint64   // the first block to print trace debug
+	DeleteSourceDbs          bool     // delete source databases
+	DeletionDb               string   // directory of deleted account database
+	DiagnosticServer         int64    // if not zero, the port used for hosting a HTTP server for performance diagnostics
+	ErrorLogging             string   // if defined, error logging to file is enabled
 	EvmImpl                  string
 	Genesis                  string         // genesis file
 	EthTestType              EthTestType    // which geth test are we running
@@ -202,8 +203,12 @@ type Config struct {
 	KeepDb                   bool           // set to true if db is kept after run
 	KeysNumber               int64          // number of keys to generate
 	LogLevel                 string         // level of the logging of the app action
+	MarkovOrder              int            // order of the Markov process recorded for stochastic simulation
+	MaxCohensW               float64        // maximum Cohen's w of simulated from recorded operation frequencies in stochastic validation
+	MaxKsDistance            float64        // maximum Kolmogorov-Smirnov distance of simulated from recorded access distributions in stochastic validation
 	MaxNumErrors             int            // maximum number of errors when ContinueOnFailure is enabled
 	MaxNumTransactions       int            // the maximum number of processed transactions
+	MaxTotalVariation        float64        // maximum total variation distance of simulated from recorded stationary distribution in stochastic validation
 	MemoryBreakdown          bool           // enable printing of memory breakdown
 	MemoryProfile            string         // capture the memory heap profile into the file
 	MicroProfiling           bool           // enable micro-profiling of EVM
@@ -212,8 +217,12 @@ type Config struct {
 	OnlySuccessful           bool           // only runs transactions that have been successful
 	OperaBinary              string         // path to opera binary
 	OperaDb                  string         // path to opera database
+	OperationLog             string         // file logging the operations executed by the stochastic replay
 	Output                   string         // output directory for aida-db patches or path to events.json file in stochastic generation
 	OverwriteRunId           string         // when registering runs, use provided id instead of the autogenerated run id
+	PhaseLength              uint64         // number of blocks per phase of a time-varying stochastic model
+	PatchCache               string         // directory keeping downloaded aida-db patches
+	PatchMirror              string         // local directory or url mirroring the aida-db patch repository
 	PathToStateDb            string         // Path to a working state-db directory
 	PrimeRandom              bool           // enable randomized priming
 	PrimeThreshold           int            // set account threshold before commit
@@ -227,8 +236,19 @@ type Config struct {
 	ProfileSqlite3           string         // output profiling results to sqlite3 DB
 	ProfilingDbName          string         // set a database name for storing micro-profiling results
 	RandomSeed               int64          // set random seed for stochastic testing
+	ReaderStreams            int            // number of concurrent reader streams of the stochastic replay
 	RegisterRun              string         // register run to the provided connection string
+	RpcEstimateGas           bool           // if enabled, estimateGas requests are replayed against archive of the recorded block
+	RpcEstimateGasTolerance  float64        // maximum relative deviation of replayed estimateGas result from the recorded one
+	RpcListenAddress         string         // address on which the JSON-RPC server listens
+	RpcOffline               bool           // if enabled, resent requests are answered only from the reference cache
+	RpcRecordingMaxSize      uint64         // maximum size of a single recording file in MB
 	RpcRecordingPath         string         // path to source file (or dir with files) with recorded RPC requests
+	RpcReferenceCache        string         // file caching responses of the reference endpoint
+	RpcReferenceUrl          string         // URL of the reference endpoint mismatched requests are resent to
+	RpcRulesFile             string         // JSON file with exclusions and tolerances of the RPC comparison
+	RpcTargetUrl             string         // URL of the JSON-RPC endpoint requests are forwarded to
+	RpcTimeScale             float64        // scale of intervals between recorded timestamps, 0 disables pacing
 	ShadowDb                 bool           // defines we want to open an existing db as shadow
 	ShadowImpl               string         // implementation of the shadow DB to use, empty if disabled
 	ShadowVariant            string         // database variant of the shadow DB to be used
@@ -248,6 +268,8 @@ type Config struct {
 	Trace                    bool           // trace flag
 	TraceDirectory           string         // name of trace directory
 	TraceFile                string         // name of trace file
+	TraceTiming              bool           // if enabled, time between operations is recorded and reproduced in storage traces
+	TimeScale                float64        // scaling factor for recorded time between operations
 	TrackProgress            bool           // enables track progress logging
 	TrackerGranularity       int            // defines how often will tracker report achieved block
 	TransactionLength        uint64         // determines indirectly the length of a transaction
@@ -263,6 +285,8 @@ type Config struct {
 	Workers                  int            // number of worker threads
 	TxGeneratorType          []string       // type of the application used for transaction generation
 	Forks                    []string       // Which forks are going to get executed byz
+	MergeWeights             []float64      // weights of the event files merged for stochastic simulation
+	FromTrace                bool           // if enabled, stochastic events are recorded from storage traces
 
 	// -- cached results --
 
@@ -392,6 +416,11 @@ func (cc *configContext) setFirstOperaBlock() error {
 
 // setAidaDbRepositoryUrl based on chain id selects correct aida-db repository url
 func (cc *configContext) setAidaDbRepositoryUrl() error {
+	// a mirror replaces the aida-db repository of any chain
+	if cc.cfg.PatchMirror != "" {
+		AidaDbRepositoryUrl = strings.TrimSuffix(cc.cfg.PatchMirror, "/")
+		return nil
+	}
 	switch cc.cfg.ChainID {
 	case MainnetChainID:
 		AidaDbRepositoryUrl = AidaDbRepositoryMainnetUrl
@@ -47,6 +47,7 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		CompactDb:                getFlagValue(ctx, CompactDbFlag).(bool),
 		ContinueOnFailure:        getFlagValue(ctx, ContinueOnFailureFlag).(bool),
 		ContractNumber:           getFlagValue(ctx, ContractNumberFlag).(int64),
+		Contracts:                getFlagValue(ctx, ContractsFlag).([]string),
 		CustomDbName:             getFlagValue(ctx, CustomDbNameFlag).(string),
 		DbComponent:              getFlagValue(ctx, DbComponentFlag).(string),
 		DbImpl:                   getFlagValue(ctx, StateDbImplementationFlag).(string),
@@ -61,12 +62,18 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		ErrorLogging:             getFlagValue(ctx, ErrorLoggingFlag).(string),
 		EvmImpl:                  getFlagValue(ctx, EvmImplementation).(string),
 		Forks:                    getFlagValue(ctx, ForksFlag).([]string),
+		FromTrace:                getFlagValue(ctx, FromTraceFlag).(bool),
 		Genesis:                  getFlagValue(ctx, GenesisFlag).(string),
 		EthTestType:              EthTestType(getFlagValue(ctx, EthTestTypeFlag).(int)),
 		IncludeStorage:           getFlagValue(ctx, IncludeStorageFlag).(bool),
 		KeepDb:                   getFlagValue(ctx, KeepDbFlag).(bool),
 		KeysNumber:               getFlagValue(ctx, KeysNumberFlag).(int64),
 		LogLevel:                 getFlagValue(ctx, logger.LogLevelFlag).(string),
+		MarkovOrder:              getFlagValue(ctx, MarkovOrderFlag).(int),
+		MaxCohensW:               getFlagValue(ctx, MaxCohensWFlag).(float64),
+		MaxKsDistance:            getFlagValue(ctx, MaxKsDistanceFlag).(float64),
+		MaxTotalVariation:        getFlagValue(ctx, MaxTotalVariationFlag).(float64),
+		MergeWeights:             getFlagValue(ctx, MergeWeightsFlag).([]float64),
 		MaxNumErrors:             getFlagValue(ctx, MaxNumErrorsFlag).(int),
 		MaxNumTransactions:       getFlagValue(ctx, MaxNumTransactionsFlag).(int),
 		MemoryBreakdown:          getFlagValue(ctx, MemoryBreakdownFlag).(bool),
@@ -77,8 +84,12 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		OnlySuccessful:           getFlagValue(ctx, OnlySuccessfulFlag).(bool),
 		OperaBinary:              getFlagValue(ctx, OperaBinaryFlag).(string),
 		OperaDb:                  getFlagValue(ctx, OperaDbFlag).(string),
+		OperationLog:             getFlagValue(ctx, OperationLogFlag).(string),
 		Output:                   getFlagValue(ctx, OutputFlag).(string),
 		OverwriteRunId:           getFlagValue(ctx, OverwriteRunIdFlag).(string),
+		PhaseLength:              getFlagValue(ctx, PhaseLengthFlag).(uint64),
+		PatchCache:               getFlagValue(ctx, PatchCacheFlag).(string),
+		PatchMirror:              getFlagValue(ctx, PatchMirrorFlag).(string),
 		PrimeRandom:              getFlagValue(ctx, RandomizePrimingFlag).(bool),
 		PrimeThreshold:           getFlagValue(ctx, PrimeThresholdFlag).(int),
 		Profile:                  getFlagValue(ctx, ProfileFlag).(bool),
@@ -91,8 +102,19 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		ProfileSqlite3:           getFlagValue(ctx, ProfileSqlite3Flag).(string),
 		ProfilingDbName:          getFlagValue(ctx, ProfilingDbNameFlag).(string),
 		RandomSeed:               getFlagValue(ctx, RandomSeedFlag).(int64),
+		ReaderStreams:            getFlagValue(ctx, ReaderStreamsFlag).(int),
 		RegisterRun:              getFlagValue(ctx, RegisterRunFlag).(string),
+		RpcEstimateGas:           getFlagValue(ctx, RpcEstimateGasFlag).(bool),
+		RpcEstimateGasTolerance:  getFlagValue(ctx, RpcEstimateGasToleranceFlag).(float64),
+		RpcListenAddress:         getFlagValue(ctx, RpcListenFlag).(string),
+		RpcOffline:               getFlagValue(ctx, RpcOfflineFlag).(bool),
+		RpcRecordingMaxSize:      getFlagValue(ctx, RpcRecordingMaxSizeFlag).(uint64),
 		RpcRecordingPath:         getFlagValue(ctx, RpcRecordingFileFlag).(string),
+		RpcReferenceCache:        getFlagValue(ctx, RpcReferenceCacheFlag).(string),
+		RpcReferenceUrl:          getFlagValue(ctx, RpcReferenceFlag).(string),
+		RpcRulesFile:             getFlagValue(ctx, RpcRulesFlag).(string),
+		RpcTargetUrl:             getFlagValue(ctx, RpcTargetFlag).(string),
+		RpcTimeScale:             getFlagValue(ctx, RpcTimeScaleFlag).(float64),
 		ShadowDb:                 getFlagValue(ctx, ShadowDb).(bool),
 		ShadowImpl:               getFlagValue(ctx, ShadowDbImplementationFlag).(string),
 		ShadowVariant:            getFlagValue(ctx, ShadowDbVariantFlag).(string),
@@ -114,6 +136,8 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		Trace:               getFlagValue(ctx, TraceFlag).(bool),
 		TraceDirectory:      getFlagValue(ctx, TraceDirectoryFlag).(string),
 		TraceFile:           getFlagValue(ctx, TraceFileFlag).(string),
+		TraceTiming:         getFlagValue(ctx, TraceTimingFlag).(bool),
+		TimeScale:           getFlagValue(ctx, TimeScaleFlag).(float64),
 		TrackProgress:       getFlagValue(ctx, TrackProgressFlag).(bool),
 		TrackerGranularity:  getFlagValue(ctx, TrackerGranularityFlag).(int),
 		TransactionLength:   getFlagValue(ctx, TransactionLengthFlag).(uint64),
@@ -165,6 +189,11 @@ func getFlagValue(ctx *cli.Context, flag interface{}) interface{} {
 				return ctx.Path(f.Name)
 			}
 
+		case cli.Float64Flag:
+			if cmdFlag.Names()[0] == f.Name {
+				return ctx.Float64(f.Name)
+			}
+
 		case cli.BoolFlag:
 			if cmdFlag.Names()[0] == f.Name {
 				return ctx.Bool(f.Name)
@@ -173,6 +202,10 @@ func getFlagValue(ctx *cli.Context, flag interface{}) interface{} {
 			if cmdFlag.Names()[0] == f.Name {
 				return ctx.StringSlice(f.Name)
 			}
+		case cli.Float64SliceFlag:
+			if cmdFlag.Names()[0] == f.Name {
+				return ctx.Float64Slice(f.Name)
+			}
 		}
 	}
 
@@ -188,6 +221,8 @@ func getFlagValue(ctx *cli.Context, flag interface{}) interface{} {
 		return f.Value
 	case cli.PathFlag:
 		return f.Value
+	case cli.Float64Flag:
+		return f.Value
 	case cli.BoolFlag:
 		return f.Value
 	case cli.StringSliceFlag:
@@ -195,6 +230,11 @@ func getFlagValue(ctx *cli.Context, flag interface{}) interface{} {
 			return []string{}
 		}
 		return f.Value.Value()
+	case cli.Float64SliceFlag:
+		if f.Value == nil {
+			return []float64{}
+		}
+		return f.Value.Value()
 	}
 
 	return nil
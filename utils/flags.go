@@ -27,6 +27,49 @@ var (
 		Usage:   "Path to source file with recorded API data",
 		Aliases: []string{"r"},
 	}
+	RpcEstimateGasFlag = cli.BoolFlag{
+		Name:  "rpc-estimate-gas",
+		Usage: "replay estimateGas requests against archive of the recorded block",
+	}
+	RpcEstimateGasToleranceFlag = cli.Float64Flag{
+		Name:  "rpc-estimate-gas-tolerance",
+		Usage: "maximum relative deviation of replayed estimateGas result from the recorded one (e.g. 0.05 for 5%)",
+	}
+	RpcRecordingMaxSizeFlag = cli.Uint64Flag{
+		Name:  "rpc-recording-max-size",
+		Usage: "maximum size of a single recording file in MB, output is rotated into a new file once exceeded",
+		Value: 1024,
+	}
+	RpcTargetFlag = cli.StringFlag{
+		Name:  "rpc-target",
+		Usage: "URL of the JSON-RPC endpoint requests are forwarded to",
+		Value: "http://localhost:18545",
+	}
+	RpcReferenceFlag = cli.StringFlag{
+		Name:  "rpc-reference",
+		Usage: "URL of the reference JSON-RPC endpoint mismatched requests are resent to, defaults to the public endpoint of the chain",
+	}
+	RpcReferenceCacheFlag = cli.PathFlag{
+		Name:  "rpc-reference-cache",
+		Usage: "file caching responses of the reference endpoint, every resent request is stored in it",
+	}
+	RpcRulesFlag = cli.PathFlag{
+		Name:  "rpc-rules",
+		Usage: "JSON file with rules excluding requests from the comparison or comparing them with a tolerance",
+	}
+	RpcOfflineFlag = cli.BoolFlag{
+		Name:  "rpc-offline",
+		Usage: "never contact the reference endpoint, resent requests are answered only from the reference cache",
+	}
+	RpcTimeScaleFlag = cli.Float64Flag{
+		Name:  "rpc-time-scale",
+		Usage: "scale of intervals between recorded timestamps (e.g. 0.5 replays twice as fast), requests are sent as fast as possible if 0",
+	}
+	RpcListenFlag = cli.StringFlag{
+		Name:  "rpc-listen",
+		Usage: "address on which the JSON-RPC server listens",
+		Value: "localhost:8545",
+	}
 	ArchiveModeFlag = cli.BoolFlag{
 		Name:  "archive",
 		Usage: "set node type to archival mode. If set, the node keep all the EVM state history; otherwise the state history will be pruned.",
@@ -174,6 +217,10 @@ var (
 		Usage: "Set random seed",
 		Value: -1,
 	}
+	ReaderStreamsFlag = cli.IntFlag{
+		Name:  "reader-streams",
+		Usage: "number of concurrent streams issuing read-only operations on archive states of past blocks",
+	}
 	SkipPrimingFlag = cli.BoolFlag{
 		Name:  "skip-priming",
 		Usage: "if set, DB priming should be skipped; most useful with the 'memory' DB implementation",
@@ -241,6 +288,19 @@ var (
 		Name:  "trace-dir",
 		Usage: "set storage trace directory",
 	}
+	TraceTimingFlag = cli.BoolFlag{
+		Name:  "trace-timing",
+		Usage: "record the time between operations in storage traces or reproduce it when replaying",
+	}
+	TimeScaleFlag = cli.Float64Flag{
+		Name:  "time-scale",
+		Usage: "scaling factor for recorded time between operations (e.g. 0.5 replays twice as fast)",
+		Value: 1.0,
+	}
+	FromTraceFlag = cli.BoolFlag{
+		Name:  "from-trace",
+		Usage: "read StateDB operations from storage traces instead of executing substates",
+	}
 	UpdateDbFlag = cli.PathFlag{
 		Name:  "update-db",
 		Usage: "set update-set database directory",
@@ -272,6 +332,30 @@ var (
 		Usage: "select VM implementation",
 		Value: "geth",
 	}
+	MarkovOrderFlag = cli.IntFlag{
+		Name:  "markov-order",
+		Usage: "number of preceding operations forming the context of an operation in the recorded Markov process",
+		Value: 1,
+	}
+	MaxCohensWFlag = cli.Float64Flag{
+		Name:  "max-cohens-w",
+		Usage: "maximum Cohen's w of the chi-square test of simulated against recorded operation frequencies",
+		Value: 0.1,
+	}
+	MaxKsDistanceFlag = cli.Float64Flag{
+		Name:  "max-ks-distance",
+		Usage: "maximum Kolmogorov-Smirnov distance of simulated from recorded access-class distributions",
+		Value: 0.1,
+	}
+	MaxTotalVariationFlag = cli.Float64Flag{
+		Name:  "max-total-variation",
+		Usage: "maximum total variation distance of simulated from recorded stationary distribution",
+		Value: 0.05,
+	}
+	MergeWeightsFlag = cli.Float64SliceFlag{
+		Name:  "weights",
+		Usage: "weights of the merged event files (default: equal weights)",
+	}
 	MaxNumTransactionsFlag = cli.IntFlag{
 		Name:  "max-tx",
 		Usage: "limit the maximum number of processed transactions, default: unlimited",
@@ -281,6 +365,22 @@ var (
 		Name:  "output",
 		Usage: "output path",
 	}
+	OperationLogFlag = cli.PathFlag{
+		Name:  "operation-log",
+		Usage: "logs executed stochastic operations with their arguments to the given file",
+	}
+	PhaseLengthFlag = cli.Uint64Flag{
+		Name:  "phase-length",
+		Usage: "number of blocks per phase of a time-varying stochastic model (0 for a single stationary model)",
+	}
+	PatchCacheFlag = cli.PathFlag{
+		Name:  "patch-cache",
+		Usage: "directory keeping downloaded aida-db patches for reuse across updates; downloaded patches are removed if empty",
+	}
+	PatchMirrorFlag = cli.StringFlag{
+		Name:  "patch-mirror",
+		Usage: "local directory or url of a file server mirroring the aida-db patch repository",
+	}
 	PortFlag = cli.StringFlag{
 		Name:        "port",
 		Aliases:     []string{"v"},
@@ -302,6 +402,11 @@ var (
 		Usage:    "set substate, updateset and deleted accounts directory",
 		Required: true,
 	}
+	ContractsFlag = cli.StringSliceFlag{
+		Name:     "contracts",
+		Usage:    "list of contract addresses whose transactions are cloned",
+		Required: true,
+	}
 	ContractNumberFlag = cli.Int64Flag{
 		Name:  "num-contracts",
 		Usage: "Number of contracts to create",
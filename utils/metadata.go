@@ -24,6 +24,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Fantom-foundation/Aida/logger"
@@ -1023,6 +1025,33 @@ func (md *AidaDbMetadata) getVerboseDbType() string {
 
 // DownloadPatchesJson downloads list of available patches from aida-db generation server.
 func DownloadPatchesJson() ([]PatchJson, error) {
+	body, err := readPatchesJson()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the JSON data
+	var data []PatchJson
+
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON response body: %s ; %v", string(body), err)
+	}
+
+	// Access the JSON data
+	return data, nil
+}
+
+// readPatchesJson reads the list of available patches from a local or remote aida-db repository.
+func readPatchesJson() ([]byte, error) {
+	if dir, ok := LocalRepositoryPath(AidaDbRepositoryUrl); ok {
+		body, err := os.ReadFile(filepath.Join(dir, "patches.json"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading patches.json from %s: %v", dir, err)
+		}
+		return body, nil
+	}
+
 	// Make the HTTP GET request
 	patchesUrl := AidaDbRepositoryUrl + "/patches.json"
 	response, err := http.Get(patchesUrl)
@@ -1030,23 +1059,25 @@ func DownloadPatchesJson() ([]PatchJson, error) {
 		return nil, fmt.Errorf("error making GET request for %s: %v", patchesUrl, err)
 	}
 	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error making GET request for %s: bad status %s", patchesUrl, response.Status)
+	}
 
 	// Read the response body
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body: %v", err)
 	}
+	return body, nil
+}
 
-	// Parse the JSON data
-	var data []PatchJson
-
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing JSON response body: %s ; %v", string(body), err)
+// LocalRepositoryPath returns the directory of an aida-db repository which
+// is given as a local directory or file URL rather than a web server.
+func LocalRepositoryPath(url string) (string, bool) {
+	if url == "" || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return "", false
 	}
-
-	// Access the JSON data
-	return data, nil
+	return strings.TrimPrefix(url, "file://"), true
 }
 
 // getPatchFirstBlock finds first block of patch for given lastPatchBlock.
@@ -46,6 +46,11 @@ func SendRpcRequest(payload JsonRPCRequest, chainId ChainID) (map[string]interfa
 		return nil, err
 	}
 
+	return SendRpcRequestTo(payload, url)
+}
+
+// SendRpcRequestTo sends the payload to the JSON-RPC endpoint at given url.
+func SendRpcRequestTo(payload JsonRPCRequest, url string) (map[string]interface{}, error) {
 	jsonReq, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("cannot marshal req with first block; %v", err)
@@ -63,6 +68,8 @@ func SendRpcRequest(payload JsonRPCRequest, chainId ChainID) (map[string]interfa
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
 	m := make(map[string]interface{})
 
 	if err = json.NewDecoder(resp.Body).Decode(&m); err != nil {